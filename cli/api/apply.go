@@ -0,0 +1,239 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/vsco/dcdr/models"
+)
+
+var (
+	// ErrInvalidType returned for features with an unknown type.
+	ErrInvalidType = errors.New("invalid type. use percentile, boolean, string, integer, json or variant")
+	// ErrInvalidValue returned when a value cannot be represented as its type.
+	ErrInvalidValue = errors.New("value does not match the feature type")
+	// ErrInvalidRange returned for percentiles outside of 0.0-1.0.
+	ErrInvalidRange = errors.New("percentiles must be within 0.0-1.0")
+	// ErrDuplicateFeature returned when a manifest sets a feature more than once.
+	ErrDuplicateFeature = errors.New("feature is set more than once")
+)
+
+// ManifestErrors every problem found while validating a manifest.
+type ManifestErrors []error
+
+func (me ManifestErrors) Error() string {
+	msgs := make([]string, len(me))
+
+	for i, err := range me {
+		msgs[i] = err.Error()
+	}
+
+	return strings.Join(msgs, "\n")
+}
+
+// ValidateFeature checks the type, value, and rules of `ft`, inferring
+// the type when it is not set and converting whole numbers to integers.
+func ValidateFeature(ft *models.Feature) error {
+	if ft.Value == nil {
+		return ErrNilValue
+	}
+
+	if ft.FeatureType == "" {
+		ft.FeatureType = models.TypeOfValue(ft.Value)
+	}
+
+	if models.ParseFeatureType(string(ft.FeatureType)) == models.Invalid {
+		return ErrInvalidType
+	}
+
+	f, isNumber := number(ft.Value)
+
+	switch ft.FeatureType {
+	case models.Percentile:
+		if !isNumber {
+			return ErrInvalidValue
+		}
+
+		if f < 0 || f > 1 {
+			return ErrInvalidRange
+		}
+
+		ft.Value = f
+	case models.Integer:
+		if !isNumber || f != float64(int64(f)) {
+			return ErrInvalidValue
+		}
+
+		ft.Value = int64(f)
+	case models.Boolean, models.String:
+		if models.TypeOfValue(ft.Value) != ft.FeatureType {
+			return ErrInvalidValue
+		}
+	case models.JSON:
+		switch ft.Value.(type) {
+		case map[string]interface{}, []interface{}:
+		default:
+			return ErrInvalidValue
+		}
+	case models.Variant:
+		if _, ok := models.WeightedVariantsFromValue(ft.Value); !ok {
+			return ErrInvalidValue
+		}
+	}
+
+	if ft.Targeted() && ft.FeatureType != models.Boolean && ft.FeatureType != models.Percentile {
+		return ErrRulesType
+	}
+
+	return nil
+}
+
+// PlanApply validates every feature in `fts` and returns the changes
+// needed for the store to match them. Features that already match are
+// skipped. Nothing is returned unless the whole set is valid, including
+// the absence of type changes to existing features.
+func (c *Client) PlanApply(fts models.Features) ([]Change, error) {
+	current, err := c.List("", "")
+
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make(map[string]models.Feature)
+
+	for _, ft := range current {
+		existing[scopedPath(ft.GetScope(), ft.Key)] = ft
+	}
+
+	var errs ManifestErrors
+	var changes []Change
+
+	seen := make(map[string]bool)
+
+	for _, ft := range fts {
+		path := scopedPath(ft.GetScope(), ft.Key)
+
+		if seen[path] {
+			errs = append(errs, fmt.Errorf("%s: %w", path, ErrDuplicateFeature))
+			continue
+		}
+
+		seen[path] = true
+		ft.Namespace = c.Namespace()
+
+		if ft.Rules == nil {
+			ft.Rules = models.Rules{}
+		}
+
+		if err := ValidateFeature(&ft); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			continue
+		}
+
+		prev, ok := existing[path]
+
+		if !ok {
+			changes = append(changes, Change{Feature: ft})
+			continue
+		}
+
+		if prev.FeatureType != ft.FeatureType {
+			errs = append(errs, fmt.Errorf("%s: %w from %s to %s", path, ErrTypeChange, prev.FeatureType, ft.FeatureType))
+			continue
+		}
+
+		if reflect.DeepEqual(normalize(prev.MapValue()), normalize(ft.MapValue())) &&
+			(ft.Comment == "" || ft.Comment == prev.Comment) &&
+			sameMetadata(prev.Metadata, ft.Metadata) {
+			continue
+		}
+
+		changes = append(changes, Change{Feature: ft, Previous: &prev})
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	sortChanges(changes)
+
+	return changes, nil
+}
+
+// ApplyAll applies `changes` like `Apply`, restoring the previous state
+// of any changes already applied if one of them fails.
+func (c *Client) ApplyAll(changes []Change) error {
+	for i := range changes {
+		err := c.Apply(changes[i : i+1])
+
+		if err == nil {
+			continue
+		}
+
+		for j := i - 1; j >= 0; j-- {
+			if rerr := c.revert(changes[j]); rerr != nil {
+				return fmt.Errorf("%v. could not revert %s: %v", err, changes[j].Feature.ScopedKey(), rerr)
+			}
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// revert restores the state of the store before `change` was applied.
+func (c *Client) revert(change Change) error {
+	if change.Previous == nil {
+		return c.Delete(change.Feature.Key, change.Feature.GetScope())
+	}
+
+	// fields missing from `prev` are cleared rather than kept by `Set`
+	prev := *change.Previous
+	prev.ClearOwner = prev.Owner == ""
+	prev.ClearExpiry = prev.ExpiresAt == nil
+
+	if prev.Rules == nil {
+		prev.Rules = models.Rules{}
+	}
+
+	if prev.Tags == nil {
+		prev.Tags = []string{}
+	}
+
+	return c.Set(&prev)
+}
+
+// sameMetadata checks if the owner, tags, and expiry set in `next`
+// already match `prev`. Unset fields are kept by `Set` and match
+// unless they are cleared.
+func sameMetadata(prev models.Metadata, next models.Metadata) bool {
+	if (next.Owner != "" || next.ClearOwner) && next.Owner != prev.Owner {
+		return false
+	}
+
+	if next.Tags != nil && (len(next.Tags) > 0 || len(prev.Tags) > 0) && !reflect.DeepEqual(next.Tags, prev.Tags) {
+		return false
+	}
+
+	if next.ExpiresAt == nil {
+		return !next.ClearExpiry || prev.ExpiresAt == nil
+	}
+
+	return prev.ExpiresAt != nil && next.ExpiresAt.Equal(*prev.ExpiresAt)
+}
+
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	default:
+		return 0, false
+	}
+}
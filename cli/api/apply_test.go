@@ -0,0 +1,139 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vsco/dcdr/models"
+)
+
+func TestValidateFeature(t *testing.T) {
+	cases := []struct {
+		Type  models.FeatureType
+		Value interface{}
+		Err   error
+	}{
+		{"", 0.5, nil},
+		{"", nil, ErrNilValue},
+		{models.Percentile, 1.5, ErrInvalidRange},
+		{models.Percentile, "half", ErrInvalidValue},
+		{models.Integer, 50.0, nil},
+		{models.Integer, 1.5, ErrInvalidValue},
+		{models.Boolean, "true", ErrInvalidValue},
+		{models.String, "on", nil},
+		{models.JSON, map[string]interface{}{"a": 1.0}, nil},
+		{models.JSON, "{}", ErrInvalidValue},
+		{models.Variant, []interface{}{map[string]interface{}{"name": "a", "weight": 100.0}}, nil},
+		{models.Variant, []interface{}{}, ErrInvalidValue},
+		{"float", 0.5, ErrInvalidType},
+	}
+
+	for _, tc := range cases {
+		ft := &models.Feature{Key: "a", FeatureType: tc.Type, Value: tc.Value}
+		assert.Equal(t, tc.Err, ValidateFeature(ft), "%s %v", tc.Type, tc.Value)
+	}
+
+	ft := &models.Feature{Key: "a", FeatureType: models.Integer, Value: 50.0}
+	ValidateFeature(ft)
+	assert.Equal(t, int64(50), ft.Value)
+
+	ft = &models.Feature{Key: "a", Value: "on", Rules: models.Rules{{Attribute: "country", Operator: models.In, Values: []string{"us"}}}}
+	assert.Equal(t, ErrRulesType, ValidateFeature(ft))
+}
+
+func TestPlanApply(t *testing.T) {
+	c, _ := historyClient(&historyRepo{},
+		models.NewFeature("same", true, "", "", "", "dcdr"),
+		models.NewFeature("flag", 0.1, "old comment", "", "", "dcdr"),
+		models.NewFeature("typed", "on", "", "", "", "dcdr"))
+
+	changes, err := c.PlanApply(models.Features{
+		*models.NewFeature("same", true, "", "", "", ""),
+		*models.NewFeature("flag", 0.5, "", "", "", ""),
+		{Key: "new", Value: 50.0, Scope: "user-groups/beta"},
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, changes, 2)
+	assert.Equal(t, "dcdr/features/default/flag", changes[0].Feature.ScopedKey())
+	assert.Equal(t, 0.1, changes[0].Previous.Value)
+	assert.Equal(t, "dcdr/features/user-groups/beta/new", changes[1].Feature.ScopedKey())
+	assert.Nil(t, changes[1].Previous)
+	assert.Equal(t, models.Integer, changes[1].Feature.FeatureType)
+
+	owned := models.NewFeature("same", true, "", "", "", "")
+	owned.Owner = "growth"
+
+	changes, err = c.PlanApply(models.Features{*owned})
+	assert.NoError(t, err)
+	assert.Len(t, changes, 1)
+
+	_, err = c.PlanApply(models.Features{
+		*models.NewFeature("flag", 1.5, "", "", "", ""),
+		*models.NewFeature("typed", true, "", "", "", ""),
+		*models.NewFeature("dupe", true, "", "", "", ""),
+		*models.NewFeature("dupe", false, "", "", "", ""),
+		*models.NewFeature("fine", true, "", "", "", ""),
+	})
+
+	errs, ok := err.(ManifestErrors)
+	assert.True(t, ok)
+	assert.Len(t, errs, 3)
+	assert.True(t, errors.Is(errs[0], ErrInvalidRange))
+	assert.True(t, errors.Is(errs[1], ErrTypeChange))
+	assert.True(t, errors.Is(errs[2], ErrDuplicateFeature))
+	assert.Equal(t, 3, len(strings.Split(errs.Error(), "\n")))
+}
+
+type failingStore struct {
+	*memStore
+	failOn string
+}
+
+func (fs *failingStore) Set(key string, bts []byte) error {
+	if key == fs.failOn {
+		return errors.New("boom")
+	}
+
+	return fs.memStore.Set(key, bts)
+}
+
+func (fs *failingStore) SetCAS(key string, bts []byte, version uint64) error {
+	if key == fs.failOn {
+		return errors.New("boom")
+	}
+
+	return fs.memStore.SetCAS(key, bts, version)
+}
+
+func TestApplyAllReverts(t *testing.T) {
+	c, ms := historyClient(&historyRepo{}, models.NewFeature("flag", 0.1, "", "", "", "dcdr"))
+	c.Store = &failingStore{memStore: ms, failOn: "dcdr/features/default/zzz"}
+
+	owned := models.NewFeature("flag", 0.5, "", "", "", "")
+	owned.Owner = "growth"
+	owned.Tags = []string{"checkout"}
+
+	changes, err := c.PlanApply(models.Features{
+		*owned,
+		*models.NewFeature("new", true, "", "", "", ""),
+		*models.NewFeature("zzz", true, "", "", "", ""),
+	})
+	assert.NoError(t, err)
+	assert.Len(t, changes, 3)
+
+	err = c.ApplyAll(changes)
+	assert.Error(t, err)
+
+	var ft models.Feature
+	json.Unmarshal(ms.kvs["dcdr/features/default/flag"], &ft)
+	assert.Equal(t, 0.1, ft.Value)
+	assert.Empty(t, ft.Owner)
+	assert.Empty(t, ft.Tags)
+
+	_, ok := ms.kvs["dcdr/features/default/new"]
+	assert.False(t, ok)
+}
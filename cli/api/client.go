@@ -1,6 +1,7 @@
 package api
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -22,9 +23,15 @@ const InfoNameSpace = "info"
 var ErrTypeChange = errors.New("cannot change existing feature types")
 var ErrRepoExists = errors.New("repository already exists")
 var ErrNilValue = errors.New("value cannot be nil")
+var ErrRulesType = errors.New("rules are only supported for boolean and percentile features")
+var ErrNotFound = errors.New("not found")
+
+// ErrConflict returned by `Set` when the feature was changed by another
+// writer after it was read.
+var ErrConflict = stores.ErrConflict
 
 func KeyNotFoundError(n string) error {
-	return fmt.Errorf("%s not found", n)
+	return fmt.Errorf("%s %w", n, ErrNotFound)
 }
 
 type ClientIFace interface {
@@ -35,8 +42,23 @@ type ClientIFace interface {
 	GetInfo() (*models.Info, error)
 	InitRepo(create bool) error
 	Commit(ft *models.Feature, deleted bool) error
+	CommitMessage(msg string) error
+	History(key string, scope string) ([]FeatureRevision, error)
+	Rollback(sha string, key string, scope string) ([]Change, error)
+	Reconcile(dryRun bool) ([]Change, error)
+	Diff(from Source, to Source) (*DiffResult, error)
+	PlanApply(fts models.Features) ([]Change, error)
+	ApplyAll(changes []Change) error
+	Stale(opts StaleOptions) ([]StaleFeature, error)
+	SetSchedule(s *models.Schedule) error
+	Schedules() ([]models.Schedule, error)
+	DeleteSchedule(key string, scope string) error
+	RunSchedules(owner string, ttl time.Duration, now time.Time) ([]Change, error)
+	AcquireLock(name string, owner string, ttl time.Duration, now time.Time) (bool, error)
+	ReleaseLock(name string, owner string) error
 	Push() error
 	UpdateCurrentSHA() (string, error)
+	Migrate() (int, error)
 	Watch()
 	Namespace() string
 }
@@ -62,6 +84,20 @@ func (c *Client) Namespace() string {
 	return c.config.Namespace
 }
 
+// Migrate moves keys written by earlier versions of the store into its
+// current layout. Stores that have not changed layout have nothing to move.
+func (c *Client) Migrate() (int, error) {
+	defer c.Store.Close()
+
+	m, ok := c.Store.(stores.Migrator)
+
+	if !ok {
+		return 0, nil
+	}
+
+	return m.Migrate(c.Namespace())
+}
+
 func (c *Client) List(prefix string, scope string) (models.Features, error) {
 	defer c.Store.Close()
 
@@ -71,6 +107,11 @@ func (c *Client) List(prefix string, scope string) (models.Features, error) {
 		prefix = fmt.Sprintf("%s/features/%s/%s", c.Namespace(), scope, prefix)
 	}
 
+	return c.listFeatures(prefix)
+}
+
+// listFeatures unmarshals every `Feature` stored under `prefix`.
+func (c *Client) listFeatures(prefix string) (models.Features, error) {
 	res, err := c.Store.List(prefix)
 
 	fts := make(models.Features, len(res))
@@ -97,6 +138,7 @@ func (c *Client) Set(ft *models.Feature) error {
 	defer c.Store.Close()
 
 	var existing *models.Feature
+	var version uint64
 
 	kvb, err := c.Store.Get(ft.ScopedKey())
 
@@ -105,6 +147,7 @@ func (c *Client) Set(ft *models.Feature) error {
 	}
 
 	if kvb != nil {
+		version = kvb.Version
 		err = json.Unmarshal(kvb.Bytes, &existing)
 
 		if err != nil {
@@ -112,10 +155,6 @@ func (c *Client) Set(ft *models.Feature) error {
 		}
 	}
 
-	if err != nil {
-		return err
-	}
-
 	if existing != nil {
 		if ft.Comment == "" {
 			ft.Comment = existing.Comment
@@ -129,19 +168,48 @@ func (c *Client) Set(ft *models.Feature) error {
 		if ft.FeatureType == "" {
 			ft.FeatureType = existing.FeatureType
 		}
+		if ft.Rules == nil {
+			ft.Rules = existing.Rules
+		}
+		if ft.Owner == "" && !ft.ClearOwner {
+			ft.Owner = existing.Owner
+		}
+		if ft.Tags == nil {
+			ft.Tags = existing.Tags
+		}
+		if ft.ExpiresAt == nil && !ft.ClearExpiry {
+			ft.ExpiresAt = existing.ExpiresAt
+		}
+		if existing.CreatedAt != nil {
+			ft.CreatedAt = existing.CreatedAt
+		}
 	} else {
 		if ft.Value == nil {
 			return ErrNilValue
 		}
 	}
 
+	if ft.Targeted() && ft.FeatureType != models.Boolean && ft.FeatureType != models.Percentile {
+		return ErrRulesType
+	}
+
+	now := time.Now().UTC()
+
+	if ft.CreatedAt == nil {
+		ft.CreatedAt = &now
+	}
+
+	ft.UpdatedAt = &now
+
 	bts, err := ft.ToJSON()
 
 	if err != nil {
 		return err
 	}
 
-	return c.Store.Set(ft.ScopedKey(), bts)
+	// the write only succeeds if the feature is unchanged since it was
+	// read so concurrent edits are reported rather than lost
+	return c.Store.SetCAS(ft.ScopedKey(), bts, version)
 }
 
 func (c *Client) Get(key string, v interface{}) error {
@@ -190,6 +258,19 @@ func (c *Client) Delete(key string, scope string) error {
 }
 
 func (c *Client) Commit(ft *models.Feature, deleted bool) error {
+	var msg string
+
+	if deleted {
+		msg = fmt.Sprintf("%s deleted %s", ft.UpdatedBy, ft.ScopedKey())
+	} else {
+		msg = fmt.Sprintf("%s set %s to %v", ft.UpdatedBy, ft.ScopedKey(), ft.Value)
+	}
+
+	return c.CommitMessage(msg)
+}
+
+// CommitMessage commits the current feature set to the audit repo with `msg`.
+func (c *Client) CommitMessage(msg string) error {
 	if !c.Repo.Exists() {
 		err := c.Repo.Clone()
 
@@ -216,21 +297,79 @@ func (c *Client) Commit(ft *models.Feature, deleted bool) error {
 		return err
 	}
 
-	var msg string
+	return c.Repo.Commit(bts, msg)
+}
 
-	if deleted {
-		msg = fmt.Sprintf("%s deleted %s", ft.UpdatedBy, ft.ScopedKey())
-	} else {
-		msg = fmt.Sprintf("%s set %s to %v", ft.UpdatedBy, ft.ScopedKey(), ft.Value)
+// CommitFeatures commits the current feature set to the audit repo,
+// updates info/current_sha, and pushes to origin when configured.
+// Returns the new SHA or an empty string if git is not enabled.
+func CommitFeatures(c ClientIFace, cfg *config.Config, ft *models.Feature, deleted bool) (string, error) {
+	if !cfg.GitEnabled() {
+		return "", nil
 	}
 
-	err = c.Repo.Commit(bts, msg)
+	err := c.Commit(ft, deleted)
 
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	return nil
+	return publish(c, cfg)
+}
+
+// ExistingFeatureType returns the type of the stored feature `key` in
+// `scope` so values can be parsed as that type, or an empty type if it
+// does not exist.
+func ExistingFeatureType(c ClientIFace, key string, scope string) (models.FeatureType, error) {
+	var ft *models.Feature
+
+	if scope == "" {
+		scope = models.DefaultScope
+	}
+
+	err := c.Get(fmt.Sprintf("%s/%s/%s", models.FeatureScope, scope, key), &ft)
+
+	if errors.Is(err, ErrNotFound) {
+		return "", nil
+	}
+
+	if err != nil || ft == nil {
+		return "", err
+	}
+
+	return ft.FeatureType, nil
+}
+
+// CommitChanges commits the current feature set to the audit repo with
+// `msg`, updates info/current_sha, and pushes to origin when configured.
+// Returns the new SHA or an empty string if git is not enabled.
+func CommitChanges(c ClientIFace, cfg *config.Config, msg string) (string, error) {
+	if !cfg.GitEnabled() {
+		return "", nil
+	}
+
+	err := c.CommitMessage(msg)
+
+	if err != nil {
+		return "", err
+	}
+
+	return publish(c, cfg)
+}
+
+// publish updates info/current_sha and pushes the latest commit.
+func publish(c ClientIFace, cfg *config.Config) (string, error) {
+	sha, err := c.UpdateCurrentSHA()
+
+	if err != nil {
+		return sha, err
+	}
+
+	if cfg.PushEnabled() {
+		err = c.Push()
+	}
+
+	return sha, err
 }
 
 func (c *Client) Push() error {
@@ -250,7 +389,7 @@ func (c *Client) GetInfo() (*models.Info, error) {
 		return nil, err
 	}
 
-	if len(kv.Bytes) == 0 {
+	if kv == nil || len(kv.Bytes) == 0 {
 		return &models.Info{}, nil
 	}
 
@@ -300,11 +439,67 @@ func (c *Client) InitRepo(create bool) error {
 	return c.Repo.Clone()
 }
 
+// Watch writes the feature set to `Watcher:OutputPath` each time it
+// changes in the store.
 func (c *Client) Watch() {
-	c.Store.Register(c.WriteOutputFile)
+	c.watch(c.WriteOutputFile)
+}
+
+// watch registers `cb` with the store, skipping updates that only
+// change schedules and locks, such as the scheduler renewing its lock.
+func (c *Client) watch(cb func(kvb stores.KVBytes)) {
+	var last stores.KVBytes
+	called := false
+
+	c.Store.Register(func(kvb stores.KVBytes) {
+		fkvb := c.featureKVs(kvb)
+
+		if called && sameKVs(last, fkvb) {
+			return
+		}
+
+		called = true
+		last = fkvb
+		cb(kvb)
+	})
+
 	c.Store.Watch()
 }
 
+// featureKVs the keys in `kvb` that are part of the feature set.
+func (c *Client) featureKVs(kvb stores.KVBytes) stores.KVBytes {
+	fkvb := make(stores.KVBytes, 0, len(kvb))
+
+	for _, kv := range kvb {
+		if !c.internalKey(kv.Key) {
+			fkvb = append(fkvb, kv)
+		}
+	}
+
+	return fkvb
+}
+
+// internalKey checks if `key` is a schedule or lock, which are stored
+// within the namespace but are not part of the feature set.
+func (c *Client) internalKey(key string) bool {
+	return strings.HasPrefix(key, fmt.Sprintf("%s/%s/", c.Namespace(), models.ScheduleScope)) ||
+		strings.HasPrefix(key, fmt.Sprintf("%s/%s/", c.Namespace(), LocksNameSpace))
+}
+
+func sameKVs(a stores.KVBytes, b stores.KVBytes) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i].Key != b[i].Key || !bytes.Equal(a[i].Bytes, b[i].Bytes) {
+			return false
+		}
+	}
+
+	return true
+}
+
 func (c *Client) WriteOutputFile(kvb stores.KVBytes) {
 	fts, err := c.KVsToFeatureMap(kvb)
 
@@ -338,6 +533,10 @@ func (c *Client) KVsToFeatureMap(kvb stores.KVBytes) (*models.FeatureMap, error)
 		var key string
 		var value interface{}
 
+		if c.internalKey(v.Key) {
+			continue
+		}
+
 		if v.Key == fmt.Sprintf("%s/%s", c.Namespace(), InfoNameSpace) {
 			var info models.Info
 			err := json.Unmarshal(v.Bytes, &info)
@@ -358,6 +557,9 @@ func (c *Client) KVsToFeatureMap(kvb stores.KVBytes) (*models.FeatureMap, error)
 
 			key = strings.Replace(v.Key, fmt.Sprintf("%s/features/", c.Namespace()), "", 1)
 			value = ft.Value
+			fm.Dcdr.AddType(key, ft.FeatureType)
+			fm.Dcdr.AddRules(key, ft.Rules)
+			fm.Dcdr.AddMetadata(key, ft.Metadata)
 		}
 
 		explode(fm.Dcdr.FeatureScopes, key, value)
@@ -366,6 +568,22 @@ func (c *Client) KVsToFeatureMap(kvb stores.KVBytes) (*models.FeatureMap, error)
 	return fm, nil
 }
 
+// FeaturesToFeatureMap helper for nesting `Features` into a `FeatureMap`
+// by their scope.
+func FeaturesToFeatureMap(fts models.Features) *models.FeatureMap {
+	fm := models.EmptyFeatureMap()
+
+	for _, ft := range fts {
+		path := fmt.Sprintf("%s/%s", ft.GetScope(), ft.Key)
+		explode(fm.Dcdr.FeatureScopes, path, ft.Value)
+		fm.Dcdr.AddType(path, ft.FeatureType)
+		fm.Dcdr.AddRules(path, ft.Rules)
+		fm.Dcdr.AddMetadata(path, ft.Metadata)
+	}
+
+	return fm
+}
+
 func explode(m models.FeatureScopes, k string, v interface{}) {
 	if strings.Contains(k, "/") {
 		pts := strings.Split(k, "/")
@@ -1,8 +1,10 @@
 package api
 
 import (
+	"encoding/json"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/vsco/dcdr/cli/api/stores"
@@ -75,6 +77,97 @@ func TestSet(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func TestSetTypedValues(t *testing.T) {
+	fts := models.Features{
+		*models.NewFeature("test", "variant-a", "c", "u", "s", "n"),
+		*models.NewFeature("test", int64(10), "c", "u", "s", "n"),
+		*models.NewFeature("test", map[string]interface{}{"a": 1.0}, "c", "u", "s", "n"),
+	}
+
+	for _, ft := range fts {
+		cs := stores.NewMockStore(&ft, nil)
+		c := New(cs, &stores.MockRepo{}, config.DefaultConfig(), nil)
+
+		assert.NoError(t, c.Set(&ft))
+	}
+}
+
+func TestTypeChangeErrorSetString(t *testing.T) {
+	orig := models.NewFeature("test", "variant-a", "c", "u", "s", "n")
+	bad := models.NewFeature("test", int64(1), "c", "u", "s", "n")
+
+	cs := stores.NewMockStore(orig, nil)
+	c := New(cs, nil, config.DefaultConfig(), nil)
+
+	assert.Equal(t, ErrTypeChange, c.Set(bad))
+}
+
+func TestSetRulesType(t *testing.T) {
+	ft := models.NewFeature("test", "variant-a", "c", "u", "s", "n")
+	ft.Rules = models.Rules{{Attribute: "country", Operator: models.In, Values: []string{"us"}}}
+	c := New(stores.NewMockStore(nil, nil), &stores.MockRepo{}, config.DefaultConfig(), nil)
+
+	assert.Equal(t, ErrRulesType, c.Set(ft))
+}
+
+func TestSetPreservesRules(t *testing.T) {
+	orig := models.NewFeature("test", true, "c", "u", "s", "n")
+	orig.Rules = models.Rules{{Attribute: "country", Operator: models.In, Values: []string{"us"}}}
+	update := models.NewFeature("test", false, "", "u", "s", "n")
+	c := New(stores.NewMockStore(orig, nil), &stores.MockRepo{}, config.DefaultConfig(), nil)
+
+	assert.NoError(t, c.Set(update))
+	assert.Equal(t, orig.Rules, update.Rules)
+
+	update.Rules = models.Rules{}
+	assert.NoError(t, c.Set(update))
+	assert.False(t, update.Targeted())
+}
+
+func TestSetPreservesMetadata(t *testing.T) {
+	exp := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	orig := models.NewFeature("test", true, "c", "u", "", "dcdr")
+	orig.Owner = "growth"
+	orig.Tags = []string{"checkout"}
+	orig.ExpiresAt = &exp
+
+	c, ms := historyClient(&historyRepo{})
+	assert.NoError(t, c.Set(orig))
+	assert.NotNil(t, orig.CreatedAt)
+	assert.Equal(t, orig.CreatedAt, orig.UpdatedAt)
+
+	update := models.NewFeature("test", false, "", "u", "", "dcdr")
+	assert.NoError(t, c.Set(update))
+
+	var ft models.Feature
+	json.Unmarshal(ms.kvs[orig.ScopedKey()], &ft)
+	assert.Equal(t, "growth", ft.Owner)
+	assert.Equal(t, []string{"checkout"}, ft.Tags)
+	assert.True(t, exp.Equal(*ft.ExpiresAt))
+	assert.True(t, orig.CreatedAt.Equal(*ft.CreatedAt))
+	assert.False(t, ft.UpdatedAt.Before(*ft.CreatedAt))
+
+	update.Tags = []string{}
+	assert.NoError(t, c.Set(update))
+
+	var cleared models.Feature
+	json.Unmarshal(ms.kvs[orig.ScopedKey()], &cleared)
+	assert.Empty(t, cleared.Tags)
+	assert.Equal(t, "growth", cleared.Owner)
+	assert.True(t, exp.Equal(*cleared.ExpiresAt))
+
+	update = models.NewFeature("test", false, "", "u", "", "dcdr")
+	update.ClearOwner = true
+	update.ClearExpiry = true
+	assert.NoError(t, c.Set(update))
+
+	cleared = models.Feature{}
+	json.Unmarshal(ms.kvs[orig.ScopedKey()], &cleared)
+	assert.Empty(t, cleared.Owner)
+	assert.Nil(t, cleared.ExpiresAt)
+	assert.True(t, orig.CreatedAt.Equal(*cleared.CreatedAt))
+}
+
 func TestSetErrorOnNilValue(t *testing.T) {
 	ft := models.NewFeature("test", nil, "c", "u", "s", "n")
 	cs := stores.NewMockStore(nil, nil)
@@ -107,6 +200,38 @@ func TestSetWithError(t *testing.T) {
 	assert.Equal(t, e, err)
 }
 
+// racingStore writes to every key right after it is read.
+type racingStore struct {
+	*memStore
+}
+
+func (rs *racingStore) Get(key string) (*stores.KVByte, error) {
+	kv, err := rs.memStore.Get(key)
+
+	if kv != nil {
+		rs.memStore.Set(key, kv.Bytes)
+	}
+
+	return kv, err
+}
+
+func TestSetConflict(t *testing.T) {
+	orig := models.NewFeature("test", 0.5, "c", "u", "", "dcdr")
+	c, ms := historyClient(&historyRepo{}, orig)
+
+	err := c.Set(models.NewFeature("test", 0.6, "", "", "", "dcdr"))
+	assert.NoError(t, err)
+
+	c.Store = &racingStore{ms}
+
+	err = c.Set(models.NewFeature("test", 0.7, "", "", "", "dcdr"))
+	assert.Equal(t, ErrConflict, err)
+
+	var ft models.Feature
+	json.Unmarshal(ms.kvs[orig.ScopedKey()], &ft)
+	assert.Equal(t, 0.6, ft.Value)
+}
+
 func TestDelete(t *testing.T) {
 	ft := models.NewFeature("test", 0.5, "c", "u", "s", "n")
 	cs := stores.NewMockStore(ft, nil)
@@ -147,3 +272,105 @@ func TestKVsToFeatureMapInfoExistByNameSpace(t *testing.T) {
 	assert.Equal(t, fm.Dcdr.Info.CurrentSHA, "abcdef")
 	assert.Equal(t, fm.Dcdr.Info.LastModifiedDate, int64(123456))
 }
+
+func TestKVsToFeatureMapTargeted(t *testing.T) {
+	ft := models.NewFeature("test", true, "c", "u", "default", "dcdr")
+	ft.Rules = models.Rules{{Attribute: "country", Operator: models.In, Values: []string{"us"}}}
+	bts, _ := ft.ToJSON()
+
+	kvb := stores.KVBytes{
+		&stores.KVByte{
+			Key:   ft.ScopedKey(),
+			Bytes: bts,
+		},
+	}
+
+	c := New(stores.NewMockStore(nil, nil), &stores.MockRepo{}, config.DefaultConfig(), nil)
+
+	fm, err := c.KVsToFeatureMap(kvb)
+	assert.NoError(t, err)
+
+	assert.Equal(t, true, fm.Dcdr.Defaults()["test"])
+	assert.Equal(t, ft.Rules, fm.Dcdr.Rules["default/test"])
+}
+
+func TestKVsToFeatureMapMetadata(t *testing.T) {
+	ft := models.NewFeature("test", true, "c", "u", "user-groups/beta", "dcdr")
+	ft.Owner = "growth"
+	bts, _ := ft.ToJSON()
+	plain, _ := models.NewFeature("plain", true, "c", "u", "", "dcdr").ToJSON()
+
+	kvb := stores.KVBytes{
+		&stores.KVByte{Key: ft.ScopedKey(), Bytes: bts},
+		&stores.KVByte{Key: "dcdr/features/default/plain", Bytes: plain},
+	}
+
+	c := New(stores.NewMockStore(nil, nil), &stores.MockRepo{}, config.DefaultConfig(), nil)
+	fm, err := c.KVsToFeatureMap(kvb)
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]models.Metadata{"user-groups/beta/test": {Owner: "growth"}}, fm.Dcdr.Metadata)
+	assert.Equal(t, fm.Dcdr.Metadata, FeaturesToFeatureMap(models.Features{*ft}).Dcdr.Metadata)
+}
+
+type migratingStore struct {
+	*stores.MockStore
+	namespace string
+}
+
+func (ms *migratingStore) Migrate(namespace string) (int, error) {
+	ms.namespace = namespace
+
+	return 3, nil
+}
+
+func TestMigrate(t *testing.T) {
+	c := New(stores.NewMockStore(nil, nil), &stores.MockRepo{}, config.DefaultConfig(), nil)
+	n, err := c.Migrate()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	ms := &migratingStore{MockStore: stores.NewMockStore(nil, nil)}
+	c = New(ms, &stores.MockRepo{}, config.DefaultConfig(), nil)
+	n, err = c.Migrate()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, c.Namespace(), ms.namespace)
+}
+
+type watchingStore struct {
+	stores.MockStore
+	cb func(kvb stores.KVBytes)
+}
+
+func (ws *watchingStore) Register(cb func(kvb stores.KVBytes)) {
+	ws.cb = cb
+}
+
+func TestWatchSkipsLocksAndSchedules(t *testing.T) {
+	ws := &watchingStore{}
+	c := New(ws, nil, config.TestConfig(), nil)
+
+	var calls []stores.KVBytes
+	c.watch(func(kvb stores.KVBytes) {
+		calls = append(calls, kvb)
+	})
+
+	ft := &stores.KVByte{Key: "dcdr/features/default/a", Bytes: []byte(`{"value": true}`)}
+	lock := &stores.KVByte{Key: "dcdr/locks/scheduler", Bytes: []byte(`{"owner": "a"}`)}
+	renewed := &stores.KVByte{Key: "dcdr/locks/scheduler", Bytes: []byte(`{"owner": "b"}`)}
+	schedule := &stores.KVByte{Key: "dcdr/schedules/default/a", Bytes: []byte(`{}`)}
+	changed := &stores.KVByte{Key: "dcdr/features/default/a", Bytes: []byte(`{"value": false}`)}
+
+	ws.cb(stores.KVBytes{ft, lock})
+	ws.cb(stores.KVBytes{ft, renewed})
+	ws.cb(stores.KVBytes{ft, renewed, schedule})
+	assert.Len(t, calls, 1)
+
+	ws.cb(stores.KVBytes{changed, renewed, schedule})
+	assert.Len(t, calls, 2)
+	assert.Equal(t, changed, calls[1][0])
+
+	ws.cb(stores.KVBytes{renewed})
+	assert.Len(t, calls, 3)
+}
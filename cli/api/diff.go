@@ -0,0 +1,293 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/vsco/dcdr/models"
+	"gopkg.in/yaml.v3"
+)
+
+// SourceKind the kind of features a `Source` refers to.
+type SourceKind string
+
+const (
+	// ScopeSource features within a single scope of the current namespace
+	ScopeSource SourceKind = "scope"
+	// NamespaceSource every feature within a namespace
+	NamespaceSource SourceKind = "namespace"
+	// FileSource a decider.json snapshot written by the watcher or a
+	// file written by `dcdr export` in any format
+	FileSource SourceKind = "file"
+	// SHASource the features committed to the audit repo at a SHA
+	SHASource SourceKind = "sha"
+)
+
+// ErrInvalidSource returned for unknown `Source` kinds.
+var ErrInvalidSource = errors.New("invalid source. use scope:<scope>, namespace:<namespace>, file:<path> or sha:<sha>")
+
+// Source a set of features to compare with `Diff`.
+type Source struct {
+	Kind  SourceKind
+	Value string
+}
+
+// ParseSource parses a source formatted as "<kind>:<value>". Sources
+// without a kind are scopes.
+func ParseSource(s string) (Source, error) {
+	pts := strings.SplitN(s, ":", 2)
+
+	if len(pts) == 1 {
+		return Source{Kind: ScopeSource, Value: s}, nil
+	}
+
+	src := Source{Kind: SourceKind(pts[0]), Value: pts[1]}
+
+	switch src.Kind {
+	case ScopeSource, NamespaceSource, FileSource, SHASource:
+	default:
+		return src, ErrInvalidSource
+	}
+
+	if src.Value == "" {
+		return src, ErrInvalidSource
+	}
+
+	return src, nil
+}
+
+func (s Source) String() string {
+	return fmt.Sprintf("%s:%s", s.Kind, s.Value)
+}
+
+// ValueChange the values of a key in both sources of a `Diff`.
+type ValueChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// DiffResult the keys added, removed, or changed from one source to another.
+type DiffResult struct {
+	From    string                 `json:"from"`
+	To      string                 `json:"to"`
+	Added   map[string]interface{} `json:"added"`
+	Removed map[string]interface{} `json:"removed"`
+	Changed map[string]ValueChange `json:"changed"`
+}
+
+// Empty checks if both sources are the same.
+func (d *DiffResult) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Keys returns every key in the diff sorted.
+func (d *DiffResult) Keys() []string {
+	var keys []string
+
+	for _, m := range []map[string]interface{}{d.Added, d.Removed} {
+		for k := range m {
+			keys = append(keys, k)
+		}
+	}
+
+	for k := range d.Changed {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Diff flattens `from` and `to` into key/value sets and compares them.
+// Scope sources are keyed by feature name, all others by "<scope>/<name>".
+func (c *Client) Diff(from Source, to Source) (*DiffResult, error) {
+	fromValues, err := c.SourceValues(from)
+
+	if err != nil {
+		return nil, err
+	}
+
+	toValues, err := c.sourceValues(to, containsKey(fromValues))
+
+	if err != nil {
+		return nil, err
+	}
+
+	// snapshots are flattened using the other source's keys so JSON
+	// features are compared whole rather than as nested scopes
+	if from.Kind == FileSource || from.Kind == SHASource {
+		fromValues, err = c.sourceValues(from, containsKey(toValues))
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result := &DiffResult{
+		From:    from.String(),
+		To:      to.String(),
+		Added:   make(map[string]interface{}),
+		Removed: make(map[string]interface{}),
+		Changed: make(map[string]ValueChange),
+	}
+
+	prev, next := fromValues.Diff(toValues)
+
+	for k, v := range prev {
+		if nv, ok := next[k]; ok {
+			result.Changed[k] = ValueChange{From: v, To: nv}
+		} else {
+			result.Removed[k] = v
+		}
+	}
+
+	for k, v := range next {
+		if _, ok := prev[k]; !ok {
+			result.Added[k] = v
+		}
+	}
+
+	return result, nil
+}
+
+// SourceValues flattens the features of `src` into normalized values by key.
+func (c *Client) SourceValues(src Source) (models.FeatureScopes, error) {
+	return c.sourceValues(src, containsKey(nil))
+}
+
+func (c *Client) sourceValues(src Source, known func(string) bool) (models.FeatureScopes, error) {
+	values := make(models.FeatureScopes)
+
+	switch src.Kind {
+	case ScopeSource:
+		fts, err := c.List("", src.Value)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ft := range fts {
+			if ft.GetScope() == src.Value {
+				values[ft.Key] = normalize(ft.MapValue())
+			}
+		}
+	case NamespaceSource:
+		fts, err := c.listFeatures(fmt.Sprintf("%s/%s/", src.Value, models.FeatureScope))
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ft := range fts {
+			values[scopedPath(ft.GetScope(), ft.Key)] = normalize(ft.MapValue())
+		}
+	case FileSource, SHASource:
+		fm, err := c.sourceFeatureMap(src)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for k, v := range flattenScopes(&fm.Dcdr, known) {
+			values[k] = normalize(v)
+		}
+	default:
+		return nil, ErrInvalidSource
+	}
+
+	return values, nil
+}
+
+func containsKey(fs models.FeatureScopes) func(string) bool {
+	return func(k string) bool {
+		_, ok := fs[k]
+		return ok
+	}
+}
+
+func (c *Client) sourceFeatureMap(src Source) (*models.FeatureMap, error) {
+	if src.Kind == SHASource {
+		if !c.config.GitEnabled() {
+			return nil, ErrGitDisabled
+		}
+
+		if err := c.syncRepo(); err != nil {
+			return nil, err
+		}
+
+		return c.Snapshot(src.Value)
+	}
+
+	bts, err := ioutil.ReadFile(src.Value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if format, ok := exportFormat(bts); ok {
+		fts, err := DecodeFeatures(bts, format, models.DefaultScope)
+
+		if err != nil {
+			return nil, fmt.Errorf("could not parse %s: %v", src.Value, err)
+		}
+
+		return FeaturesToFeatureMap(fts), nil
+	}
+
+	fm, err := models.NewFeatureMap(bts)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not parse %s: %v", src.Value, err)
+	}
+
+	return fm, nil
+}
+
+// exportFormat detects the format of a file written by `dcdr export`.
+// Returns false for feature maps such as the watcher's decider.json.
+func exportFormat(bts []byte) (Format, bool) {
+	var top map[string]json.RawMessage
+
+	if err := json.Unmarshal(bts, &top); err != nil {
+		if bytes.HasPrefix(bts, []byte(dotenvMeta)) || bytes.Contains(bts, []byte("\n"+dotenvMeta)) {
+			return DotenvFormat, true
+		}
+
+		var nested map[string]interface{}
+
+		// invalid JSON that is not YAML either is reported by `NewFeatureMap`
+		if err = yaml.Unmarshal(bts, &nested); err != nil || len(nested) == 0 {
+			return "", false
+		}
+
+		return YAMLFormat, true
+	}
+
+	var root struct {
+		Features json.RawMessage `json:"features"`
+	}
+
+	if raw, ok := top["dcdr"]; ok && json.Unmarshal(raw, &root) == nil && root.Features != nil {
+		return "", false
+	}
+
+	// flat exports are keyed by path with a record as each value while
+	// nested exports hold a map of records for each scope
+	for _, raw := range top {
+		var r struct {
+			FeatureType *models.FeatureType `json:"feature_type"`
+		}
+
+		if json.Unmarshal(raw, &r) == nil && r.FeatureType != nil {
+			return FlatJSONFormat, true
+		}
+	}
+
+	return JSONFormat, true
+}
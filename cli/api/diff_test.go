@@ -0,0 +1,124 @@
+package api
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vsco/dcdr/models"
+)
+
+func TestParseSource(t *testing.T) {
+	src, err := ParseSource("user-groups/beta")
+	assert.NoError(t, err)
+	assert.Equal(t, Source{Kind: ScopeSource, Value: "user-groups/beta"}, src)
+
+	src, err = ParseSource("namespace:staging")
+	assert.NoError(t, err)
+	assert.Equal(t, Source{Kind: NamespaceSource, Value: "staging"}, src)
+
+	src, err = ParseSource("file:/tmp/decider.json")
+	assert.NoError(t, err)
+	assert.Equal(t, Source{Kind: FileSource, Value: "/tmp/decider.json"}, src)
+
+	_, err = ParseSource("store:consul")
+	assert.Equal(t, ErrInvalidSource, err)
+
+	_, err = ParseSource("sha:")
+	assert.Equal(t, ErrInvalidSource, err)
+}
+
+func TestDiffScopes(t *testing.T) {
+	c, _ := historyClient(&historyRepo{},
+		models.NewFeature("flag", 0.1, "", "", "", "dcdr"),
+		models.NewFeature("same", true, "", "", "", "dcdr"),
+		models.NewFeature("removed", true, "", "", "", "dcdr"),
+		models.NewFeature("flag", 0.5, "", "", "user-groups/beta", "dcdr"),
+		models.NewFeature("same", true, "", "", "user-groups/beta", "dcdr"),
+		models.NewFeature("added", "a", "", "", "user-groups/beta", "dcdr"),
+		models.NewFeature("nested", 1.0, "", "", "user-groups/beta/ios", "dcdr"))
+
+	diff, err := c.Diff(Source{ScopeSource, "default"}, Source{ScopeSource, "user-groups/beta"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"added": "a"}, diff.Added)
+	assert.Equal(t, map[string]interface{}{"removed": true}, diff.Removed)
+	assert.Equal(t, map[string]ValueChange{"flag": {From: 0.1, To: 0.5}}, diff.Changed)
+	assert.Equal(t, []string{"added", "flag", "removed"}, diff.Keys())
+	assert.Equal(t, "scope:default", diff.From)
+}
+
+func TestDiffNamespaces(t *testing.T) {
+	c, _ := historyClient(&historyRepo{},
+		models.NewFeature("flag", 0.1, "", "", "", "dcdr"),
+		models.NewFeature("flag", 0.2, "", "", "", "staging"),
+		models.NewFeature("only", true, "", "", "country-codes/us", "staging"))
+
+	diff, err := c.Diff(Source{NamespaceSource, "dcdr"}, Source{NamespaceSource, "staging"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"country-codes/us/only": true}, diff.Added)
+	assert.Equal(t, map[string]ValueChange{"default/flag": {From: 0.1, To: 0.2}}, diff.Changed)
+	assert.Empty(t, diff.Removed)
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	config := models.NewFeature("config", map[string]interface{}{"a": 1.0}, "", "", "user-groups/beta", "dcdr")
+
+	hr := &historyRepo{}
+	hr.commit("a", models.NewFeature("flag", 0.1, "", "", "", "dcdr"), config)
+
+	fm := models.EmptyFeatureMap()
+	explode(fm.Dcdr.FeatureScopes, "default/flag", 0.3)
+	bts, _ := fm.ToJSON()
+
+	fp := filepath.Join(t.TempDir(), "decider.json")
+	assert.NoError(t, ioutil.WriteFile(fp, bts, 0644))
+
+	c, _ := historyClient(hr, models.NewFeature("flag", 0.1, "", "", "", "dcdr"), config)
+
+	diff, err := c.Diff(Source{SHASource, "a"}, Source{NamespaceSource, "dcdr"})
+	assert.NoError(t, err)
+	assert.True(t, diff.Empty())
+
+	diff, err = c.Diff(Source{SHASource, "a"}, Source{FileSource, fp})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]ValueChange{"default/flag": {From: 0.1, To: 0.3}}, diff.Changed)
+	assert.Len(t, diff.Removed, 1)
+
+	_, err = c.Diff(Source{FileSource, "/does/not/exist"}, Source{ScopeSource, "default"})
+	assert.Error(t, err)
+}
+
+func TestDiffExportFiles(t *testing.T) {
+	fts := []*models.Feature{
+		models.NewFeature("flag", 0.1, "c", "u", "", "dcdr"),
+		models.NewFeature("config", map[string]interface{}{"a": 1.0}, "", "", "user-groups/beta", "dcdr"),
+		models.NewFeature("limit", int64(25), "", "", "user-groups/beta", "dcdr"),
+	}
+	fts[2].FeatureType = models.Integer
+
+	c, ms := historyClient(&historyRepo{}, fts...)
+	exported, err := c.List("", "")
+	assert.NoError(t, err)
+
+	changed := models.NewFeature("flag", 0.4, "c", "u", "", "dcdr")
+	bts, _ := changed.ToJSON()
+	ms.Set(changed.ScopedKey(), bts)
+
+	for _, format := range []Format{JSONFormat, FlatJSONFormat, YAMLFormat, DotenvFormat} {
+		// the output of `dcdr export --format <format>`
+		out, err := EncodeFeatures(exported, format)
+		assert.NoError(t, err)
+
+		fp := filepath.Join(t.TempDir(), "export")
+		assert.NoError(t, ioutil.WriteFile(fp, out, 0644))
+
+		diff, err := c.Diff(Source{FileSource, fp}, Source{NamespaceSource, "dcdr"})
+		assert.NoError(t, err, format)
+		assert.Empty(t, diff.Added, format)
+		assert.Empty(t, diff.Removed, format)
+		assert.Equal(t, map[string]ValueChange{"default/flag": {From: 0.1, To: 0.4}}, diff.Changed, format)
+	}
+}
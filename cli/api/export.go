@@ -0,0 +1,380 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vsco/dcdr/models"
+	"gopkg.in/yaml.v3"
+)
+
+// Format an export and import file format.
+type Format string
+
+const (
+	// JSONFormat features nested by scope and name
+	JSONFormat Format = "json"
+	// FlatJSONFormat features keyed by "<scope>/<name>". Keys without a
+	// scope and plain values are also accepted on import.
+	FlatJSONFormat Format = "flat-json"
+	// YAMLFormat features nested by scope and name
+	YAMLFormat Format = "yaml"
+	// DotenvFormat one environment variable per feature preceded by a
+	// comment holding its metadata
+	DotenvFormat Format = "dotenv"
+	// dotenvMeta prefix of the comment holding a feature's metadata
+	dotenvMeta = "# dcdr: "
+)
+
+var (
+	// ErrInvalidFormat returned for unknown formats.
+	ErrInvalidFormat = errors.New("invalid format. use json, flat-json, yaml or dotenv")
+
+	envUnsafe  = regexp.MustCompile(`[^A-Z0-9]+`)
+	envLiteral = regexp.MustCompile(`^[A-Za-z0-9_./:-]*$`)
+)
+
+// ParseFormat validates a user provided format name.
+func ParseFormat(f string) (Format, error) {
+	switch format := Format(strings.ToLower(f)); format {
+	case JSONFormat, FlatJSONFormat, YAMLFormat, DotenvFormat:
+		return format, nil
+	default:
+		return "", ErrInvalidFormat
+	}
+}
+
+// record a `Feature` as written to exports. Namespace, scope, and key
+// are given by the record's position in the export.
+type record struct {
+	FeatureType models.FeatureType `json:"feature_type"`
+	Value       interface{}        `json:"value"`
+	Comment     string             `json:"comment,omitempty"`
+	UpdatedBy   string             `json:"updated_by,omitempty"`
+	Rules       models.Rules       `json:"rules,omitempty"`
+	models.Metadata
+}
+
+// dotenvRecord the metadata comment preceding a dotenv variable.
+type dotenvRecord struct {
+	Scope       string             `json:"scope"`
+	Key         string             `json:"key"`
+	FeatureType models.FeatureType `json:"feature_type"`
+	Comment     string             `json:"comment,omitempty"`
+	UpdatedBy   string             `json:"updated_by,omitempty"`
+	Rules       models.Rules       `json:"rules,omitempty"`
+	models.Metadata
+}
+
+// EncodeFeatures writes `fts` in `format` including their types,
+// comments, rules, metadata, and `UpdatedBy`.
+func EncodeFeatures(fts models.Features, format Format) ([]byte, error) {
+	switch format {
+	case JSONFormat:
+		return json.MarshalIndent(nestedRecords(fts), "", "  ")
+	case FlatJSONFormat:
+		flat := make(map[string]record)
+
+		for _, ft := range fts {
+			flat[scopedPath(ft.GetScope(), ft.Key)] = newRecord(ft)
+		}
+
+		return json.MarshalIndent(flat, "", "  ")
+	case YAMLFormat:
+		return yaml.Marshal(normalize(nestedRecords(fts)))
+	case DotenvFormat:
+		return encodeDotenv(fts)
+	default:
+		return nil, ErrInvalidFormat
+	}
+}
+
+// DecodeFeatures reads features written in `format`. Values without
+// metadata are accepted for JSON formats and keys without a scope are
+// placed in `scope`. Every feature is checked with `ValidateFeature`.
+func DecodeFeatures(bts []byte, format Format, scope string) (models.Features, error) {
+	fts, err := decodeFeatures(bts, format, scope)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var errs ManifestErrors
+
+	for i := range fts {
+		if err = ValidateFeature(&fts[i]); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", scopedPath(fts[i].GetScope(), fts[i].Key), err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return fts, nil
+}
+
+func decodeFeatures(bts []byte, format Format, scope string) (models.Features, error) {
+	switch format {
+	case JSONFormat:
+		var nested map[string]map[string]interface{}
+
+		if err := json.Unmarshal(bts, &nested); err != nil {
+			return nil, err
+		}
+
+		return decodeNested(nested)
+	case YAMLFormat:
+		var nested map[string]map[string]interface{}
+
+		if err := yaml.Unmarshal(bts, &nested); err != nil {
+			return nil, err
+		}
+
+		return decodeNested(nested)
+	case FlatJSONFormat:
+		var flat map[string]interface{}
+
+		if err := json.Unmarshal(bts, &flat); err != nil {
+			return nil, err
+		}
+
+		var fts models.Features
+
+		for k, v := range flat {
+			s := scope
+
+			if i := strings.LastIndex(k, "/"); i >= 0 {
+				s, k = k[:i], k[i+1:]
+			}
+
+			ft, err := decodeRecord(s, k, v)
+
+			if err != nil {
+				return nil, err
+			}
+
+			fts = append(fts, ft)
+		}
+
+		sortFeatures(fts)
+
+		return fts, nil
+	case DotenvFormat:
+		return decodeDotenv(bts)
+	default:
+		return nil, ErrInvalidFormat
+	}
+}
+
+func newRecord(ft models.Feature) record {
+	return record{
+		FeatureType: ft.FeatureType,
+		Value:       ft.Value,
+		Comment:     ft.Comment,
+		UpdatedBy:   ft.UpdatedBy,
+		Rules:       ft.Rules,
+		Metadata:    ft.Metadata,
+	}
+}
+
+func nestedRecords(fts models.Features) map[string]map[string]record {
+	nested := make(map[string]map[string]record)
+
+	for _, ft := range fts {
+		if _, ok := nested[ft.GetScope()]; !ok {
+			nested[ft.GetScope()] = make(map[string]record)
+		}
+
+		nested[ft.GetScope()][ft.Key] = newRecord(ft)
+	}
+
+	return nested
+}
+
+func decodeNested(nested map[string]map[string]interface{}) (models.Features, error) {
+	var fts models.Features
+
+	for scope, kvs := range nested {
+		for k, v := range kvs {
+			ft, err := decodeRecord(scope, k, v)
+
+			if err != nil {
+				return nil, err
+			}
+
+			fts = append(fts, ft)
+		}
+	}
+
+	sortFeatures(fts)
+
+	return fts, nil
+}
+
+// decodeRecord builds a `Feature` from an exported record or a plain value.
+func decodeRecord(scope string, key string, v interface{}) (models.Feature, error) {
+	m, ok := v.(map[string]interface{})
+
+	if _, typed := m["feature_type"]; !ok || !typed {
+		ft := models.NewFeature(key, v, "", "", scope, "")
+		ft.FeatureType = ""
+
+		return *ft, nil
+	}
+
+	bts, err := json.Marshal(m)
+
+	if err != nil {
+		return models.Feature{}, err
+	}
+
+	var r record
+
+	if err = json.Unmarshal(bts, &r); err != nil {
+		return models.Feature{}, fmt.Errorf("invalid record for %s/%s: %v", scope, key, err)
+	}
+
+	return models.Feature{
+		FeatureType: r.FeatureType,
+		Key:         key,
+		Scope:       scope,
+		Value:       r.Value,
+		Comment:     r.Comment,
+		UpdatedBy:   r.UpdatedBy,
+		Rules:       r.Rules,
+		Metadata:    r.Metadata,
+	}, nil
+}
+
+func encodeDotenv(fts models.Features) ([]byte, error) {
+	var buf bytes.Buffer
+
+	sorted := append(models.Features{}, fts...)
+	sortFeatures(sorted)
+
+	for _, ft := range sorted {
+		meta, err := json.Marshal(dotenvRecord{
+			Scope:       ft.GetScope(),
+			Key:         ft.Key,
+			FeatureType: ft.FeatureType,
+			Comment:     ft.Comment,
+			UpdatedBy:   ft.UpdatedBy,
+			Rules:       ft.Rules,
+			Metadata:    ft.Metadata,
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := dotenvValue(ft.Value)
+
+		if err != nil {
+			return nil, err
+		}
+
+		fmt.Fprintf(&buf, "%s%s\n%s=%s\n", dotenvMeta, meta, EnvName(ft), value)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func decodeDotenv(bts []byte) (models.Features, error) {
+	var fts models.Features
+	var meta *dotenvRecord
+
+	scanner := bufio.NewScanner(bytes.NewReader(bts))
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if strings.HasPrefix(line, dotenvMeta) {
+			meta = &dotenvRecord{}
+
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, dotenvMeta)), meta); err != nil {
+				return nil, fmt.Errorf("invalid metadata %q: %v", line, err)
+			}
+
+			continue
+		}
+
+		pts := strings.SplitN(line, "=", 2)
+
+		if meta == nil || len(pts) != 2 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		raw := pts[1]
+
+		if uq, err := strconv.Unquote(raw); err == nil {
+			raw = uq
+		} else if len(raw) > 1 && strings.HasPrefix(raw, "'") && strings.HasSuffix(raw, "'") {
+			raw = raw[1 : len(raw)-1]
+		}
+
+		v, ft := models.ParseValueForFeatureType(raw, meta.FeatureType)
+
+		if ft == models.Invalid {
+			return nil, fmt.Errorf("invalid %s value for %s: %s", meta.FeatureType, pts[0], pts[1])
+		}
+
+		fts = append(fts, models.Feature{
+			FeatureType: ft,
+			Key:         meta.Key,
+			Scope:       meta.Scope,
+			Value:       v,
+			Comment:     meta.Comment,
+			UpdatedBy:   meta.UpdatedBy,
+			Rules:       meta.Rules,
+			Metadata:    meta.Metadata,
+		})
+
+		meta = nil
+	}
+
+	return fts, scanner.Err()
+}
+
+// EnvName the environment variable name for `ft`, for example
+// "DCDR_USER_GROUPS_BETA_NEW_FEATURE".
+func EnvName(ft models.Feature) string {
+	name := strings.ToUpper(fmt.Sprintf("%s_%s_%s", ft.Namespace, ft.GetScope(), ft.Key))
+
+	return strings.Trim(envUnsafe.ReplaceAllString(name, "_"), "_")
+}
+
+func dotenvValue(v interface{}) (string, error) {
+	if s, ok := v.(string); ok {
+		if envLiteral.MatchString(s) {
+			return s, nil
+		}
+
+		return strconv.Quote(s), nil
+	}
+
+	bts, err := json.Marshal(v)
+
+	if err != nil {
+		return "", err
+	}
+
+	if envLiteral.MatchString(string(bts)) {
+		return string(bts), nil
+	}
+
+	return fmt.Sprintf("'%s'", bts), nil
+}
+
+func sortFeatures(fts models.Features) {
+	sort.Slice(fts, func(i, j int) bool {
+		return scopedPath(fts[i].GetScope(), fts[i].Key) < scopedPath(fts[j].GetScope(), fts[j].Key)
+	})
+}
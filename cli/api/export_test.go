@@ -0,0 +1,116 @@
+package api
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vsco/dcdr/models"
+)
+
+func exportFeatures() models.Features {
+	targeted := models.NewFeature("targeted", 0.5, "targeted rollout", "alice", "user-groups/beta", "dcdr")
+	targeted.Rules = models.Rules{{Attribute: "country", Operator: models.In, Values: []string{"us", "ca"}}}
+	targeted.Owner = "growth"
+	targeted.Tags = []string{"experiment"}
+	exp := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	targeted.ExpiresAt = &exp
+
+	limit := models.NewFeature("limit", int64(50), "", "bob", "", "dcdr")
+	limit.Value = 50.0
+
+	return models.Features{
+		*models.NewFeature("flag", true, "a boolean", "alice", "", "dcdr"),
+		*limit,
+		*models.NewFeature("greeting", "hello world", "", "", "", "dcdr"),
+		*models.NewFeature("config", map[string]interface{}{"a": 1.0}, "", "", "country-codes/us", "dcdr"),
+		*targeted,
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	f, err := ParseFormat("YAML")
+	assert.NoError(t, err)
+	assert.Equal(t, YAMLFormat, f)
+
+	_, err = ParseFormat("toml")
+	assert.Equal(t, ErrInvalidFormat, err)
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	fts := exportFeatures()
+	expected := append(models.Features{}, fts...)
+	sortFeatures(expected)
+
+	for i := range expected {
+		expected[i].Namespace = ""
+	}
+
+	for _, format := range []Format{JSONFormat, FlatJSONFormat, YAMLFormat, DotenvFormat} {
+		bts, err := EncodeFeatures(fts, format)
+		assert.NoError(t, err, format)
+
+		decoded, err := DecodeFeatures(bts, format, models.DefaultScope)
+		assert.NoError(t, err, format)
+		assert.Len(t, decoded, len(fts), format)
+
+		for i := range decoded {
+			assert.Equal(t, expected[i].ScopedKey(), decoded[i].ScopedKey(), format)
+			assert.Equal(t, expected[i].FeatureType, decoded[i].FeatureType, format)
+			assert.Equal(t, expected[i].Comment, decoded[i].Comment, format)
+			assert.Equal(t, expected[i].UpdatedBy, decoded[i].UpdatedBy, format)
+			assert.Equal(t, expected[i].Rules, decoded[i].Rules, format)
+			assert.Equal(t, expected[i].Metadata, decoded[i].Metadata, format)
+			assert.Equal(t, normalize(expected[i].Value), normalize(decoded[i].Value), format)
+		}
+	}
+}
+
+func TestExportDotenv(t *testing.T) {
+	bts, err := EncodeFeatures(exportFeatures(), DotenvFormat)
+	assert.NoError(t, err)
+
+	out := string(bts)
+	assert.Contains(t, out, "DCDR_DEFAULT_FLAG=true\n")
+	assert.Contains(t, out, "DCDR_DEFAULT_GREETING=\"hello world\"\n")
+	assert.Contains(t, out, "DCDR_COUNTRY_CODES_US_CONFIG='{\"a\":1}'\n")
+	assert.Contains(t, out, `# dcdr: {"scope":"user-groups/beta","key":"targeted"`)
+
+	// values can be edited in place
+	edited := strings.Replace(out, "DCDR_DEFAULT_FLAG=true", "DCDR_DEFAULT_FLAG=false", 1)
+	fts, err := DecodeFeatures([]byte(edited), DotenvFormat, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "flag", fts[1].Key)
+	assert.Equal(t, false, fts[1].Value)
+
+	_, err = DecodeFeatures([]byte("# dcdr: {\"key\":\"a\",\"feature_type\":\"integer\"}\nA=nope\n"), DotenvFormat, "")
+	assert.Error(t, err)
+}
+
+func TestImportFlatValues(t *testing.T) {
+	fts, err := DecodeFeatures([]byte(`{"some-feature": true, "some-other-feature": 0.5, "limit": 50, "user-groups/beta/new": "on"}`), FlatJSONFormat, "country-codes/us")
+
+	assert.NoError(t, err)
+	assert.Len(t, fts, 4)
+
+	assert.Equal(t, "country-codes/us/limit", scopedPath(fts[0].Scope, fts[0].Key))
+	assert.Equal(t, models.Integer, fts[0].FeatureType)
+	assert.Equal(t, models.Boolean, fts[1].FeatureType)
+	assert.Equal(t, models.Percentile, fts[2].FeatureType)
+	assert.Equal(t, "user-groups/beta", fts[3].Scope)
+	assert.Equal(t, models.String, fts[3].FeatureType)
+}
+
+func TestImportValidates(t *testing.T) {
+	_, err := DecodeFeatures([]byte(`{"x": 1.5}`), FlatJSONFormat, "")
+	assert.True(t, errors.Is(err.(ManifestErrors)[0], ErrInvalidRange))
+
+	_, err = DecodeFeatures([]byte(`{"default": {"a": {"feature_type": "boolean", "value": "yes"}}}`), JSONFormat, "")
+	assert.True(t, errors.Is(err.(ManifestErrors)[0], ErrInvalidValue))
+
+	fts, err := DecodeFeatures([]byte(`{"default": {"limit": {"feature_type": "integer", "value": 50}}}`), JSONFormat, "")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(50), fts[0].Value)
+}
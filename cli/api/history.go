@@ -0,0 +1,376 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/vsco/dcdr/cli/repo"
+	"github.com/vsco/dcdr/models"
+)
+
+// ErrGitDisabled returned when reading history without a configured repo.
+var ErrGitDisabled = errors.New("no repository configured")
+
+// FeatureRevision the value of a feature following a change in the audit repo.
+type FeatureRevision struct {
+	repo.Revision
+	Value   interface{}
+	Deleted bool
+}
+
+// Change a feature to be set or deleted in the store.
+type Change struct {
+	Feature  models.Feature
+	Previous *models.Feature
+	Deleted  bool
+}
+
+// History walks the audit repo log and returns each change to the value
+// of `key` within `scope`, newest first.
+func (c *Client) History(key string, scope string) ([]FeatureRevision, error) {
+	revs, err := c.revisions()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var history []FeatureRevision
+	var prev interface{}
+	var existed bool
+
+	for i := len(revs) - 1; i >= 0; i-- {
+		fm, err := c.Snapshot(revs[i].SHA)
+
+		if err != nil {
+			return nil, err
+		}
+
+		v, ok := fm.Dcdr.InScope(scope)[key]
+		v = normalize(fm.Dcdr.TargetedValue(scopedPath(scope, key), v))
+
+		if ok == existed && reflect.DeepEqual(v, prev) {
+			continue
+		}
+
+		if ok || existed {
+			history = append([]FeatureRevision{{
+				Revision: revs[i],
+				Value:    v,
+				Deleted:  !ok,
+			}}, history...)
+		}
+
+		prev, existed = v, ok
+	}
+
+	return history, nil
+}
+
+// Snapshot returns the `FeatureMap` committed to the audit repo at `sha`.
+func (c *Client) Snapshot(sha string) (*models.FeatureMap, error) {
+	bts, err := c.Repo.Show(sha)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(strings.TrimSpace(string(bts))) == 0 {
+		return models.EmptyFeatureMap(), nil
+	}
+
+	fm, err := models.NewFeatureMap(bts)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not parse features at %s: %v", sha, err)
+	}
+
+	if fm.Dcdr.FeatureScopes == nil {
+		fm.Dcdr.FeatureScopes = models.FeatureScopes{}
+	}
+
+	return fm, nil
+}
+
+// Rollback restores the features in the store to their state at `sha`
+// using `Set` and `Delete`. When `key` is empty every feature in the
+// namespace is restored, otherwise only `key` within `scope`. The
+// applied changes are returned and are not committed. If the store fails
+// partway through, changes already applied are reverted and none are
+// returned.
+func (c *Client) Rollback(sha string, key string, scope string) ([]Change, error) {
+	if !c.config.GitEnabled() {
+		return nil, ErrGitDisabled
+	}
+
+	if err := c.syncRepo(); err != nil {
+		return nil, err
+	}
+
+	fm, err := c.Snapshot(sha)
+
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := c.Plan(fm, key, scope)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = c.ApplyAll(changes); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// Plan returns the changes needed for the store to match `fm`. When
+// `key` is empty every feature in the namespace is compared, otherwise
+// only `key` within `scope`. Features keep their current comment and
+// type where they still exist.
+func (c *Client) Plan(fm *models.FeatureMap, key string, scope string) ([]Change, error) {
+	current, err := c.List("", "")
+
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make(map[string]models.Feature)
+
+	for _, ft := range current {
+		if key == "" || (ft.Key == key && ft.GetScope() == scope) {
+			existing[scopedPath(ft.GetScope(), ft.Key)] = ft
+		}
+	}
+
+	target := make(map[string]interface{})
+
+	if key == "" {
+		target = flattenScopes(&fm.Dcdr, func(path string) bool {
+			_, ok := existing[path]
+			return ok
+		})
+	} else if v, ok := fm.Dcdr.InScope(scope)[key]; ok {
+		target[scopedPath(scope, key)] = fm.Dcdr.TargetedValue(scopedPath(scope, key), v)
+	}
+
+	var changes []Change
+
+	for path, v := range target {
+		ft, ok := existing[path]
+
+		if ok && reflect.DeepEqual(normalize(ft.MapValue()), normalize(v)) {
+			continue
+		}
+
+		change := Change{Feature: *c.restoredFeature(&fm.Dcdr, path, v, ft, ok)}
+
+		if ok {
+			prev := ft
+			change.Previous = &prev
+		}
+
+		changes = append(changes, change)
+	}
+
+	for path, ft := range existing {
+		if _, ok := target[path]; ok {
+			continue
+		}
+
+		prev := ft
+		ft.UpdatedBy = c.config.Username
+		changes = append(changes, Change{Feature: ft, Previous: &prev, Deleted: true})
+	}
+
+	sortChanges(changes)
+
+	return changes, nil
+}
+
+// Apply writes `changes` to the store using `Set` and `Delete`. Features
+// whose type has changed are deleted and set again.
+func (c *Client) Apply(changes []Change) error {
+	for i := range changes {
+		ft := &changes[i].Feature
+
+		if changes[i].Deleted {
+			if err := c.Delete(ft.Key, ft.GetScope()); err != nil {
+				return fmt.Errorf("could not delete %s: %v", ft.ScopedKey(), err)
+			}
+
+			continue
+		}
+
+		err := c.Set(ft)
+
+		if err == ErrTypeChange {
+			if err = c.Delete(ft.Key, ft.GetScope()); err == nil {
+				err = c.Set(ft)
+			}
+		}
+
+		if err != nil {
+			return fmt.Errorf("could not set %s: %v", ft.ScopedKey(), err)
+		}
+	}
+
+	return nil
+}
+
+// restoredFeature builds the feature at `path` with the value `v` and the
+// type and metadata recorded in the snapshot `d`. Metadata missing from
+// the snapshot is kept from the existing feature by `Set`.
+func (c *Client) restoredFeature(d *models.Root, path string, v interface{}, existing models.Feature, ok bool) *models.Feature {
+	md := d.Metadata[path]
+	i := strings.LastIndex(path, "/")
+	ft := models.NewFeature(path[i+1:], nil, "", c.config.Username, path[:i], c.Namespace())
+	ft.Rules = models.Rules{}
+
+	if tv, targeted := models.TargetedValueFromValue(v); targeted {
+		ft.Rules = tv.Rules
+		v = tv.Value
+	}
+
+	ft.Value = v
+	ft.FeatureType = d.TypeOf(path, v)
+	_, recorded := d.Types[path]
+	ft.Owner = md.Owner
+	ft.Tags = md.Tags
+	ft.CreatedAt = md.CreatedAt
+	ft.ExpiresAt = md.ExpiresAt
+
+	if ok {
+		ft.Comment = existing.Comment
+
+		if !recorded && compatibleTypes(existing.FeatureType, ft.FeatureType) {
+			ft.FeatureType = existing.FeatureType
+		}
+	}
+
+	if f, isFloat := v.(float64); isFloat && ft.FeatureType == models.Integer {
+		ft.Value = int64(f)
+	}
+
+	return ft
+}
+
+// compatibleTypes checks if a value inferred as `inferred` from a snapshot
+// without recorded types can be stored as `current`. Small integers look
+// like percentiles and JSON arrays can look like variants once decoded.
+func compatibleTypes(current models.FeatureType, inferred models.FeatureType) bool {
+	switch {
+	case current == inferred:
+		return true
+	case current == models.Integer && inferred == models.Percentile:
+		return true
+	case current == models.JSON && inferred == models.Variant:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *Client) revisions() ([]repo.Revision, error) {
+	if !c.config.GitEnabled() {
+		return nil, ErrGitDisabled
+	}
+
+	if err := c.syncRepo(); err != nil {
+		return nil, err
+	}
+
+	return c.Repo.Log()
+}
+
+// syncRepo clones the audit repo if needed and pulls when it has an origin.
+func (c *Client) syncRepo() error {
+	if !c.Repo.Exists() {
+		return c.Repo.Clone()
+	}
+
+	if c.config.PushEnabled() {
+		return c.Repo.Pull()
+	}
+
+	return nil
+}
+
+// flattenScopes collects the feature values nested in `d` by their
+// scoped path, wrapping targeted features with their rules. Features are
+// found by their recorded types. Snapshots written before types were
+// recorded are walked treating maps as nested scopes unless they are
+// targeted values, in the default scope, or `known` feature paths.
+func flattenScopes(d *models.Root, known func(path string) bool) map[string]interface{} {
+	target := make(map[string]interface{})
+
+	if d.Types != nil {
+		for path := range d.Types {
+			i := strings.LastIndex(path, "/")
+
+			if v, ok := d.InScope(path[:i])[path[i+1:]]; ok {
+				target[path] = v
+			}
+		}
+	} else {
+		for scope, v := range d.FeatureScopes {
+			flatten(target, known, scope, v)
+		}
+	}
+
+	for path, v := range target {
+		target[path] = d.TargetedValue(path, v)
+	}
+
+	return target
+}
+
+func flatten(target map[string]interface{}, known func(path string) bool, path string, v interface{}) {
+	m, isMap := v.(map[string]interface{})
+	_, targeted := models.TargetedValueFromValue(v)
+	leaf := strings.Contains(path, "/") &&
+		(!isMap || targeted || known(path) || strings.HasPrefix(path, models.DefaultScope+"/"))
+
+	if leaf {
+		target[path] = v
+		return
+	}
+
+	if !isMap {
+		return
+	}
+
+	for k, nv := range m {
+		flatten(target, known, scopedPath(path, k), nv)
+	}
+}
+
+func sortChanges(changes []Change) {
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Feature.ScopedKey() < changes[j].Feature.ScopedKey()
+	})
+}
+
+func scopedPath(scope string, key string) string {
+	return fmt.Sprintf("%s/%s", scope, key)
+}
+
+// normalize round trips `v` through JSON so values read from the store
+// and from the audit repo can be compared.
+func normalize(v interface{}) interface{} {
+	bts, err := json.Marshal(v)
+
+	if err != nil {
+		return v
+	}
+
+	var n interface{}
+	json.Unmarshal(bts, &n)
+
+	return n
+}
@@ -0,0 +1,331 @@
+package api
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vsco/dcdr/cli/api/stores"
+	"github.com/vsco/dcdr/cli/repo"
+	"github.com/vsco/dcdr/config"
+	"github.com/vsco/dcdr/models"
+)
+
+type historyRepo struct {
+	stores.MockRepo
+	revs  []repo.Revision
+	files map[string][]byte
+}
+
+func (hr *historyRepo) Exists() bool {
+	return true
+}
+
+func (hr *historyRepo) Log() ([]repo.Revision, error) {
+	return hr.revs, nil
+}
+
+func (hr *historyRepo) Show(sha string) ([]byte, error) {
+	if sha == "HEAD" {
+		sha, _ = hr.CurrentSHA()
+	}
+
+	return hr.files[sha], nil
+}
+
+func (hr *historyRepo) Contains(sha string) (bool, error) {
+	_, ok := hr.files[sha]
+
+	return ok, nil
+}
+
+func (hr *historyRepo) CurrentSHA() (string, error) {
+	return hr.revs[0].SHA, nil
+}
+
+// commit prepends a revision with `fts` as the committed feature set.
+func (hr *historyRepo) commit(sha string, fts ...*models.Feature) {
+	fm := models.EmptyFeatureMap()
+
+	for _, ft := range fts {
+		explode(fm.Dcdr.FeatureScopes, scopedPath(ft.GetScope(), ft.Key), ft.Value)
+		fm.Dcdr.AddType(scopedPath(ft.GetScope(), ft.Key), ft.FeatureType)
+		fm.Dcdr.AddRules(scopedPath(ft.GetScope(), ft.Key), ft.Rules)
+		fm.Dcdr.AddMetadata(scopedPath(ft.GetScope(), ft.Key), ft.Metadata)
+	}
+
+	bts, _ := fm.ToJSON()
+
+	if hr.files == nil {
+		hr.files = make(map[string][]byte)
+	}
+
+	hr.files[sha] = bts
+	hr.revs = append([]repo.Revision{{
+		SHA:     sha,
+		Author:  "dcdr",
+		Date:    time.Unix(int64(len(hr.revs)), 0),
+		Message: "commit " + sha,
+	}}, hr.revs...)
+}
+
+type memStore struct {
+	stores.MockStore
+	kvs      map[string][]byte
+	versions map[string]uint64
+}
+
+func (ms *memStore) List(prefix string) (stores.KVBytes, error) {
+	var kvb stores.KVBytes
+
+	for k, v := range ms.kvs {
+		if strings.HasPrefix(k, prefix) {
+			kvb = append(kvb, &stores.KVByte{Key: k, Bytes: v})
+		}
+	}
+
+	return kvb, nil
+}
+
+func (ms *memStore) Get(key string) (*stores.KVByte, error) {
+	if v, ok := ms.kvs[key]; ok {
+		return &stores.KVByte{Key: key, Bytes: v, Version: ms.versions[key]}, nil
+	}
+
+	return nil, nil
+}
+
+func (ms *memStore) Set(key string, bts []byte) error {
+	if ms.versions == nil {
+		ms.versions = make(map[string]uint64)
+	}
+
+	ms.kvs[key] = bts
+	ms.versions[key]++
+
+	return nil
+}
+
+func (ms *memStore) SetCAS(key string, bts []byte, version uint64) error {
+	if ms.versions[key] != version {
+		return stores.ErrConflict
+	}
+
+	return ms.Set(key, bts)
+}
+
+func (ms *memStore) Delete(key string) error {
+	delete(ms.kvs, key)
+	delete(ms.versions, key)
+	return nil
+}
+
+func historyClient(hr *historyRepo, fts ...*models.Feature) (*Client, *memStore) {
+	cfg := config.DefaultConfig()
+	cfg.Git.RepoPath = "/tmp/dcdr-audit"
+	cfg.Username = "rollbacker"
+
+	ms := &memStore{kvs: make(map[string][]byte)}
+
+	for _, ft := range fts {
+		bts, _ := ft.ToJSON()
+		ms.Set(ft.ScopedKey(), bts)
+	}
+
+	return New(ms, hr, cfg, nil), ms
+}
+
+func TestRollbackReverts(t *testing.T) {
+	hr := &historyRepo{}
+	hr.commit("a",
+		models.NewFeature("flag", 0.1, "", "", "", "dcdr"),
+		models.NewFeature("zzz", true, "", "", "", "dcdr"))
+
+	c, ms := historyClient(hr, models.NewFeature("flag", 0.5, "", "", "", "dcdr"))
+	c.Store = &failingStore{memStore: ms, failOn: "dcdr/features/default/zzz"}
+
+	changes, err := c.Rollback("a", "", models.DefaultScope)
+
+	assert.Error(t, err)
+	assert.Empty(t, changes)
+
+	var ft models.Feature
+	json.Unmarshal(ms.kvs["dcdr/features/default/flag"], &ft)
+	assert.Equal(t, 0.5, ft.Value)
+}
+
+func TestRollbackUntypedSnapshot(t *testing.T) {
+	hr := &historyRepo{}
+	hr.commit("a")
+	hr.files["a"] = []byte(`{"dcdr": {"features": {
+		"default": {"flag": 1, "config": {"a": 1}},
+		"beta": {"limit": 1}
+	}}}`)
+
+	c, ms := historyClient(hr,
+		models.NewFeature("config", map[string]interface{}{"a": 2.0}, "", "", "", "dcdr"),
+		models.NewFeature("limit", int64(5), "", "", "beta", "dcdr"))
+
+	changes, err := c.Rollback("a", "", models.DefaultScope)
+
+	assert.NoError(t, err)
+	assert.Len(t, changes, 3)
+
+	var ft models.Feature
+	json.Unmarshal(ms.kvs["dcdr/features/default/flag"], &ft)
+	assert.Equal(t, models.Percentile, ft.FeatureType)
+
+	json.Unmarshal(ms.kvs["dcdr/features/beta/limit"], &ft)
+	assert.Equal(t, models.Integer, ft.FeatureType)
+	assert.Equal(t, 1.0, ft.Value)
+}
+
+func TestHistory(t *testing.T) {
+	hr := &historyRepo{}
+	hr.commit("a", models.NewFeature("flag", 0.1, "", "", "", "dcdr"))
+	hr.commit("b", models.NewFeature("flag", 0.1, "", "", "", "dcdr"), models.NewFeature("other", true, "", "", "", "dcdr"))
+	hr.commit("c", models.NewFeature("flag", 0.5, "", "", "", "dcdr"))
+	hr.commit("d")
+	hr.commit("e", models.NewFeature("flag", 1.0, "", "", "", "dcdr"))
+
+	c, _ := historyClient(hr)
+	revs, err := c.History("flag", models.DefaultScope)
+
+	assert.NoError(t, err)
+	assert.Len(t, revs, 4)
+
+	assert.Equal(t, "e", revs[0].SHA)
+	assert.Equal(t, 1.0, revs[0].Value)
+	assert.Equal(t, "d", revs[1].SHA)
+	assert.True(t, revs[1].Deleted)
+	assert.Equal(t, "c", revs[2].SHA)
+	assert.Equal(t, 0.5, revs[2].Value)
+	assert.Equal(t, "a", revs[3].SHA)
+	assert.Equal(t, "dcdr", revs[3].Author)
+
+	revs, err = c.History("missing", models.DefaultScope)
+	assert.NoError(t, err)
+	assert.Empty(t, revs)
+}
+
+func TestHistoryGitDisabled(t *testing.T) {
+	c := New(stores.NewMockStore(nil, nil), &stores.MockRepo{}, config.DefaultConfig(), nil)
+
+	_, err := c.History("flag", models.DefaultScope)
+	assert.Equal(t, ErrGitDisabled, err)
+
+	_, err = c.Rollback("a", "", models.DefaultScope)
+	assert.Equal(t, ErrGitDisabled, err)
+}
+
+func TestRollbackFeature(t *testing.T) {
+	hr := &historyRepo{}
+	hr.commit("a",
+		models.NewFeature("flag", 0.1, "", "", "", "dcdr"),
+		models.NewFeature("flag", 0.2, "", "", "user-groups/beta", "dcdr"))
+
+	c, ms := historyClient(hr,
+		models.NewFeature("flag", 0.9, "rollout", "", "", "dcdr"),
+		models.NewFeature("flag", 0.8, "", "", "user-groups/beta", "dcdr"))
+
+	changes, err := c.Rollback("a", "flag", models.DefaultScope)
+
+	assert.NoError(t, err)
+	assert.Len(t, changes, 1)
+	assert.Equal(t, 0.1, changes[0].Feature.Value)
+	assert.Equal(t, "rollout", changes[0].Feature.Comment)
+	assert.Equal(t, "rollbacker", changes[0].Feature.UpdatedBy)
+
+	var ft models.Feature
+	json.Unmarshal(ms.kvs["dcdr/features/user-groups/beta/flag"], &ft)
+	assert.Equal(t, 0.8, ft.Value)
+
+	changes, err = c.Rollback("a", "flag", models.DefaultScope)
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+}
+
+func TestRollbackMetadata(t *testing.T) {
+	committed := models.NewFeature("flag", 0.1, "", "", "", "dcdr")
+	committed.Owner = "growth"
+	committed.Tags = []string{"checkout"}
+
+	hr := &historyRepo{}
+	hr.commit("a", committed)
+
+	current := models.NewFeature("flag", 0.9, "", "", "", "dcdr")
+	current.Owner = "search"
+
+	c, ms := historyClient(hr, current)
+
+	changes, err := c.Rollback("a", "flag", models.DefaultScope)
+	assert.NoError(t, err)
+	assert.Len(t, changes, 1)
+
+	var ft models.Feature
+	json.Unmarshal(ms.kvs[current.ScopedKey()], &ft)
+	assert.Equal(t, 0.1, ft.Value)
+	assert.Equal(t, "growth", ft.Owner)
+	assert.Equal(t, []string{"checkout"}, ft.Tags)
+}
+
+func TestRollbackNamespace(t *testing.T) {
+	targeted := models.NewFeature("targeted", true, "", "", "", "dcdr")
+	targeted.Rules = models.Rules{{Attribute: "country", Operator: models.In, Values: []string{"us"}}}
+
+	hr := &historyRepo{}
+	hr.commit("a",
+		models.NewFeature("flag", 0.1, "", "", "", "dcdr"),
+		models.NewFeature("config", map[string]interface{}{"a": 1.0}, "", "", "", "dcdr"),
+		models.NewFeature("limit", int64(50), "", "", "country-codes/us", "dcdr"),
+		models.NewFeature("cfg", map[string]interface{}{"a": 0.5, "b": true}, "", "", "beta", "dcdr"),
+		models.NewFeature("retries", int64(1), "", "", "beta", "dcdr"),
+		targeted)
+
+	c, ms := historyClient(hr,
+		models.NewFeature("flag", 0.1, "", "", "", "dcdr"),
+		models.NewFeature("targeted", false, "", "", "", "dcdr"),
+		models.NewFeature("added", true, "", "", "user-groups/beta", "dcdr"))
+
+	changes, err := c.Rollback("a", "", models.DefaultScope)
+
+	assert.NoError(t, err)
+	assert.Len(t, changes, 6)
+
+	keys := make(map[string]bool)
+
+	for _, change := range changes {
+		keys[change.Feature.ScopedKey()] = change.Deleted
+	}
+
+	assert.Equal(t, map[string]bool{
+		"dcdr/features/default/config":         false,
+		"dcdr/features/default/targeted":       false,
+		"dcdr/features/country-codes/us/limit": false,
+		"dcdr/features/beta/cfg":               false,
+		"dcdr/features/beta/retries":           false,
+		"dcdr/features/user-groups/beta/added": true,
+	}, keys)
+
+	var cfg models.Feature
+	json.Unmarshal(ms.kvs["dcdr/features/beta/cfg"], &cfg)
+	assert.Equal(t, models.JSON, cfg.FeatureType)
+	assert.Equal(t, map[string]interface{}{"a": 0.5, "b": true}, cfg.Value)
+
+	var retries models.Feature
+	json.Unmarshal(ms.kvs["dcdr/features/beta/retries"], &retries)
+	assert.Equal(t, models.Integer, retries.FeatureType)
+
+	var ft models.Feature
+	json.Unmarshal(ms.kvs["dcdr/features/country-codes/us/limit"], &ft)
+	assert.Equal(t, models.Integer, ft.FeatureType)
+
+	json.Unmarshal(ms.kvs["dcdr/features/default/targeted"], &ft)
+	assert.True(t, ft.Targeted())
+	assert.Equal(t, true, ft.Value)
+
+	_, ok := ms.kvs["dcdr/features/user-groups/beta/added"]
+	assert.False(t, ok)
+}
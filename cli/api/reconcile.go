@@ -0,0 +1,66 @@
+package api
+
+import "errors"
+
+// ErrRepoBehind returned when the store has changes that are not in the audit repo.
+var ErrRepoBehind = errors.New("audit repo is behind the store's info/current_sha. commit or pull the missing changes before reconciling")
+
+// Reconcile makes the store match the features committed at the HEAD of
+// the audit repo and returns the changes needed to do so. The repo is
+// pulled first and nothing is applied if the store's info/current_sha
+// is not in its history. With `dryRun` the changes are only planned.
+func (c *Client) Reconcile(dryRun bool) ([]Change, error) {
+	if !c.config.GitEnabled() {
+		return nil, ErrGitDisabled
+	}
+
+	if err := c.syncRepo(); err != nil {
+		return nil, err
+	}
+
+	info, err := c.GetInfo()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if info.CurrentSHA != "" {
+		ok, err := c.Repo.Contains(info.CurrentSHA)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			return nil, ErrRepoBehind
+		}
+	}
+
+	fm, err := c.Snapshot("HEAD")
+
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := c.Plan(fm, "", "")
+
+	if err != nil || dryRun {
+		return changes, err
+	}
+
+	err = c.Apply(changes)
+
+	if err != nil {
+		return changes, err
+	}
+
+	sha, err := c.Repo.CurrentSHA()
+
+	if err != nil || sha == info.CurrentSHA {
+		return changes, err
+	}
+
+	_, err = c.UpdateCurrentSHA()
+
+	return changes, err
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vsco/dcdr/models"
+)
+
+func TestReconcile(t *testing.T) {
+	hr := &historyRepo{}
+	hr.commit("a", models.NewFeature("flag", 0.1, "", "", "", "dcdr"))
+	hr.commit("b",
+		models.NewFeature("flag", 0.5, "", "", "", "dcdr"),
+		models.NewFeature("limit", int64(50), "", "", "", "dcdr"))
+
+	c, ms := historyClient(hr,
+		models.NewFeature("flag", 0.1, "", "", "", "dcdr"),
+		models.NewFeature("removed", true, "", "", "user-groups/beta", "dcdr"))
+
+	info, _ := json.Marshal(models.Info{CurrentSHA: "a"})
+	ms.kvs["dcdr/info"] = info
+
+	changes, err := c.Reconcile(true)
+	assert.NoError(t, err)
+	assert.Len(t, changes, 3)
+	assert.Len(t, ms.kvs, 3)
+
+	changes, err = c.Reconcile(false)
+	assert.NoError(t, err)
+	assert.Len(t, changes, 3)
+
+	var ft models.Feature
+	json.Unmarshal(ms.kvs["dcdr/features/default/flag"], &ft)
+	assert.Equal(t, 0.5, ft.Value)
+
+	_, ok := ms.kvs["dcdr/features/user-groups/beta/removed"]
+	assert.False(t, ok)
+
+	var updated models.Info
+	json.Unmarshal(ms.kvs["dcdr/info"], &updated)
+	assert.Equal(t, "b", updated.CurrentSHA)
+
+	changes, err = c.Reconcile(false)
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+}
+
+func TestReconcileTypeChange(t *testing.T) {
+	hr := &historyRepo{}
+	hr.commit("a", models.NewFeature("flag", "on", "", "", "", "dcdr"))
+
+	c, ms := historyClient(hr, models.NewFeature("flag", true, "", "", "", "dcdr"))
+
+	_, err := c.Reconcile(false)
+	assert.NoError(t, err)
+
+	var ft models.Feature
+	json.Unmarshal(ms.kvs["dcdr/features/default/flag"], &ft)
+	assert.Equal(t, models.String, ft.FeatureType)
+	assert.Equal(t, "on", ft.Value)
+}
+
+func TestReconcileRepoBehind(t *testing.T) {
+	hr := &historyRepo{}
+	hr.commit("a", models.NewFeature("flag", 0.1, "", "", "", "dcdr"))
+
+	c, ms := historyClient(hr, models.NewFeature("flag", 0.9, "", "", "", "dcdr"))
+
+	info, _ := json.Marshal(models.Info{CurrentSHA: "unpushed"})
+	ms.kvs["dcdr/info"] = info
+
+	_, err := c.Reconcile(false)
+	assert.Equal(t, ErrRepoBehind, err)
+
+	var ft models.Feature
+	json.Unmarshal(ms.kvs["dcdr/features/default/flag"], &ft)
+	assert.Equal(t, 0.9, ft.Value)
+}
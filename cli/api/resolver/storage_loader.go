@@ -5,15 +5,22 @@ import (
 
 	"github.com/vsco/dcdr/cli/api/stores"
 	"github.com/vsco/dcdr/cli/api/stores/consul"
+	"github.com/vsco/dcdr/cli/api/stores/etcd"
+	"github.com/vsco/dcdr/cli/api/stores/file"
 	"github.com/vsco/dcdr/cli/api/stores/redis"
 	"github.com/vsco/dcdr/config"
 )
 
 func LoadStore(cfg *config.Config) stores.IFace {
 	switch cfg.Storage {
-	case "etcd":
-		log.Fatal("etcd is no longer supported")
-		return nil
+	case config.StorageEtcd:
+		e, err := etcd.NewDefault(cfg)
+
+		if err != nil {
+			log.Fatalf("could not load etcd: %v", err)
+		}
+
+		return e
 	case "redis":
 		r, err := redis.New(cfg)
 
@@ -22,6 +29,14 @@ func LoadStore(cfg *config.Config) stores.IFace {
 		}
 
 		return r
+	case config.StorageFile:
+		f, err := file.New(cfg)
+
+		if err != nil {
+			log.Fatalf("could not load file store: %v", err)
+		}
+
+		return f
 	default:
 		c, err := consul.NewDefault(cfg)
 
@@ -0,0 +1,352 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vsco/dcdr/models"
+)
+
+const (
+	// LocksNameSpace keys holding locks shared by replicas.
+	LocksNameSpace = "locks"
+	// SchedulerLock the lock held by the active `dcdr scheduler`.
+	SchedulerLock = "scheduler"
+)
+
+var (
+	// ErrScheduleType returned when scheduling a feature type without scalar values.
+	ErrScheduleType = errors.New("schedules support percentile, boolean, integer and string features")
+	// ErrEmptySchedule returned for schedules without steps.
+	ErrEmptySchedule = errors.New("schedules require at least one step")
+	// ErrLockLost returned by `RunSchedules` when another owner has taken
+	// the scheduler lock.
+	ErrLockLost = errors.New("scheduler lock is held by another owner")
+
+	errScheduleRemoved = errors.New("feature no longer exists. schedule removed")
+)
+
+// Lock a lease on a named lock held by `Owner` until `ExpiresAt`.
+type Lock struct {
+	Owner     string    `json:"owner"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// SetSchedule validates the steps of `s` against the type of the
+// feature it changes and stores it, replacing any existing schedule.
+func (c *Client) SetSchedule(s *models.Schedule) error {
+	defer c.Store.Close()
+
+	if len(s.Steps) == 0 {
+		return ErrEmptySchedule
+	}
+
+	ft, err := c.feature(s.FeatureKey())
+
+	if err != nil {
+		return err
+	}
+
+	for _, step := range s.Steps {
+		if _, err := stepValue(ft.FeatureType, step.Value); err != nil {
+			return err
+		}
+	}
+
+	bts, err := json.Marshal(s)
+
+	if err != nil {
+		return err
+	}
+
+	return c.Store.Set(s.ScopedKey(), bts)
+}
+
+// Schedules returns every pending schedule in the namespace ordered by
+// their next step.
+func (c *Client) Schedules() ([]models.Schedule, error) {
+	defer c.Store.Close()
+
+	kvb, err := c.Store.List(fmt.Sprintf("%s/%s/", c.Namespace(), models.ScheduleScope))
+
+	if err != nil {
+		return nil, err
+	}
+
+	schedules := make([]models.Schedule, len(kvb))
+
+	for i, kv := range kvb {
+		err = json.Unmarshal(kv.Bytes, &schedules[i])
+
+		if err != nil {
+			return nil, fmt.Errorf("could not parse %s: %v", kv.Key, err)
+		}
+	}
+
+	sort.SliceStable(schedules, func(i, j int) bool {
+		a, b := schedules[i].Next(), schedules[j].Next()
+
+		return a != nil && (b == nil || a.At.Before(b.At))
+	})
+
+	return schedules, nil
+}
+
+// DeleteSchedule cancels the schedule for `key` within `scope`.
+func (c *Client) DeleteSchedule(key string, scope string) error {
+	defer c.Store.Close()
+
+	s := &models.Schedule{Key: key, Scope: scope, Namespace: c.Namespace()}
+	kv, err := c.Store.Get(s.ScopedKey())
+
+	if err != nil {
+		return err
+	}
+
+	if kv == nil {
+		return KeyNotFoundError(s.ScopedKey())
+	}
+
+	return c.Store.Delete(s.ScopedKey())
+}
+
+// RunSchedules sets each scheduled feature to the value of its latest
+// step due at `now` using `Set` and removes the applied steps. Schedules
+// for features that no longer exist are removed. Before each feature is
+// set the scheduler lock is renewed for `owner` until `now` plus `ttl`,
+// and the run stops with `ErrLockLost` if another owner has taken it.
+// Other errors do not stop other schedules from being applied. The
+// applied changes are returned and are not committed.
+func (c *Client) RunSchedules(owner string, ttl time.Duration, now time.Time) ([]Change, error) {
+	schedules, err := c.Schedules()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	var errs []string
+
+	for i := range schedules {
+		change, err := c.runSchedule(&schedules[i], owner, ttl, now)
+
+		if change != nil {
+			changes = append(changes, *change)
+		}
+
+		if err == ErrLockLost {
+			return changes, err
+		}
+
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", schedules[i].FeatureKey(), err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return changes, errors.New(strings.Join(errs, "; "))
+	}
+
+	return changes, nil
+}
+
+func (c *Client) runSchedule(s *models.Schedule, owner string, ttl time.Duration, now time.Time) (*Change, error) {
+	step, rest := s.Due(now)
+
+	if step == nil {
+		return nil, nil
+	}
+
+	ft, err := c.feature(s.FeatureKey())
+
+	if errors.Is(err, ErrNotFound) {
+		if err = c.Store.Delete(s.ScopedKey()); err != nil {
+			return nil, err
+		}
+
+		return nil, errScheduleRemoved
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := stepValue(ft.FeatureType, step.Value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err := c.AcquireLock(SchedulerLock, owner, ttl, now)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return nil, ErrLockLost
+	}
+
+	prev := *ft
+	ft.Value = v
+	ft.UpdatedBy = s.CreatedBy
+
+	err = c.Set(ft)
+
+	if err != nil {
+		return nil, err
+	}
+
+	change := &Change{Feature: *ft, Previous: &prev}
+
+	if len(rest) == 0 {
+		return change, c.Store.Delete(s.ScopedKey())
+	}
+
+	kv, err := c.Store.Get(s.ScopedKey())
+
+	if err != nil || kv == nil {
+		return change, err
+	}
+
+	s.Steps = rest
+	bts, err := json.Marshal(s)
+
+	if err != nil {
+		return change, err
+	}
+
+	// a conflict means the schedule was replaced or cancelled since it
+	// was listed, which takes precedence over the applied steps
+	if err = c.Store.SetCAS(s.ScopedKey(), bts, kv.Version); err == ErrConflict {
+		return change, nil
+	}
+
+	return change, err
+}
+
+// AcquireLock takes or renews the lock `name` for `owner` until `now`
+// plus `ttl`. Returns false if the lock is held by another owner. Writes
+// are compare-and-swap so only one of several replicas can succeed.
+func (c *Client) AcquireLock(name string, owner string, ttl time.Duration, now time.Time) (bool, error) {
+	defer c.Store.Close()
+
+	key := c.lockKey(name)
+	kv, err := c.Store.Get(key)
+
+	if err != nil {
+		return false, err
+	}
+
+	var version uint64
+
+	if kv != nil {
+		var l Lock
+
+		if err = json.Unmarshal(kv.Bytes, &l); err == nil && l.Owner != owner && now.Before(l.ExpiresAt) {
+			return false, nil
+		}
+
+		version = kv.Version
+	}
+
+	bts, err := json.Marshal(Lock{Owner: owner, ExpiresAt: now.Add(ttl)})
+
+	if err != nil {
+		return false, err
+	}
+
+	err = c.Store.SetCAS(key, bts, version)
+
+	if err == ErrConflict {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// ReleaseLock deletes the lock `name` if it is held by `owner`.
+func (c *Client) ReleaseLock(name string, owner string) error {
+	defer c.Store.Close()
+
+	key := c.lockKey(name)
+	kv, err := c.Store.Get(key)
+
+	if err != nil || kv == nil {
+		return err
+	}
+
+	var l Lock
+
+	if err = json.Unmarshal(kv.Bytes, &l); err != nil || l.Owner != owner {
+		return err
+	}
+
+	return c.Store.Delete(key)
+}
+
+func (c *Client) lockKey(name string) string {
+	return fmt.Sprintf("%s/%s/%s", c.Namespace(), LocksNameSpace, name)
+}
+
+// feature reads the `Feature` stored at the fully scoped `key`.
+func (c *Client) feature(key string) (*models.Feature, error) {
+	kv, err := c.Store.Get(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if kv == nil {
+		return nil, KeyNotFoundError(key)
+	}
+
+	var ft models.Feature
+	err = json.Unmarshal(kv.Bytes, &ft)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &ft, nil
+}
+
+// stepValue converts a decoded step value to the value stored for a
+// feature of type `ft`.
+func stepValue(ft models.FeatureType, v interface{}) (interface{}, error) {
+	switch ft {
+	case models.Percentile:
+		if f, ok := v.(float64); ok {
+			if f < 0 || f > 1 {
+				return nil, ErrInvalidRange
+			}
+
+			return f, nil
+		}
+	case models.Boolean:
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+	case models.Integer:
+		switch n := v.(type) {
+		case int64:
+			return n, nil
+		case float64:
+			if n == float64(int64(n)) {
+				return int64(n), nil
+			}
+		}
+	case models.String:
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+	default:
+		return nil, ErrScheduleType
+	}
+
+	return nil, fmt.Errorf("invalid value %v for %s feature", v, ft)
+}
@@ -0,0 +1,189 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vsco/dcdr/cli/api/stores"
+	"github.com/vsco/dcdr/models"
+)
+
+func TestSetSchedule(t *testing.T) {
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	c, ms := historyClient(&historyRepo{}, models.NewFeature("rollout", 0.0, "", "", "", "dcdr"))
+
+	s := models.NewSchedule("rollout", "", "dcdr", "alice", nil)
+	assert.Equal(t, ErrEmptySchedule, c.SetSchedule(s))
+
+	s.Steps = []models.ScheduleStep{{At: start, Value: 1.5}}
+	assert.Equal(t, ErrInvalidRange, c.SetSchedule(s))
+
+	s.Steps = []models.ScheduleStep{{At: start, Value: true}}
+	assert.EqualError(t, c.SetSchedule(s), "invalid value true for percentile feature")
+
+	missing := models.NewSchedule("missing", "", "dcdr", "alice", []models.ScheduleStep{{At: start, Value: 0.5}})
+	assert.True(t, errors.Is(c.SetSchedule(missing), ErrNotFound))
+
+	s.Steps = []models.ScheduleStep{{At: start, Value: 0.5}}
+	assert.NoError(t, c.SetSchedule(s))
+	assert.Contains(t, ms.kvs, "dcdr/schedules/default/rollout")
+
+	schedules, err := c.Schedules()
+	assert.NoError(t, err)
+	assert.Len(t, schedules, 1)
+	assert.Equal(t, "alice", schedules[0].CreatedBy)
+
+	assert.NoError(t, c.DeleteSchedule("rollout", models.DefaultScope))
+	assert.True(t, errors.Is(c.DeleteSchedule("rollout", models.DefaultScope), ErrNotFound))
+}
+
+func TestRunSchedules(t *testing.T) {
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	limit := models.NewFeature("limit", int64(1), "", "", "", "dcdr")
+	limit.FeatureType = models.Integer
+
+	c, ms := historyClient(&historyRepo{},
+		models.NewFeature("rollout", 0.0, "ramp", "", "", "dcdr"),
+		limit)
+
+	steps, _ := models.Ramp(0, 1, start, 4*time.Hour, time.Hour)
+	assert.NoError(t, c.SetSchedule(models.NewSchedule("rollout", "", "dcdr", "alice", steps)))
+	assert.NoError(t, c.SetSchedule(models.NewSchedule("limit", "", "dcdr", "bob", []models.ScheduleStep{
+		{At: start.Add(time.Hour), Value: 10.0},
+	})))
+
+	changes, err := c.RunSchedules("a", time.Hour, start.Add(-time.Minute))
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+
+	changes, err = c.RunSchedules("a", time.Hour, start.Add(150*time.Minute))
+	assert.NoError(t, err)
+	assert.Len(t, changes, 2)
+
+	var ft models.Feature
+	json.Unmarshal(ms.kvs["dcdr/features/default/rollout"], &ft)
+	assert.Equal(t, 0.5, ft.Value)
+	assert.Equal(t, "ramp", ft.Comment)
+	assert.Equal(t, "alice", ft.UpdatedBy)
+
+	json.Unmarshal(ms.kvs["dcdr/features/default/limit"], &ft)
+	assert.Equal(t, 10.0, ft.Value)
+	assert.NotContains(t, ms.kvs, "dcdr/schedules/default/limit")
+
+	schedules, _ := c.Schedules()
+	assert.Len(t, schedules, 1)
+	assert.Len(t, schedules[0].Steps, 2)
+
+	changes, err = c.RunSchedules("a", time.Hour, start.Add(150*time.Minute))
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+
+	changes, err = c.RunSchedules("a", time.Hour, start.Add(5*time.Hour))
+	assert.NoError(t, err)
+	assert.Len(t, changes, 1)
+	assert.Equal(t, 1.0, changes[0].Feature.Value)
+	assert.Equal(t, 0.5, changes[0].Previous.Value)
+	assert.NotContains(t, ms.kvs, "dcdr/schedules/default/rollout")
+}
+
+func TestRunSchedulesRemovesMissingFeatures(t *testing.T) {
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	c, ms := historyClient(&historyRepo{}, models.NewFeature("rollout", 0.0, "", "", "", "dcdr"))
+
+	assert.NoError(t, c.SetSchedule(models.NewSchedule("rollout", "", "dcdr", "alice", []models.ScheduleStep{
+		{At: start, Value: 0.5},
+	})))
+	delete(ms.kvs, "dcdr/features/default/rollout")
+
+	changes, err := c.RunSchedules("a", time.Hour, start)
+	assert.Empty(t, changes)
+	assert.EqualError(t, err, "dcdr/features/default/rollout: feature no longer exists. schedule removed")
+	assert.NotContains(t, ms.kvs, "dcdr/schedules/default/rollout")
+}
+
+func TestRunSchedulesLockLost(t *testing.T) {
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	c, ms := historyClient(&historyRepo{}, models.NewFeature("rollout", 0.0, "", "", "", "dcdr"))
+
+	assert.NoError(t, c.SetSchedule(models.NewSchedule("rollout", "", "dcdr", "alice", []models.ScheduleStep{
+		{At: start, Value: 0.5},
+	})))
+
+	// another replica took the lock after this run acquired it
+	ok, err := c.AcquireLock(SchedulerLock, "b", time.Hour, start)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	changes, err := c.RunSchedules("a", time.Hour, start)
+	assert.Equal(t, ErrLockLost, err)
+	assert.Empty(t, changes)
+
+	var ft models.Feature
+	json.Unmarshal(ms.kvs["dcdr/features/default/rollout"], &ft)
+	assert.Equal(t, 0.0, ft.Value)
+	assert.Contains(t, ms.kvs, "dcdr/schedules/default/rollout")
+}
+
+func TestLocks(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	c, ms := historyClient(&historyRepo{})
+
+	ok, err := c.AcquireLock(SchedulerLock, "a", time.Minute, now)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = c.AcquireLock(SchedulerLock, "b", time.Minute, now.Add(30*time.Second))
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	ok, err = c.AcquireLock(SchedulerLock, "a", time.Minute, now.Add(30*time.Second))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = c.AcquireLock(SchedulerLock, "b", time.Minute, now.Add(2*time.Minute))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	assert.NoError(t, c.ReleaseLock(SchedulerLock, "a"))
+	assert.Contains(t, ms.kvs, "dcdr/locks/scheduler")
+
+	assert.NoError(t, c.ReleaseLock(SchedulerLock, "b"))
+	assert.NotContains(t, ms.kvs, "dcdr/locks/scheduler")
+}
+
+// lockRaceStore simulates another replica taking the lock between the
+// read and the compare-and-swap.
+type lockRaceStore struct {
+	memStore
+}
+
+func (ls *lockRaceStore) SetCAS(key string, bts []byte, version uint64) error {
+	ls.memStore.Set(key, []byte(`{"owner":"other"}`))
+
+	return ls.memStore.SetCAS(key, bts, version)
+}
+
+func TestAcquireLockConflict(t *testing.T) {
+	c, _ := historyClient(&historyRepo{})
+	c.Store = &lockRaceStore{memStore{kvs: make(map[string][]byte)}}
+
+	ok, err := c.AcquireLock(SchedulerLock, "a", time.Minute, time.Now())
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestKVsToFeatureMapSkipsSchedules(t *testing.T) {
+	c, _ := historyClient(&historyRepo{})
+
+	fm, err := c.KVsToFeatureMap(stores.KVBytes{
+		{Key: "dcdr/features/default/rollout", Bytes: []byte(`{"key":"rollout","value":0.5,"feature_type":"percentile"}`)},
+		{Key: "dcdr/schedules/default/rollout", Bytes: []byte(`{"key":"rollout","steps":[]}`)},
+		{Key: "dcdr/locks/scheduler", Bytes: []byte(`{"owner":"a"}`)},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.FeatureScopes{"default": map[string]interface{}{"rollout": 0.5}}, fm.Dcdr.FeatureScopes)
+}
@@ -0,0 +1,210 @@
+package api
+
+import (
+	"strings"
+	"time"
+
+	"github.com/vsco/dcdr/models"
+)
+
+// StaleReason why a feature is reported by `Stale`.
+type StaleReason string
+
+const (
+	// Expired the feature is past its `ExpiresAt`.
+	Expired StaleReason = "expired"
+	// RolledOut the feature has been fully enabled in every scope for
+	// longer than `StaleOptions.RolloutAge`.
+	RolledOut StaleReason = "rolled out"
+	// Unused the feature has no recorded evaluations.
+	Unused StaleReason = "unused"
+)
+
+// StaleOptions thresholds used to find stale features.
+type StaleOptions struct {
+	// Now the time expiry and rollout age are compared against.
+	Now time.Time
+	// RolloutAge how long a feature must be fully rolled out to be stale.
+	RolloutAge time.Duration
+	// Evaluations the number of evaluations of each feature recorded
+	// by `client.StatsClient`. Unused features are only reported when set.
+	Evaluations map[string]uint64
+}
+
+// StaleFeature a feature and the reasons it is stale.
+type StaleFeature struct {
+	models.Feature
+	Reasons     []StaleReason
+	RolledOutAt *time.Time
+}
+
+// Stale returns the features in the namespace that are expired, fully
+// rolled out for longer than `opts.RolloutAge`, or unused. Rollouts are
+// dated by walking the audit repo or, without one, by `UpdatedAt`.
+func (c *Client) Stale(opts StaleOptions) ([]StaleFeature, error) {
+	fts, err := c.List("", "")
+
+	if err != nil {
+		return nil, err
+	}
+
+	since, err := c.rolledOutSince(fts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var stale []StaleFeature
+
+	for _, ft := range fts {
+		sf := StaleFeature{Feature: ft}
+
+		if ft.Expired(opts.Now) {
+			sf.Reasons = append(sf.Reasons, Expired)
+		}
+
+		if t, ok := since[ft.Key]; ok {
+			sf.RolledOutAt = &t
+
+			if !t.After(opts.Now.Add(-opts.RolloutAge)) {
+				sf.Reasons = append(sf.Reasons, RolledOut)
+			}
+		}
+
+		if opts.Evaluations != nil && opts.Evaluations[ft.Key] == 0 {
+			sf.Reasons = append(sf.Reasons, Unused)
+		}
+
+		if len(sf.Reasons) > 0 {
+			stale = append(stale, sf)
+		}
+	}
+
+	return stale, nil
+}
+
+// rolledOutSince returns when each feature that is fully enabled in
+// every scope of `fts` was last rolled out.
+func (c *Client) rolledOutSince(fts models.Features) (map[string]time.Time, error) {
+	scopes := make(map[string]models.Features)
+
+	for _, ft := range fts {
+		scopes[ft.Key] = append(scopes[ft.Key], ft)
+	}
+
+	rolled := make(map[string]bool)
+
+	for key, fs := range scopes {
+		rolled[key] = true
+
+		for _, ft := range fs {
+			if !fullyOn(ft) {
+				delete(rolled, key)
+				break
+			}
+		}
+	}
+
+	if !c.config.GitEnabled() {
+		return updatedSince(scopes, rolled), nil
+	}
+
+	revs, err := c.revisions()
+
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]bool)
+
+	for _, ft := range fts {
+		known[scopedPath(ft.GetScope(), ft.Key)] = true
+	}
+
+	since := make(map[string]time.Time)
+
+	for i := len(revs) - 1; i >= 0; i-- {
+		fm, err := c.Snapshot(revs[i].SHA)
+
+		if err != nil {
+			return nil, err
+		}
+
+		on := make(map[string]bool)
+
+		for path, v := range flattenScopes(&fm.Dcdr, func(path string) bool { return known[path] }) {
+			key := path[strings.LastIndex(path, "/")+1:]
+
+			if !rolled[key] {
+				continue
+			}
+
+			if prev, ok := on[key]; !ok || prev {
+				on[key] = fullyOnValue(v)
+			}
+		}
+
+		for key := range rolled {
+			if !on[key] {
+				delete(since, key)
+			} else if _, ok := since[key]; !ok {
+				since[key] = revs[i].Date
+			}
+		}
+	}
+
+	return since, nil
+}
+
+// updatedSince dates each rolled out feature by the latest `UpdatedAt`
+// of its scopes, skipping features without one.
+func updatedSince(scopes map[string]models.Features, rolled map[string]bool) map[string]time.Time {
+	since := make(map[string]time.Time)
+
+	for key := range rolled {
+		var latest time.Time
+
+		for _, ft := range scopes[key] {
+			if ft.UpdatedAt == nil {
+				latest = time.Time{}
+				break
+			}
+
+			if ft.UpdatedAt.After(latest) {
+				latest = *ft.UpdatedAt
+			}
+		}
+
+		if !latest.IsZero() {
+			since[key] = latest
+		}
+	}
+
+	return since
+}
+
+// fullyOn checks if `ft` is a boolean set to true or a percentile set
+// to 1.0 without targeting rules.
+func fullyOn(ft models.Feature) bool {
+	if len(ft.Rules) > 0 {
+		return false
+	}
+
+	switch ft.FeatureType {
+	case models.Boolean, models.Percentile:
+		return fullyOnValue(ft.Value)
+	default:
+		return false
+	}
+}
+
+func fullyOnValue(v interface{}) bool {
+	switch tv := v.(type) {
+	case bool:
+		return tv
+	case float64:
+		return tv == 1
+	default:
+		return false
+	}
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"github.com/stretchr/testify/assert"
+	"github.com/vsco/dcdr/client"
+	"github.com/vsco/dcdr/config"
+	"github.com/vsco/dcdr/models"
+)
+
+func staleByKey(stale []StaleFeature) map[string]StaleFeature {
+	m := make(map[string]StaleFeature)
+
+	for _, sf := range stale {
+		m[sf.ScopedKey()] = sf
+	}
+
+	return m
+}
+
+func TestStale(t *testing.T) {
+	hr := &historyRepo{}
+	hr.commit("a",
+		models.NewFeature("rollout", 0.5, "", "", "", "dcdr"),
+		models.NewFeature("done", true, "", "", "", "dcdr"))
+	hr.commit("b",
+		models.NewFeature("rollout", 1.0, "", "", "", "dcdr"),
+		models.NewFeature("done", true, "", "", "", "dcdr"))
+	hr.commit("c",
+		models.NewFeature("rollout", 1.0, "", "", "", "dcdr"),
+		models.NewFeature("rollout", 0.5, "", "", "user-groups/beta", "dcdr"),
+		models.NewFeature("done", true, "", "", "", "dcdr"))
+	hr.commit("d",
+		models.NewFeature("rollout", 1.0, "", "", "", "dcdr"),
+		models.NewFeature("rollout", 1.0, "", "", "user-groups/beta", "dcdr"),
+		models.NewFeature("done", true, "", "", "", "dcdr"))
+
+	exp := time.Unix(5, 0)
+	old := models.NewFeature("old", false, "", "", "", "dcdr")
+	old.ExpiresAt = &exp
+
+	c, _ := historyClient(hr,
+		models.NewFeature("rollout", 1.0, "", "", "", "dcdr"),
+		models.NewFeature("rollout", 1.0, "", "", "user-groups/beta", "dcdr"),
+		models.NewFeature("done", true, "", "", "", "dcdr"),
+		models.NewFeature("partial", 0.5, "", "", "", "dcdr"),
+		old)
+
+	stale, err := c.Stale(StaleOptions{
+		Now:         time.Unix(10, 0),
+		RolloutAge:  8 * time.Second,
+		Evaluations: map[string]uint64{"rollout": 5},
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, stale, 3)
+
+	byKey := staleByKey(stale)
+
+	assert.Equal(t, []StaleReason{RolledOut, Unused}, byKey["dcdr/features/default/done"].Reasons)
+	assert.Equal(t, time.Unix(0, 0), *byKey["dcdr/features/default/done"].RolledOutAt)
+	assert.Equal(t, []StaleReason{Unused}, byKey["dcdr/features/default/partial"].Reasons)
+	assert.Equal(t, []StaleReason{Expired, Unused}, byKey["dcdr/features/default/old"].Reasons)
+
+	stale, err = c.Stale(StaleOptions{Now: time.Unix(10, 0), RolloutAge: 5 * time.Second})
+
+	assert.NoError(t, err)
+
+	byKey = staleByKey(stale)
+
+	assert.Len(t, stale, 4)
+	assert.Equal(t, []StaleReason{RolledOut}, byKey["dcdr/features/user-groups/beta/rollout"].Reasons)
+	assert.Equal(t, time.Unix(3, 0), *byKey["dcdr/features/user-groups/beta/rollout"].RolledOutAt)
+}
+
+func TestStaleWithoutRepo(t *testing.T) {
+	c, _ := historyClient(&historyRepo{})
+	c.config.Git.RepoPath = ""
+
+	assert.NoError(t, c.Set(models.NewFeature("done", true, "", "", "", "dcdr")))
+	assert.NoError(t, c.Set(models.NewFeature("partial", 0.5, "", "", "", "dcdr")))
+
+	ft := models.NewFeature("targeted", 1.0, "", "", "", "dcdr")
+	ft.Rules = models.Rules{{Attribute: "country", Operator: models.In, Values: []string{"us"}}}
+	assert.NoError(t, c.Set(ft))
+
+	stale, err := c.Stale(StaleOptions{Now: time.Now().Add(48 * time.Hour), RolloutAge: 24 * time.Hour})
+
+	assert.NoError(t, err)
+	assert.Len(t, stale, 1)
+	assert.Equal(t, "done", stale[0].Key)
+
+	stale, err = c.Stale(StaleOptions{Now: time.Now(), RolloutAge: 24 * time.Hour})
+
+	assert.NoError(t, err)
+	assert.Empty(t, stale)
+}
+
+func TestStaleTypedGetters(t *testing.T) {
+	sc, err := client.NewStatsClient(&config.Config{Namespace: "dcdr"}, &statsd.NoOpClient{})
+	assert.NoError(t, err)
+
+	sc.StringValue("color", "blue")
+	sc.IntValue("limit", 10)
+	sc.JSONValue("search", &map[string]interface{}{})
+
+	c, _ := historyClient(&historyRepo{},
+		models.NewFeature("color", "red", "", "", "", "dcdr"),
+		models.NewFeature("limit", int64(5), "", "", "", "dcdr"),
+		models.NewFeature("search", map[string]interface{}{"boost": 2.0}, "", "", "", "dcdr"),
+		models.NewFeature("unread", "x", "", "", "", "dcdr"))
+	c.config.Git.RepoPath = ""
+
+	stale, err := c.Stale(StaleOptions{Now: time.Unix(10, 0), Evaluations: sc.Evaluations()})
+
+	assert.NoError(t, err)
+	assert.Len(t, stale, 1)
+	assert.Equal(t, "unread", stale[0].Key)
+}
@@ -12,6 +12,7 @@ type ConsulKVIFace interface {
 	List(prefix string, q *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error)
 	Get(key string, q *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error)
 	Put(p *api.KVPair, q *api.WriteOptions) (*api.WriteMeta, error)
+	CAS(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error)
 	Delete(key string, w *api.WriteOptions) (*api.WriteMeta, error)
 }
 
@@ -63,6 +64,7 @@ func (cs *Store) Get(key string) (*stores.KVByte, error) {
 
 	k.Key = kv.Key
 	k.Bytes = kv.Value
+	k.Version = kv.ModifyIndex
 
 	return k, nil
 }
@@ -78,6 +80,27 @@ func (cs *Store) Set(key string, bts []byte) error {
 	return err
 }
 
+// SetCAS uses the consul `ModifyIndex` as the version of `key`.
+func (cs *Store) SetCAS(key string, bts []byte, version uint64) error {
+	p := &api.KVPair{
+		Key:         key,
+		Value:       bts,
+		ModifyIndex: version,
+	}
+
+	ok, _, err := cs.kv.CAS(p, cs.wo)
+
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return stores.ErrConflict
+	}
+
+	return nil
+}
+
 func (cs *Store) Delete(key string) error {
 	_, err := cs.kv.Delete(key, cs.wo)
 
@@ -95,8 +118,9 @@ func (cs *Store) List(prefix string) (stores.KVBytes, error) {
 
 	for i := 0; i < len(kvs); i++ {
 		kvb[i] = &stores.KVByte{
-			Key:   kvs[i].Key,
-			Bytes: kvs[i].Value,
+			Key:     kvs[i].Key,
+			Bytes:   kvs[i].Value,
+			Version: kvs[i].ModifyIndex,
 		}
 	}
 
@@ -150,8 +174,9 @@ func KvPairsToKvBytes(kvp api.KVPairs) (stores.KVBytes, error) {
 
 	for i := 0; i < len(kvp); i++ {
 		kvb[i] = &stores.KVByte{
-			Key:   kvp[i].Key,
-			Bytes: kvp[i].Value,
+			Key:     kvp[i].Key,
+			Bytes:   kvp[i].Value,
+			Version: kvp[i].ModifyIndex,
 		}
 	}
 
@@ -48,6 +48,20 @@ func TestConsulPut(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestConsulSetCAS(t *testing.T) {
+	kvb := stores.KVBytes{&stores.KVByte{Key: "a", Bytes: MockBytes, Version: 7}}
+	mc := NewMockConsul("a", kvb, nil)
+	cs := New(config.TestConfig(), mc)
+
+	kv, err := cs.Get("a")
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(7), kv.Version)
+	assert.NoError(t, cs.SetCAS("a", MockBytes, kv.Version))
+	assert.Equal(t, stores.ErrConflict, cs.SetCAS("a", MockBytes, 6))
+	assert.Equal(t, stores.ErrConflict, cs.SetCAS("a", MockBytes, 0))
+}
+
 func TestConsulDelete(t *testing.T) {
 	mc := NewMockConsul("n", MockKVBytes, nil)
 	cs := New(config.TestConfig(), mc)
@@ -26,15 +26,17 @@ func NewMockConsul(key string, kvb stores.KVBytes, err error) (mc *MockConsul) {
 
 func (mc *MockConsul) get(key string) *api.KVPair {
 	return &api.KVPair{
-		Key:   key,
-		Value: mc.Item.Bytes,
+		Key:         key,
+		Value:       mc.Item.Bytes,
+		ModifyIndex: mc.Item.Version,
 	}
 }
 
 func (mc *MockConsul) List(prefix string, qo *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error) {
 	items := api.KVPairs{&api.KVPair{
-		Key:   mc.Item.Key,
-		Value: mc.Item.Bytes,
+		Key:         mc.Item.Key,
+		Value:       mc.Item.Bytes,
+		ModifyIndex: mc.Item.Version,
 	},
 	}
 	return items, nil, mc.Err
@@ -48,6 +50,18 @@ func (mc *MockConsul) Put(p *api.KVPair, qo *api.WriteOptions) (*api.WriteMeta,
 	return nil, mc.Err
 }
 
+func (mc *MockConsul) CAS(p *api.KVPair, qo *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	if mc.Err != nil {
+		return false, nil, mc.Err
+	}
+
+	if mc.Item == nil {
+		return p.ModifyIndex == 0, nil, nil
+	}
+
+	return p.ModifyIndex == mc.Item.Version, nil, nil
+}
+
 func (mc *MockConsul) Delete(key string, w *api.WriteOptions) (*api.WriteMeta, error) {
 	return nil, mc.Err
 }
@@ -0,0 +1,48 @@
+//go:build etcd
+
+package etcd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vsco/dcdr/config"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// endpointsEnv comma delimited endpoints of a disposable etcd cluster
+// used by the store tests when run with the etcd build tag. Defaults
+// to `config.DefaultEtcdEndpoint`.
+const endpointsEnv = "DCDR_TEST_ETCD_ENDPOINTS"
+
+// testStore connects to the test cluster using a namespace unique to
+// the test, which is deleted once it completes.
+func testStore(t *testing.T) *Store {
+	cfg := config.TestConfig()
+	cfg.Namespace = fmt.Sprintf("dcdr-%d", time.Now().UnixNano())
+
+	if eps := os.Getenv(endpointsEnv); eps != "" {
+		cfg.Etcd.Endpoints = strings.Split(eps, ",")
+	}
+
+	s, err := NewDefault(cfg)
+
+	if err != nil {
+		t.Fatalf("could not connect to etcd: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if client, err := s.acquire(); err == nil {
+			client.Delete(context.Background(), cfg.Namespace+"/", clientv3.WithPrefix())
+			s.release()
+		}
+
+		s.Close()
+	})
+
+	return s
+}
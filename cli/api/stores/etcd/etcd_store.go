@@ -0,0 +1,332 @@
+package etcd
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/vsco/dcdr/cli/api/stores"
+	"github.com/vsco/dcdr/cli/printer"
+	"github.com/vsco/dcdr/config"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+const requestTimeout = 10 * time.Second
+
+// ErrEtcdConfig returned when no endpoints are configured.
+var ErrEtcdConfig = errors.New("missing etcd config endpoints")
+
+// Store an etcd v3 store. Versions are the `ModRevision` of each key.
+// The client is connected on first use and closed by `Close` once the
+// calls in flight return, so the next call connects again.
+type Store struct {
+	cfg     *config.Config
+	dial    func() (*clientv3.Client, error)
+	mu      sync.Mutex
+	client  *clientv3.Client
+	calls   int
+	closing bool
+	cb      func(kvb stores.KVBytes)
+}
+
+// NewDefault connects to `cfg.Etcd.Endpoints` when first used.
+func NewDefault(cfg *config.Config) (*Store, error) {
+	if len(cfg.Etcd.Endpoints) == 0 {
+		return nil, ErrEtcdConfig
+	}
+
+	timeout, err := cfg.Etcd.DialDuration()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return New(cfg, func() (*clientv3.Client, error) {
+		return clientv3.New(clientv3.Config{
+			Endpoints:   cfg.Etcd.Endpoints,
+			DialTimeout: timeout,
+		})
+	}), nil
+}
+
+// New creates a `Store` that connects with `dial`.
+func New(cfg *config.Config, dial func() (*clientv3.Client, error)) *Store {
+	return &Store{
+		cfg:  cfg,
+		dial: dial,
+	}
+}
+
+func (s *Store) Get(key string) (*stores.KVByte, error) {
+	client, err := s.acquire()
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer s.release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	resp, err := client.Get(ctx, key)
+
+	if err != nil || len(resp.Kvs) == 0 {
+		return nil, err
+	}
+
+	return toKVByte(resp.Kvs[0]), nil
+}
+
+func (s *Store) Set(key string, bts []byte) error {
+	client, err := s.acquire()
+
+	if err != nil {
+		return err
+	}
+
+	defer s.release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	_, err = client.Put(ctx, key, string(bts))
+
+	return err
+}
+
+// SetCAS puts `key` in a transaction guarded by its `ModRevision`,
+// which is 0 for keys that do not exist.
+func (s *Store) SetCAS(key string, bts []byte, version uint64) error {
+	client, err := s.acquire()
+
+	if err != nil {
+		return err
+	}
+
+	defer s.release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	resp, err := client.Txn(ctx).
+		If(clientv3.Compare(clientv3.ModRevision(key), "=", int64(version))).
+		Then(clientv3.OpPut(key, string(bts))).
+		Commit()
+
+	if err != nil {
+		return err
+	}
+
+	if !resp.Succeeded {
+		return stores.ErrConflict
+	}
+
+	return nil
+}
+
+func (s *Store) Delete(key string) error {
+	client, err := s.acquire()
+
+	if err != nil {
+		return err
+	}
+
+	defer s.release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	_, err = client.Delete(ctx, key)
+
+	return err
+}
+
+func (s *Store) List(prefix string) (stores.KVBytes, error) {
+	client, err := s.acquire()
+
+	if err != nil {
+		return make(stores.KVBytes, 0), err
+	}
+
+	defer s.release()
+
+	kvb, _, err := s.list(client, prefix)
+
+	return kvb, err
+}
+
+// Watch lists the namespace and then watches it from the revision of
+// the list so no changes are missed in between. The callback receives
+// the full set of keys after each change. The namespace is listed
+// again if the watched revision has been compacted.
+func (s *Store) Watch() error {
+	client, err := s.acquire()
+
+	if err != nil {
+		return err
+	}
+
+	defer s.release()
+
+	for {
+		kvb, rev, err := s.list(client, s.cfg.Namespace)
+
+		if err != nil {
+			return err
+		}
+
+		s.Updated(kvb)
+
+		err = s.watch(client, kvb, rev+1)
+
+		if err != rpctypes.ErrCompacted {
+			return err
+		}
+
+		printer.LogErrf("watch error: %v. listing %s again", err, s.cfg.Namespace)
+	}
+}
+
+func (s *Store) Register(cb func(kvb stores.KVBytes)) {
+	s.cb = cb
+}
+
+func (s *Store) Updated(kvs interface{}) {
+	s.cb(kvs.(stores.KVBytes))
+}
+
+// Close closes the client once the calls in flight, including `Watch`,
+// have returned.
+func (s *Store) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closing = s.client != nil
+	s.closeIdle()
+}
+
+// acquire returns the client, connecting if needed. Each call must be
+// followed by `release`.
+func (s *Store) acquire() (*clientv3.Client, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.client == nil {
+		client, err := s.dial()
+
+		if err != nil {
+			return nil, err
+		}
+
+		s.client = client
+	}
+
+	s.calls++
+
+	return s.client, nil
+}
+
+func (s *Store) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls--
+	s.closeIdle()
+}
+
+// closeIdle closes the client if `Close` has been called and no calls
+// are in flight. `s.mu` must be held.
+func (s *Store) closeIdle() {
+	if !s.closing || s.calls > 0 || s.client == nil {
+		return
+	}
+
+	if err := s.client.Close(); err != nil && err != context.Canceled {
+		printer.LogErrf("etcd close error: %v", err)
+	}
+
+	s.client = nil
+	s.closing = false
+}
+
+// list returns the keys beginning with `prefix` and the store revision
+// they were read at.
+func (s *Store) list(client *clientv3.Client, prefix string) (stores.KVBytes, int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	kvb := make(stores.KVBytes, 0)
+	resp, err := client.Get(ctx, prefix,
+		clientv3.WithPrefix(),
+		clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend))
+
+	if err != nil {
+		return kvb, 0, err
+	}
+
+	for _, kv := range resp.Kvs {
+		kvb = append(kvb, toKVByte(kv))
+	}
+
+	return kvb, resp.Header.Revision, nil
+}
+
+// watch applies events from `rev` onwards to `kvb`, calling the
+// registered callback after each response.
+func (s *Store) watch(client *clientv3.Client, kvb stores.KVBytes, rev int64) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	current := make(map[string]*stores.KVByte, len(kvb))
+
+	for _, kv := range kvb {
+		current[kv.Key] = kv
+	}
+
+	wc := client.Watch(ctx, s.cfg.Namespace, clientv3.WithPrefix(), clientv3.WithRev(rev))
+
+	for resp := range wc {
+		if err := resp.Err(); err != nil {
+			return err
+		}
+
+		for _, ev := range resp.Events {
+			if ev.Type == mvccpb.DELETE {
+				delete(current, string(ev.Kv.Key))
+			} else {
+				current[string(ev.Kv.Key)] = toKVByte(ev.Kv)
+			}
+		}
+
+		s.Updated(sorted(current))
+	}
+
+	return nil
+}
+
+func sorted(current map[string]*stores.KVByte) stores.KVBytes {
+	kvb := make(stores.KVBytes, 0, len(current))
+
+	for _, kv := range current {
+		kvb = append(kvb, kv)
+	}
+
+	sort.Slice(kvb, func(i, j int) bool {
+		return kvb[i].Key < kvb[j].Key
+	})
+
+	return kvb
+}
+
+func toKVByte(kv *mvccpb.KeyValue) *stores.KVByte {
+	return &stores.KVByte{
+		Key:     string(kv.Key),
+		Bytes:   kv.Value,
+		Version: uint64(kv.ModRevision),
+	}
+}
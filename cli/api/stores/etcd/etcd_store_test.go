@@ -0,0 +1,198 @@
+package etcd
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vsco/dcdr/cli/api/stores"
+	"github.com/vsco/dcdr/config"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+)
+
+// The store tests run against an in memory keyspace by default and
+// against a real cluster with the etcd build tag. Both provide
+// `testStore`.
+
+func TestNewDefaultConfig(t *testing.T) {
+	cfg := config.TestConfig()
+	cfg.Etcd.Endpoints = nil
+
+	_, err := NewDefault(cfg)
+	assert.Equal(t, ErrEtcdConfig, err)
+}
+
+func TestToKVByte(t *testing.T) {
+	kv := toKVByte(&mvccpb.KeyValue{
+		Key:         []byte("dcdr/features/default/a"),
+		Value:       []byte("a"),
+		ModRevision: 7,
+	})
+
+	assert.Equal(t, "dcdr/features/default/a", kv.Key)
+	assert.Equal(t, []byte("a"), kv.Bytes)
+	assert.Equal(t, uint64(7), kv.Version)
+}
+
+func TestSorted(t *testing.T) {
+	kvb := sorted(map[string]*stores.KVByte{
+		"dcdr/features/default/b": {Key: "dcdr/features/default/b"},
+		"dcdr/features/default/a": {Key: "dcdr/features/default/a"},
+	})
+
+	assert.Len(t, kvb, 2)
+	assert.Equal(t, "dcdr/features/default/a", kvb[0].Key)
+	assert.Equal(t, "dcdr/features/default/b", kvb[1].Key)
+}
+
+// key scopes `k` to the namespace of `s`.
+func key(s *Store, k string) string {
+	return s.cfg.Namespace + "/" + k
+}
+
+func waitFor(t *testing.T, updates chan stores.KVBytes, keys ...string) {
+	timeout := time.After(5 * time.Second)
+
+	for {
+		select {
+		case kvb := <-updates:
+			var got []string
+
+			for _, kv := range kvb {
+				got = append(got, kv.Key)
+			}
+
+			if fmt.Sprint(got) == fmt.Sprint(keys) {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("no update with %v", keys)
+		}
+	}
+}
+
+func TestSetGetDelete(t *testing.T) {
+	s := testStore(t)
+	flag := key(s, "features/default/flag")
+
+	kv, err := s.Get(flag)
+	assert.NoError(t, err)
+	assert.Nil(t, kv)
+
+	assert.NoError(t, s.Set(flag, []byte("a")))
+
+	kv, err = s.Get(flag)
+	assert.NoError(t, err)
+	assert.Equal(t, flag, kv.Key)
+	assert.Equal(t, []byte("a"), kv.Bytes)
+	assert.NotZero(t, kv.Version)
+
+	assert.NoError(t, s.Delete(flag))
+
+	kv, err = s.Get(flag)
+	assert.NoError(t, err)
+	assert.Nil(t, kv)
+}
+
+func TestList(t *testing.T) {
+	s := testStore(t)
+
+	s.Set(key(s, "features/user-groups/beta/flag"), []byte("b"))
+	s.Set(key(s, "features/user-groups/beta"), []byte("a"))
+	s.Set(key(s, "info"), []byte("c"))
+
+	kvb, err := s.List(key(s, "features/"))
+	assert.NoError(t, err)
+	assert.Len(t, kvb, 2)
+	assert.Equal(t, key(s, "features/user-groups/beta"), kvb[0].Key)
+	assert.Equal(t, key(s, "features/user-groups/beta/flag"), kvb[1].Key)
+
+	kvb, err = s.List(key(s, "missing/"))
+	assert.NoError(t, err)
+	assert.Empty(t, kvb)
+}
+
+func TestSetCAS(t *testing.T) {
+	s := testStore(t)
+	flag := key(s, "features/default/flag")
+
+	assert.NoError(t, s.SetCAS(flag, []byte("a"), 0))
+	assert.Equal(t, stores.ErrConflict, s.SetCAS(flag, []byte("b"), 0))
+
+	kv, _ := s.Get(flag)
+
+	assert.NoError(t, s.SetCAS(flag, []byte("b"), kv.Version))
+	assert.Equal(t, stores.ErrConflict, s.SetCAS(flag, []byte("c"), kv.Version))
+
+	kv, _ = s.Get(flag)
+	assert.Equal(t, []byte("b"), kv.Bytes)
+}
+
+func TestWatch(t *testing.T) {
+	s := testStore(t)
+	a, b := key(s, "features/default/a"), key(s, "features/default/b")
+	s.Set(a, []byte("a"))
+
+	updates := make(chan stores.KVBytes, 10)
+	s.Register(func(kvb stores.KVBytes) {
+		updates <- kvb
+	})
+
+	go s.Watch()
+
+	waitFor(t, updates, a)
+
+	s.Set(b, []byte("b"))
+	waitFor(t, updates, a, b)
+
+	s.Delete(a)
+	waitFor(t, updates, b)
+}
+
+func TestWatchFromRevision(t *testing.T) {
+	s := testStore(t)
+	a, b := key(s, "features/default/a"), key(s, "features/default/b")
+	s.Set(a, []byte("a"))
+
+	client, err := s.acquire()
+	assert.NoError(t, err)
+	defer s.release()
+
+	kvb, rev, err := s.list(client, s.cfg.Namespace)
+	assert.NoError(t, err)
+
+	// written after the list but before the watch starts
+	s.Set(b, []byte("b"))
+
+	updates := make(chan stores.KVBytes, 10)
+	s.Register(func(kvb stores.KVBytes) {
+		updates <- kvb
+	})
+
+	go s.watch(client, kvb, rev+1)
+
+	waitFor(t, updates, a, b)
+}
+
+// TestWatchCompacted compacts the whole keyspace.
+func TestWatchCompacted(t *testing.T) {
+	s := testStore(t)
+	a := key(s, "features/default/a")
+	s.Set(a, []byte("a"))
+	s.Set(a, []byte("b"))
+
+	client, err := s.acquire()
+	assert.NoError(t, err)
+	defer s.release()
+
+	kv, _ := s.Get(a)
+	_, err = client.Compact(context.Background(), int64(kv.Version))
+	assert.NoError(t, err)
+
+	s.Register(func(kvb stores.KVBytes) {})
+
+	err = s.watch(client, nil, 1)
+	assert.Error(t, err)
+}
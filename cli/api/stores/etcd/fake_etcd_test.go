@@ -0,0 +1,372 @@
+//go:build !etcd
+
+package etcd
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vsco/dcdr/cli/api/stores"
+	"github.com/vsco/dcdr/config"
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// fakeEtcd an in memory keyspace implementing the `clientv3.KV` and
+// `clientv3.Watcher` calls made by `Store`. `afterList` is called once
+// after the next prefix `Get`.
+type fakeEtcd struct {
+	mu        sync.Mutex
+	rev       int64
+	kvs       map[string]*mvccpb.KeyValue
+	events    []*clientv3.Event
+	compacted int64
+	changed   chan struct{}
+	done      chan struct{}
+	afterList func()
+	dials     int
+	closed    int
+}
+
+var (
+	_ clientv3.KV      = &fakeEtcd{}
+	_ clientv3.Watcher = &fakeEtcd{}
+)
+
+func newFakeEtcd() *fakeEtcd {
+	return &fakeEtcd{
+		rev:     1,
+		kvs:     make(map[string]*mvccpb.KeyValue),
+		changed: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// fakeStore creates a `Store` backed by a `fakeEtcd`, stopping its
+// watches once the test completes.
+func fakeStore(t *testing.T) (*Store, *fakeEtcd) {
+	e := newFakeEtcd()
+	t.Cleanup(func() {
+		close(e.done)
+	})
+
+	return New(config.TestConfig(), e.dial), e
+}
+
+func testStore(t *testing.T) *Store {
+	s, _ := fakeStore(t)
+
+	return s
+}
+
+func (e *fakeEtcd) dial() (*clientv3.Client, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.dials++
+	client := clientv3.NewCtxClient(context.Background())
+	client.KV = e
+	client.Watcher = e
+
+	return client, nil
+}
+
+func (e *fakeEtcd) Put(ctx context.Context, key, val string, opts ...clientv3.OpOption) (*clientv3.PutResponse, error) {
+	resp, err := e.Do(ctx, clientv3.OpPut(key, val, opts...))
+
+	return resp.Put(), err
+}
+
+func (e *fakeEtcd) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	op := clientv3.OpGet(key, opts...)
+	resp, err := e.Do(ctx, op)
+
+	e.mu.Lock()
+	after := e.afterList
+	e.afterList = nil
+	e.mu.Unlock()
+
+	if after != nil && len(op.RangeBytes()) > 0 {
+		after()
+	}
+
+	return resp.Get(), err
+}
+
+func (e *fakeEtcd) Delete(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.DeleteResponse, error) {
+	resp, err := e.Do(ctx, clientv3.OpDelete(key, opts...))
+
+	return resp.Del(), err
+}
+
+func (e *fakeEtcd) Compact(ctx context.Context, rev int64, opts ...clientv3.CompactOption) (*clientv3.CompactResponse, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.compacted = rev
+
+	return &clientv3.CompactResponse{Header: e.header()}, nil
+}
+
+func (e *fakeEtcd) Do(ctx context.Context, op clientv3.Op) (clientv3.OpResponse, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.do(op), nil
+}
+
+func (e *fakeEtcd) Txn(ctx context.Context) clientv3.Txn {
+	return &fakeTxn{e: e}
+}
+
+// Watch sends the events matching `key` from the revision set in
+// `opts`, or the compacted revision if it has been compacted.
+func (e *fakeEtcd) Watch(ctx context.Context, key string, opts ...clientv3.OpOption) clientv3.WatchChan {
+	op := clientv3.OpGet(key, opts...)
+	wc := make(chan clientv3.WatchResponse)
+
+	go func() {
+		defer close(wc)
+
+		next := op.Rev()
+
+		for {
+			var resp clientv3.WatchResponse
+
+			e.mu.Lock()
+
+			if next > 0 && next <= e.compacted {
+				resp.CompactRevision = e.compacted
+			}
+
+			for _, ev := range e.events {
+				if ev.Kv.ModRevision >= next && inRange(op, string(ev.Kv.Key)) {
+					resp.Events = append(resp.Events, ev)
+				}
+			}
+
+			changed := e.changed
+			e.mu.Unlock()
+
+			if resp.CompactRevision != 0 || len(resp.Events) > 0 {
+				select {
+				case wc <- resp:
+				case <-ctx.Done():
+					return
+				case <-e.done:
+					return
+				}
+
+				if resp.CompactRevision != 0 {
+					return
+				}
+
+				next = resp.Events[len(resp.Events)-1].Kv.ModRevision + 1
+			}
+
+			select {
+			case <-changed:
+			case <-ctx.Done():
+				return
+			case <-e.done:
+				return
+			}
+		}
+	}()
+
+	return wc
+}
+
+func (e *fakeEtcd) RequestProgress(ctx context.Context) error {
+	return nil
+}
+
+func (e *fakeEtcd) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.closed++
+
+	return nil
+}
+
+// do applies `op`. `e.mu` must be held.
+func (e *fakeEtcd) do(op clientv3.Op) clientv3.OpResponse {
+	key := string(op.KeyBytes())
+
+	switch {
+	case op.IsPut():
+		e.rev++
+		kv := &mvccpb.KeyValue{Key: op.KeyBytes(), Value: op.ValueBytes(), ModRevision: e.rev}
+		e.kvs[key] = kv
+		e.notify(mvccpb.PUT, kv)
+
+		return (&clientv3.PutResponse{Header: e.header()}).OpResponse()
+	case op.IsDelete():
+		if _, ok := e.kvs[key]; ok {
+			e.rev++
+			delete(e.kvs, key)
+			e.notify(mvccpb.DELETE, &mvccpb.KeyValue{Key: op.KeyBytes(), ModRevision: e.rev})
+		}
+
+		return (&clientv3.DeleteResponse{Header: e.header()}).OpResponse()
+	default:
+		resp := &clientv3.GetResponse{Header: e.header()}
+
+		for _, k := range e.keys() {
+			if inRange(op, k) {
+				resp.Kvs = append(resp.Kvs, e.kvs[k])
+			}
+		}
+
+		return resp.OpResponse()
+	}
+}
+
+func (e *fakeEtcd) header() *pb.ResponseHeader {
+	return &pb.ResponseHeader{Revision: e.rev}
+}
+
+func (e *fakeEtcd) keys() []string {
+	keys := make([]string, 0, len(e.kvs))
+
+	for k := range e.kvs {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// notify records an event and wakes up watches.
+func (e *fakeEtcd) notify(typ mvccpb.Event_EventType, kv *mvccpb.KeyValue) {
+	e.events = append(e.events, &clientv3.Event{Type: typ, Kv: kv})
+	close(e.changed)
+	e.changed = make(chan struct{})
+}
+
+// compare supports the `ModRevision` equality compares made by `SetCAS`.
+func (e *fakeEtcd) compare(cmp clientv3.Cmp) bool {
+	var rev int64
+
+	if kv, ok := e.kvs[string(cmp.Key)]; ok {
+		rev = kv.ModRevision
+	}
+
+	mod, ok := cmp.TargetUnion.(*pb.Compare_ModRevision)
+
+	return ok && cmp.Result == pb.Compare_EQUAL && mod.ModRevision == rev
+}
+
+// inRange checks if `key` is the key of `op` or within its range.
+func inRange(op clientv3.Op, key string) bool {
+	start, end := string(op.KeyBytes()), string(op.RangeBytes())
+
+	if end == "" {
+		return key == start
+	}
+
+	return key >= start && key < end
+}
+
+type fakeTxn struct {
+	e    *fakeEtcd
+	cmps []clientv3.Cmp
+	then []clientv3.Op
+	els  []clientv3.Op
+}
+
+func (t *fakeTxn) If(cs ...clientv3.Cmp) clientv3.Txn {
+	t.cmps = append(t.cmps, cs...)
+	return t
+}
+
+func (t *fakeTxn) Then(ops ...clientv3.Op) clientv3.Txn {
+	t.then = append(t.then, ops...)
+	return t
+}
+
+func (t *fakeTxn) Else(ops ...clientv3.Op) clientv3.Txn {
+	t.els = append(t.els, ops...)
+	return t
+}
+
+func (t *fakeTxn) Commit() (*clientv3.TxnResponse, error) {
+	t.e.mu.Lock()
+	defer t.e.mu.Unlock()
+
+	succeeded := true
+
+	for _, cmp := range t.cmps {
+		succeeded = succeeded && t.e.compare(cmp)
+	}
+
+	ops := t.then
+
+	if !succeeded {
+		ops = t.els
+	}
+
+	for _, op := range ops {
+		t.e.do(op)
+	}
+
+	return &clientv3.TxnResponse{Header: t.e.header(), Succeeded: succeeded}, nil
+}
+
+func TestWatchListsAgainWhenCompacted(t *testing.T) {
+	s, e := fakeStore(t)
+	a, b := key(s, "features/default/a"), key(s, "features/default/b")
+	s.Set(a, []byte("a"))
+
+	// written and compacted between the list and the watch
+	e.afterList = func() {
+		s.Set(b, []byte("b"))
+
+		e.mu.Lock()
+		e.compacted = e.rev
+		e.mu.Unlock()
+	}
+
+	updates := make(chan stores.KVBytes, 10)
+	s.Register(func(kvb stores.KVBytes) {
+		updates <- kvb
+	})
+
+	go s.Watch()
+
+	waitFor(t, updates, a)
+	waitFor(t, updates, a, b)
+}
+
+func TestClose(t *testing.T) {
+	s, e := fakeStore(t)
+	flag := key(s, "features/default/flag")
+
+	s.Close()
+	s.Set(flag, []byte("a"))
+	s.Get(flag)
+	assert.Equal(t, 1, e.dials)
+	assert.Equal(t, 0, e.closed)
+
+	s.Close()
+	assert.Equal(t, 1, e.closed)
+
+	s.Get(flag)
+	assert.Equal(t, 2, e.dials)
+
+	// calls in flight keep the client open until they return
+	_, err := s.acquire()
+	assert.NoError(t, err)
+
+	s.Close()
+	assert.Equal(t, 1, e.closed)
+
+	s.release()
+	assert.Equal(t, 2, e.closed)
+}
@@ -0,0 +1,322 @@
+package file
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/vsco/dcdr/cli/api/stores"
+	"github.com/vsco/dcdr/cli/printer"
+	"github.com/vsco/dcdr/config"
+)
+
+const (
+	// extension appended to keys so a feature and a scope sharing a
+	// name do not collide
+	extension = ".json"
+	lockName  = ".lock"
+	tmpPrefix = ".dcdr-"
+)
+
+// ErrFileConfig returned when no store path is configured.
+var ErrFileConfig = errors.New("missing file store path")
+
+// Store writes each key to a JSON file beneath `root`, mirroring the
+// key's path. Writes are atomic renames made while holding an exclusive
+// lock on the directory so several processes can share it.
+type Store struct {
+	cfg  *config.Config
+	root string
+	cb   func(kvb stores.KVBytes)
+}
+
+// New creates the store directory if needed.
+func New(cfg *config.Config) (*Store, error) {
+	if cfg.File.Path == "" {
+		return nil, ErrFileConfig
+	}
+
+	if err := os.MkdirAll(cfg.File.Path, 0755); err != nil {
+		return nil, err
+	}
+
+	return &Store{
+		cfg:  cfg,
+		root: cfg.File.Path,
+	}, nil
+}
+
+func (s *Store) Get(key string) (*stores.KVByte, error) {
+	bts, err := ioutil.ReadFile(s.path(key))
+
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return toKVByte(key, bts), nil
+}
+
+func (s *Store) Set(key string, bts []byte) error {
+	unlock, err := s.lock()
+
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	return s.write(key, bts)
+}
+
+// SetCAS uses a hash of the file contents as the version of `key`.
+func (s *Store) SetCAS(key string, bts []byte, version uint64) error {
+	unlock, err := s.lock()
+
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	current, err := s.Get(key)
+
+	if err != nil {
+		return err
+	}
+
+	if (current == nil && version != 0) || (current != nil && current.Version != version) {
+		return stores.ErrConflict
+	}
+
+	return s.write(key, bts)
+}
+
+func (s *Store) Delete(key string) error {
+	unlock, err := s.lock()
+
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	err = os.Remove(s.path(key))
+
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
+
+// List walks the deepest directory named by `prefix` and returns every
+// key beginning with `prefix` sorted.
+func (s *Store) List(prefix string) (stores.KVBytes, error) {
+	kvb := make(stores.KVBytes, 0)
+	dir := s.root
+
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		dir = filepath.Join(s.root, filepath.FromSlash(prefix[:i]))
+	}
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+
+			return err
+		}
+
+		if info.IsDir() || !isKeyFile(path) {
+			return nil
+		}
+
+		key, err := s.key(path)
+
+		if err != nil || !strings.HasPrefix(key, prefix) {
+			return err
+		}
+
+		bts, err := ioutil.ReadFile(path)
+
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		kvb = append(kvb, toKVByte(key, bts))
+
+		return nil
+	})
+
+	sort.Slice(kvb, func(i, j int) bool {
+		return kvb[i].Key < kvb[j].Key
+	})
+
+	return kvb, err
+}
+
+// Watch observes every directory beneath the store, calling the
+// registered callback with the namespace's keys after each change.
+func (s *Store) Watch() error {
+	w, err := fsnotify.NewWatcher()
+
+	if err != nil {
+		return err
+	}
+
+	defer w.Close()
+
+	if err = s.watchDirs(w, s.root); err != nil {
+		return err
+	}
+
+	s.UpdateKeys()
+
+	for {
+		select {
+		case event := <-w.Events:
+			if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+				if err = s.watchDirs(w, event.Name); err != nil {
+					printer.LogErrf("watch error: %v", err)
+				}
+
+				// keys may have been written before the directory was watched
+				s.UpdateKeys()
+				continue
+			}
+
+			if isKeyFile(event.Name) {
+				s.UpdateKeys()
+			}
+		case err := <-w.Errors:
+			printer.LogErrf("watch error: %v", err)
+		}
+	}
+}
+
+func (s *Store) UpdateKeys() {
+	kvbs, err := s.List(s.cfg.Namespace)
+
+	if err != nil {
+		printer.LogErrf("watch error: %v", err)
+		return
+	}
+
+	s.Updated(kvbs)
+}
+
+func (s *Store) Register(cb func(kvb stores.KVBytes)) {
+	s.cb = cb
+}
+
+func (s *Store) Updated(kvs interface{}) {
+	s.cb(kvs.(stores.KVBytes))
+}
+
+func (s *Store) Close() {}
+
+// watchDirs adds `dir` and its subdirectories to `w`. fsnotify does
+// not watch recursively so new scopes are added as they are created.
+func (s *Store) watchDirs(w *fsnotify.Watcher, dir string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() {
+			return err
+		}
+
+		return w.Add(path)
+	})
+}
+
+// write replaces the file for `key` by renaming a temporary file so
+// readers never observe a partial write.
+func (s *Store) write(key string, bts []byte) error {
+	path := s.path(key)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), tmpPrefix)
+
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(bts); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	if err = os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// lock takes an exclusive lock on the store shared with other processes.
+func (s *Store) lock() (func(), error) {
+	f, err := os.OpenFile(filepath.Join(s.root, lockName), os.O_CREATE|os.O_RDWR, 0644)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
+
+func (s *Store) path(key string) string {
+	return filepath.Join(s.root, filepath.FromSlash(key)+extension)
+}
+
+func (s *Store) key(path string) (string, error) {
+	rel, err := filepath.Rel(s.root, path)
+
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.ToSlash(strings.TrimSuffix(rel, extension)), nil
+}
+
+func isKeyFile(path string) bool {
+	name := filepath.Base(path)
+
+	return strings.HasSuffix(name, extension) && !strings.HasPrefix(name, tmpPrefix)
+}
+
+func toKVByte(key string, bts []byte) *stores.KVByte {
+	return &stores.KVByte{
+		Key:     key,
+		Bytes:   bts,
+		Version: stores.HashVersion(bts),
+	}
+}
@@ -0,0 +1,120 @@
+package file
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vsco/dcdr/cli/api/stores"
+	"github.com/vsco/dcdr/config"
+)
+
+func testStore(t *testing.T) *Store {
+	cfg := config.TestConfig()
+	cfg.File.Path = t.TempDir()
+
+	s, err := New(cfg)
+	assert.NoError(t, err)
+
+	return s
+}
+
+func TestNewWithoutPath(t *testing.T) {
+	cfg := config.TestConfig()
+	cfg.File.Path = ""
+
+	_, err := New(cfg)
+	assert.Equal(t, ErrFileConfig, err)
+}
+
+func TestSetGetDelete(t *testing.T) {
+	s := testStore(t)
+
+	kv, err := s.Get("dcdr/features/default/flag")
+	assert.NoError(t, err)
+	assert.Nil(t, kv)
+
+	assert.NoError(t, s.Set("dcdr/features/default/flag", []byte("a")))
+
+	kv, err = s.Get("dcdr/features/default/flag")
+	assert.NoError(t, err)
+	assert.Equal(t, "dcdr/features/default/flag", kv.Key)
+	assert.Equal(t, []byte("a"), kv.Bytes)
+	assert.NotZero(t, kv.Version)
+
+	assert.NoError(t, s.Delete("dcdr/features/default/flag"))
+	assert.NoError(t, s.Delete("dcdr/features/default/flag"))
+
+	kv, err = s.Get("dcdr/features/default/flag")
+	assert.NoError(t, err)
+	assert.Nil(t, kv)
+}
+
+func TestList(t *testing.T) {
+	s := testStore(t)
+
+	s.Set("dcdr/features/user-groups/beta", []byte("a"))
+	s.Set("dcdr/features/user-groups/beta/flag", []byte("b"))
+	s.Set("dcdr/features/default/flag", []byte("c"))
+	s.Set("dcdr/info", []byte("d"))
+
+	kvb, err := s.List("dcdr/features/user-groups")
+	assert.NoError(t, err)
+	assert.Len(t, kvb, 2)
+	assert.Equal(t, "dcdr/features/user-groups/beta", kvb[0].Key)
+	assert.Equal(t, "dcdr/features/user-groups/beta/flag", kvb[1].Key)
+
+	kvb, err = s.List("dcdr")
+	assert.NoError(t, err)
+	assert.Len(t, kvb, 4)
+
+	kvb, err = s.List("missing/")
+	assert.NoError(t, err)
+	assert.Empty(t, kvb)
+}
+
+func TestSetCAS(t *testing.T) {
+	s := testStore(t)
+
+	assert.NoError(t, s.SetCAS("dcdr/features/default/flag", []byte("a"), 0))
+	assert.Equal(t, stores.ErrConflict, s.SetCAS("dcdr/features/default/flag", []byte("b"), 0))
+
+	kv, _ := s.Get("dcdr/features/default/flag")
+
+	assert.NoError(t, s.SetCAS("dcdr/features/default/flag", []byte("b"), kv.Version))
+	assert.Equal(t, stores.ErrConflict, s.SetCAS("dcdr/features/default/flag", []byte("c"), kv.Version))
+}
+
+func TestWatch(t *testing.T) {
+	s := testStore(t)
+	updates := make(chan stores.KVBytes, 10)
+
+	s.Register(func(kvb stores.KVBytes) {
+		updates <- kvb
+	})
+
+	go s.Watch()
+
+	select {
+	case kvb := <-updates:
+		assert.Empty(t, kvb)
+	case <-time.After(time.Second):
+		t.Fatal("no initial update")
+	}
+
+	s.Set("dcdr/features/user-groups/beta/flag", []byte("a"))
+
+	timeout := time.After(2 * time.Second)
+
+	for {
+		select {
+		case kvb := <-updates:
+			if len(kvb) == 1 {
+				assert.Equal(t, "dcdr/features/user-groups/beta/flag", kvb[0].Key)
+				return
+			}
+		case <-timeout:
+			t.Fatal("no update for new scope")
+		}
+	}
+}
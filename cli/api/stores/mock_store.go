@@ -1,6 +1,9 @@
 package stores
 
-import "github.com/vsco/dcdr/models"
+import (
+	"github.com/vsco/dcdr/cli/repo"
+	"github.com/vsco/dcdr/models"
+)
 
 type MockStore struct {
 	Item  *KVByte
@@ -42,6 +45,10 @@ func (ms *MockStore) Set(key string, bts []byte) error {
 	return ms.Err
 }
 
+func (ms *MockStore) SetCAS(key string, bts []byte, version uint64) error {
+	return ms.Err
+}
+
 func (ms *MockStore) Delete(key string) error {
 	return ms.Err
 }
@@ -101,3 +108,15 @@ func (mr *MockRepo) CurrentSHA() (string, error) {
 
 func (mr *MockRepo) Init() {
 }
+
+func (mr *MockRepo) Log() ([]repo.Revision, error) {
+	return nil, mr.error
+}
+
+func (mr *MockRepo) Show(sha string) ([]byte, error) {
+	return nil, mr.error
+}
+
+func (mr *MockRepo) Contains(sha string) (bool, error) {
+	return mr.error == nil, mr.error
+}
@@ -2,28 +2,41 @@ package redis
 
 import (
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	"errors"
 
-	"strings"
-
 	"github.com/garyburd/redigo/redis"
 	"github.com/vsco/dcdr/cli/api/stores"
 	"github.com/vsco/dcdr/cli/printer"
 	"github.com/vsco/dcdr/config"
 )
 
-const DefaultAddr = ":6379"
+const (
+	DefaultAddr = ":6379"
 
-var (
-	ErrRedisConfig = errors.New("missing redis config address")
-	ErrIndexRange  = errors.New("K/V index out of range")
+	maxIdle     = 3
+	idleTimeout = 4 * time.Minute
+	scanCount   = 1000
 )
 
+var ErrRedisConfig = errors.New("missing redis config address")
+
+// ErrLegacyKeys returned when listing a namespace that is still stored
+// as a string per key by earlier versions.
+var ErrLegacyKeys = errors.New("namespace is stored as legacy string keys, run `dcdr migrate` to move them into its hash")
+
+// Store keeps each namespace in a single hash named after the
+// namespace, with a field per key, so a namespace can be read
+// atomically with `HGETALL`. Every write publishes the key and its new
+// value on a channel named after the key. Deletes publish an empty value.
+// Namespaces written by earlier versions, which stored a string per key,
+// are moved into the hash by `Migrate`.
 type Store struct {
 	cfg     *config.Config
-	conn    redis.Conn
-	psConn  redis.Conn
+	pool    *redis.Pool
 	psc     redis.PubSubConn
 	pubChan string
 	cb      func(kvb stores.KVBytes)
@@ -31,17 +44,15 @@ type Store struct {
 
 func New(cfg *config.Config) (*Store, error) {
 	if cfg.Redis.Address != "" {
-		c, err := redis.Dial("tcp", cfg.Redis.Address)
-		p, err := redis.Dial("tcp", cfg.Redis.Address)
-
-		if err != nil {
-			return nil, err
-		}
-
 		s := &Store{
-			cfg:     cfg,
-			conn:    c,
-			psConn:  p,
+			cfg: cfg,
+			pool: &redis.Pool{
+				MaxIdle:     maxIdle,
+				IdleTimeout: idleTimeout,
+				Dial: func() (redis.Conn, error) {
+					return redis.Dial("tcp", cfg.Redis.Address)
+				},
+			},
 			pubChan: fmt.Sprintf("%s/*", cfg.Namespace),
 		}
 
@@ -52,70 +63,183 @@ func New(cfg *config.Config) (*Store, error) {
 }
 
 func (s *Store) Get(key string) (*stores.KVByte, error) {
-	bts, err := redis.Bytes(s.conn.Do("GET", key))
+	conn := s.pool.Get()
+	defer conn.Close()
 
-	if err != nil {
-		// redigo is super cool and makes you check error strings,
-		// this is fine evidently.
-		if strings.Contains(err.Error(), "nil returned") {
-			return nil, nil
-		}
+	return get(conn, key)
+}
 
-		return nil, err
-	}
+func (s *Store) Set(key string, bts []byte) error {
+	conn := s.pool.Get()
+	defer conn.Close()
 
-	return toKVByte(key, bts), nil
+	_, err := s.write(conn, key, bts)
+
+	return err
 }
 
-func (s *Store) Set(key string, bts []byte) error {
-	_, err := s.conn.Do("SET", key, bts)
+// SetCAS redis keys have no version so a hash of the value is used.
+// The namespace hash is watched while the value is compared so that
+// writes made between the HGET and the EXEC abort the transaction.
+func (s *Store) SetCAS(key string, bts []byte, version uint64) error {
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	if _, err := conn.Do("WATCH", hashKey(key)); err != nil {
+		return err
+	}
+
+	current, err := get(conn, key)
 
 	if err != nil {
 		return err
 	}
 
-	return s.publish(key, bts)
-}
+	if (current == nil && version != 0) || (current != nil && current.Version != version) {
+		return stores.ErrConflict
+	}
 
-func (s *Store) Delete(key string) error {
-	_, err := s.conn.Do("DEL", key)
+	reply, err := s.write(conn, key, bts)
 
 	if err != nil {
 		return err
 	}
 
-	return s.publish(key, []byte(""))
+	// EXEC replies with nil when a watched key was modified
+	if reply == nil {
+		return stores.ErrConflict
+	}
+
+	return nil
+}
+
+func (s *Store) Delete(key string) error {
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	conn.Send("MULTI")
+	conn.Send("HDEL", hashKey(key), key)
+	conn.Send("PUBLISH", key, []byte(""))
+	_, err := conn.Do("EXEC")
+
+	return err
 }
 
-// List redis requires two operations in order to obtain the values
-// from a key prefix. First we append the '*' needed by the `KEYS`
-// lookup. Then use the return values as a variadic argument for the
-// `MGET` request. See `fetchKeys` and `fetchVals` for implementation.
+// List reads the hash holding `prefix` in a single `HGETALL` and
+// returns the keys beginning with `prefix` sorted. An empty hash is
+// checked for legacy string keys so that unmigrated namespaces return
+// `ErrLegacyKeys` rather than appearing empty.
 func (s *Store) List(prefix string) (stores.KVBytes, error) {
-	kvb, err := s.fetch(prefix)
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	kvs, err := redis.StringMap(conn.Do("HGETALL", hashKey(prefix)))
+
+	if err != nil {
+		return make(stores.KVBytes, 0), err
+	}
+
+	if len(kvs) == 0 {
+		keys, err := legacyKeys(conn, hashKey(prefix), 1)
+
+		if err != nil {
+			return make(stores.KVBytes, 0), err
+		}
+
+		if len(keys) > 0 {
+			return make(stores.KVBytes, 0), ErrLegacyKeys
+		}
+	}
+
+	return toKVBytes(kvs, prefix), nil
+}
+
+// Migrate moves the string keys written by earlier versions within
+// `namespace` into its hash and deletes them, returning the number of
+// keys moved. Keys already in the hash are not overwritten. Writers
+// running earlier versions should be stopped first.
+func (s *Store) Migrate(namespace string) (int, error) {
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	keys, err := legacyKeys(conn, namespace, 0)
+
+	if err != nil || len(keys) == 0 {
+		return 0, err
+	}
+
+	args := make([]interface{}, len(keys))
+
+	for i, k := range keys {
+		args[i] = k
+	}
+
+	values, err := redis.ByteSlices(conn.Do("MGET", args...))
+
+	if err != nil {
+		return 0, err
+	}
+
+	var moved []interface{}
+
+	// values are nil for keys that are not strings, which are left alone
+	for i, k := range keys {
+		if values[i] != nil {
+			moved = append(moved, k)
+		}
+	}
+
+	if len(moved) == 0 {
+		return 0, nil
+	}
+
+	conn.Send("MULTI")
+
+	for i, k := range keys {
+		if values[i] != nil {
+			conn.Send("HSETNX", hashKey(k), k, values[i])
+		}
+	}
 
-	return kvb, err
+	conn.Send("DEL", moved...)
+
+	if _, err := conn.Do("EXEC"); err != nil {
+		return 0, err
+	}
+
+	return len(moved), nil
 }
 
+// Watch subscribes to the namespace before listing it so no writes
+// are missed, then applies each published change to the listed keys.
 func (s *Store) Watch() error {
-	s.psc = redis.PubSubConn{Conn: s.psConn}
-	s.psc.PSubscribe(s.cfg.Namespace + "/*")
+	conn := s.pool.Get()
+	s.psc = redis.PubSubConn{Conn: conn}
 	defer s.psc.Close()
 
-	s.UpdateKeys()
-	s.messageHandler()
+	if err := s.psc.PSubscribe(s.pubChan); err != nil {
+		return err
+	}
 
-	return nil
-}
+	if err := s.subscribed(); err != nil {
+		return err
+	}
 
-func (s *Store) UpdateKeys() {
-	kvbs, err := s.List(s.cfg.Namespace)
+	current := make(map[string]*stores.KVByte)
+	kvb, err := s.List(s.cfg.Namespace)
 
 	if err != nil {
-		printer.LogErrf("pubsub error: %v", err)
+		return err
+	}
+
+	for _, kv := range kvb {
+		current[kv.Key] = kv
 	}
 
-	s.Updated(kvbs)
+	s.Updated(kvb)
+	s.messageHandler(current)
+
+	return nil
 }
 
 func (s *Store) Register(cb func(kvb stores.KVBytes)) {
@@ -126,11 +250,23 @@ func (s *Store) Updated(kvs interface{}) {
 	s.cb(kvs.(stores.KVBytes))
 }
 
-func (s *Store) messageHandler() {
+// subscribed waits for redis to confirm the subscription.
+func (s *Store) subscribed() error {
+	for {
+		switch n := s.psc.Receive().(type) {
+		case redis.Subscription:
+			return nil
+		case error:
+			return n
+		}
+	}
+}
+
+func (s *Store) messageHandler(current map[string]*stores.KVByte) {
 	for {
 		switch n := s.psc.Receive().(type) {
 		case redis.PMessage:
-			s.UpdateKeys()
+			s.Updated(apply(current, n))
 		case error:
 			printer.LogErrf("watch error: %v", n.Error())
 			return
@@ -138,90 +274,128 @@ func (s *Store) messageHandler() {
 	}
 }
 
-func (s *Store) Close() {
-	s.conn.Close()
-	s.psConn.Close()
+// Close connections are returned to the pool after each call.
+func (s *Store) Close() {}
+
+// write sets `key` and publishes it within a transaction, returning
+// the reply to EXEC.
+func (s *Store) write(conn redis.Conn, key string, bts []byte) (interface{}, error) {
+	conn.Send("MULTI")
+	conn.Send("HSET", hashKey(key), key, bts)
+	conn.Send("PUBLISH", key, bts)
+
+	return conn.Do("EXEC")
 }
 
-// fetch redis needs a KEYS and a MGET in order to grab all the K/Vs
-// for a prefix. This function merges them and returns the KVBytes.
-func (s *Store) fetch(prefix string) (stores.KVBytes, error) {
-	kvb := make(stores.KVBytes, 0)
-	keys, err := s.fetchKeys(prefix)
+// legacyKeys the string keys within `namespace` written by earlier
+// versions. The keyspace is walked with `SCAN` so redis is not blocked,
+// stopping once `limit` keys are found when `limit` is positive.
+func legacyKeys(conn redis.Conn, namespace string, limit int) ([]string, error) {
+	var keys []string
+	seen := make(map[string]bool)
+	cursor := 0
 
-	if err != nil {
-		return kvb, err
-	}
+	for {
+		values, err := redis.Values(conn.Do("SCAN", cursor, "MATCH", namespace+"/*", "COUNT", scanCount))
 
-	if len(keys) == 0 {
-		return kvb, nil
-	}
+		if err == redis.ErrNil {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
 
-	vals, err := s.fetchVals(keys)
+		var page []string
 
-	if err != nil {
-		return kvb, err
-	}
+		if _, err = redis.Scan(values, &cursor, &page); err != nil {
+			return nil, err
+		}
+
+		// SCAN may return a key more than once
+		for _, k := range page {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
 
-	for i, key := range keys {
-		if len(vals) >= i {
-			kk := fmt.Sprintf("%s", key)
-			kvb = append(kvb, toKVByte(kk, vals[i]))
-		} else {
-			return kvb, ErrIndexRange
+		if cursor == 0 || (limit > 0 && len(keys) >= limit) {
+			break
 		}
 	}
 
-	return kvb, nil
+	sort.Strings(keys)
+
+	return keys, nil
 }
 
-func (s *Store) fetchKeys(prefix string) ([]interface{}, error) {
-	var strs []interface{}
-	keys, err := s.keys(prefix)
+func get(conn redis.Conn, key string) (*stores.KVByte, error) {
+	bts, err := redis.Bytes(conn.Do("HGET", hashKey(key), key))
 
-	if err != nil {
-		return strs, err
+	if err == redis.ErrNil {
+		return nil, nil
 	}
 
-	for _, k := range keys {
-		strs = append(strs, k)
+	if err != nil {
+		return nil, err
 	}
 
-	return strs, nil
+	return toKVByte(key, bts), nil
 }
 
-func (s *Store) fetchVals(keys []interface{}) ([][]byte, error) {
-	vals := make([][]byte, len(keys))
-	keys, err := s.mget(keys)
-
-	if err != nil {
-		return vals, err
+// apply updates `current` with a published change and returns its keys sorted.
+func apply(current map[string]*stores.KVByte, msg redis.PMessage) stores.KVBytes {
+	if len(msg.Data) == 0 {
+		delete(current, msg.Channel)
+	} else {
+		current[msg.Channel] = toKVByte(msg.Channel, msg.Data)
 	}
 
-	for i, v := range keys {
-		vals[i] = v.([]byte)
+	kvb := make(stores.KVBytes, 0, len(current))
+
+	for _, kv := range current {
+		kvb = append(kvb, kv)
 	}
 
-	return vals, nil
+	sortKVBytes(kvb)
+
+	return kvb
 }
 
-func (s *Store) publish(k string, bts []byte) error {
-	_, err := s.conn.Do("PUBLISH", k, bts)
+// hashKey the name of the hash holding `key`, which is its namespace.
+func hashKey(key string) string {
+	if i := strings.Index(key, "/"); i >= 0 {
+		return key[:i]
+	}
 
-	return err
+	return key
 }
 
-func (s *Store) mget(keys []interface{}) ([]interface{}, error) {
-	return redis.Values(s.conn.Do("MGET", keys...))
+func toKVBytes(kvs map[string]string, prefix string) stores.KVBytes {
+	kvb := make(stores.KVBytes, 0, len(kvs))
+
+	for k, v := range kvs {
+		if strings.HasPrefix(k, prefix) {
+			kvb = append(kvb, toKVByte(k, []byte(v)))
+		}
+	}
+
+	sortKVBytes(kvb)
+
+	return kvb
 }
 
-func (s *Store) keys(prefix string) ([]interface{}, error) {
-	return redis.Values(s.conn.Do("KEYS", fmt.Sprintf("%s*", prefix)))
+func sortKVBytes(kvb stores.KVBytes) {
+	sort.Slice(kvb, func(i, j int) bool {
+		return kvb[i].Key < kvb[j].Key
+	})
 }
 
 func toKVByte(key string, bts []byte) *stores.KVByte {
 	return &stores.KVByte{
-		Key:   key,
-		Bytes: bts,
+		Key:     key,
+		Bytes:   bts,
+		Version: stores.HashVersion(bts),
 	}
 }
@@ -5,13 +5,22 @@ import (
 
 	"errors"
 
+	"github.com/garyburd/redigo/redis"
 	"github.com/stretchr/testify/assert"
+	"github.com/vsco/dcdr/cli/api/stores"
 	"github.com/vsco/dcdr/config"
 )
 
+// MockRedis replies to commands with the next reply queued in
+// `Replies`, then with `Responses` and with `Response` otherwise.
+// Commands are recorded in `Commands` and their arguments in `Args`.
 type MockRedis struct {
-	Response interface{}
-	Error    error
+	Response  interface{}
+	Responses map[string]interface{}
+	Replies   map[string][]interface{}
+	Error     error
+	Commands  []string
+	Args      [][]interface{}
 }
 
 func (r *MockRedis) Close() error {
@@ -23,10 +32,29 @@ func (r *MockRedis) Err() error {
 }
 
 func (r *MockRedis) Do(commandName string, args ...interface{}) (reply interface{}, err error) {
+	if commandName == "" {
+		return nil, nil
+	}
+
+	r.Commands = append(r.Commands, commandName)
+	r.Args = append(r.Args, args)
+
+	if replies := r.Replies[commandName]; len(replies) > 0 {
+		r.Replies[commandName] = replies[1:]
+		return replies[0], r.Error
+	}
+
+	if resp, ok := r.Responses[commandName]; ok {
+		return resp, r.Error
+	}
+
 	return r.Response, r.Error
 }
 
 func (r *MockRedis) Send(commandName string, args ...interface{}) error {
+	r.Commands = append(r.Commands, commandName)
+	r.Args = append(r.Args, args)
+
 	return nil
 }
 
@@ -38,60 +66,199 @@ func (r *MockRedis) Receive() (reply interface{}, err error) {
 	return nil, nil
 }
 
-func TestGet(t *testing.T) {
-	r := &Store{
+func mockStore(mr *MockRedis) *Store {
+	return &Store{
 		cfg: config.TestConfig(),
-		conn: &MockRedis{
-			Response: "abcde",
+		pool: &redis.Pool{
+			Dial: func() (redis.Conn, error) {
+				return mr, nil
+			},
 		},
 	}
+}
 
-	kv, err := r.Get("foo")
+func TestGet(t *testing.T) {
+	mr := &MockRedis{
+		Response: "abcde",
+	}
+
+	kv, err := mockStore(mr).Get("dcdr/features/default/foo")
 
 	assert.NoError(t, err)
-	assert.Equal(t, "foo", kv.Key)
+	assert.Equal(t, "dcdr/features/default/foo", kv.Key)
 	assert.Equal(t, []byte("abcde"), kv.Bytes)
+	assert.Equal(t, []string{"HGET"}, mr.Commands)
 }
 
-func TestGetNilError(t *testing.T) {
-	r := &Store{
-		cfg: config.TestConfig(),
-		conn: &MockRedis{
-			Error: errors.New("nil returned"),
+func TestGetNil(t *testing.T) {
+	kv, err := mockStore(&MockRedis{}).Get("dcdr/features/default/foo")
+
+	assert.Nil(t, kv)
+	assert.NoError(t, err)
+}
+
+func TestGetError(t *testing.T) {
+	e := errors.New("down")
+	kv, err := mockStore(&MockRedis{Error: e}).Get("dcdr/features/default/foo")
+
+	assert.Nil(t, kv)
+	assert.Equal(t, e, err)
+}
+
+func TestList(t *testing.T) {
+	mr := &MockRedis{
+		Response: []interface{}{
+			[]byte("dcdr/features/default/b"), []byte("b"),
+			[]byte("dcdr/info"), []byte("i"),
+			[]byte("dcdr/features/default/a"), []byte("a"),
 		},
 	}
 
-	kv, err := r.Get("foo")
+	kvb, err := mockStore(mr).List("dcdr/features")
 
-	assert.Nil(t, kv)
 	assert.NoError(t, err)
+	assert.Len(t, kvb, 2)
+	assert.Equal(t, "dcdr/features/default/a", kvb[0].Key)
+	assert.Equal(t, []byte("a"), kvb[0].Bytes)
+	assert.Equal(t, "dcdr/features/default/b", kvb[1].Key)
+	assert.Equal(t, []string{"HGETALL"}, mr.Commands)
 }
 
-func TestFetchKeys(t *testing.T) {
-	r := &Store{
-		cfg: config.TestConfig(),
-		conn: &MockRedis{
-			Response: []interface{}{[]byte("k")},
+func TestListLegacyKeys(t *testing.T) {
+	mr := &MockRedis{
+		Responses: map[string]interface{}{
+			"HGETALL": []interface{}{},
+		},
+		Replies: map[string][]interface{}{
+			"SCAN": {
+				[]interface{}{[]byte("7"), []interface{}{}},
+				[]interface{}{[]byte("9"), []interface{}{[]byte("dcdr/features/default/a")}},
+				[]interface{}{[]byte("0"), []interface{}{[]byte("dcdr/features/default/b")}},
+			},
 		},
 	}
 
-	bts, err := r.fetchKeys("k")
+	_, err := mockStore(mr).List("dcdr/features")
+
+	assert.Equal(t, ErrLegacyKeys, err)
+	assert.Equal(t, []string{"HGETALL", "SCAN", "SCAN"}, mr.Commands)
+	assert.Equal(t, []interface{}{7, "MATCH", "dcdr/*", "COUNT", scanCount}, mr.Args[2])
+
+	mr = &MockRedis{
+		Responses: map[string]interface{}{
+			"HGETALL": []interface{}{},
+		},
+	}
+
+	kvb, err := mockStore(mr).List("dcdr/features")
 
 	assert.NoError(t, err)
-	assert.Equal(t, []byte("k"), bts[0])
+	assert.Empty(t, kvb)
 }
 
-func TestFetch(t *testing.T) {
-	r := &Store{
-		cfg: config.TestConfig(),
-		conn: &MockRedis{
-			Response: []interface{}{[]byte("x")},
+func TestMigrate(t *testing.T) {
+	mr := &MockRedis{
+		Responses: map[string]interface{}{
+			"MGET": []interface{}{[]byte("a"), []byte("b"), nil},
+			"EXEC": []interface{}{int64(1), int64(1), int64(2)},
+		},
+		Replies: map[string][]interface{}{
+			"SCAN": {
+				[]interface{}{[]byte("3"), []interface{}{
+					[]byte("team/dcdr/features/default/b"),
+					[]byte("team/dcdr/features/default/a"),
+				}},
+				[]interface{}{[]byte("0"), []interface{}{
+					[]byte("team/dcdr/features/default/a"),
+					[]byte("team/dcdr/features/default/set"),
+				}},
+			},
 		},
 	}
 
-	bts, err := r.fetch("k")
+	n, err := mockStore(mr).Migrate("team/dcdr")
 
 	assert.NoError(t, err)
-	assert.Equal(t, "x", bts[0].Key)
-	assert.Equal(t, []byte("x"), bts[0].Bytes)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []string{"SCAN", "SCAN", "MGET", "MULTI", "HSETNX", "HSETNX", "DEL", "EXEC"}, mr.Commands)
+	assert.Equal(t, []interface{}{"team", "team/dcdr/features/default/a", []byte("a")}, mr.Args[4])
+	assert.Equal(t, []interface{}{"team", "team/dcdr/features/default/b", []byte("b")}, mr.Args[5])
+
+	mr = &MockRedis{}
+	n, err = mockStore(mr).Migrate("dcdr")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+	assert.Equal(t, []string{"SCAN"}, mr.Commands)
+}
+
+func TestSet(t *testing.T) {
+	mr := &MockRedis{
+		Response: []interface{}{int64(1), int64(1)},
+	}
+
+	err := mockStore(mr).Set("dcdr/features/default/foo", []byte("abcde"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"MULTI", "HSET", "PUBLISH", "EXEC"}, mr.Commands)
+}
+
+func TestSetCAS(t *testing.T) {
+	mr := &MockRedis{
+		Response: "abcde",
+	}
+	s := mockStore(mr)
+
+	kv, err := s.Get("dcdr/features/default/foo")
+
+	assert.NoError(t, err)
+	assert.NotZero(t, kv.Version)
+	assert.NoError(t, s.SetCAS("dcdr/features/default/foo", []byte("fghij"), kv.Version))
+	assert.Equal(t, stores.ErrConflict, s.SetCAS("dcdr/features/default/foo", []byte("fghij"), kv.Version+1))
+	assert.Equal(t, stores.ErrConflict, s.SetCAS("dcdr/features/default/foo", []byte("fghij"), 0))
+}
+
+func TestSetCASAborted(t *testing.T) {
+	mr := &MockRedis{
+		Responses: map[string]interface{}{
+			"WATCH": "OK",
+		},
+	}
+
+	err := mockStore(mr).SetCAS("dcdr/features/default/foo", []byte("fghij"), 0)
+
+	assert.Equal(t, stores.ErrConflict, err)
+	assert.Equal(t, []string{"WATCH", "HGET", "MULTI", "HSET", "PUBLISH", "EXEC"}, mr.Commands)
+}
+
+func TestDelete(t *testing.T) {
+	mr := &MockRedis{
+		Response: []interface{}{int64(1), int64(1)},
+	}
+
+	err := mockStore(mr).Delete("dcdr/features/default/foo")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"MULTI", "HDEL", "PUBLISH", "EXEC"}, mr.Commands)
+}
+
+func TestApply(t *testing.T) {
+	current := map[string]*stores.KVByte{
+		"dcdr/features/default/b": toKVByte("dcdr/features/default/b", []byte("b")),
+	}
+
+	kvb := apply(current, redis.PMessage{Channel: "dcdr/features/default/a", Data: []byte("a")})
+
+	assert.Len(t, kvb, 2)
+	assert.Equal(t, "dcdr/features/default/a", kvb[0].Key)
+
+	kvb = apply(current, redis.PMessage{Channel: "dcdr/features/default/b", Data: []byte("")})
+
+	assert.Len(t, kvb, 1)
+	assert.Equal(t, "dcdr/features/default/a", kvb[0].Key)
+}
+
+func TestHashKey(t *testing.T) {
+	assert.Equal(t, "dcdr", hashKey("dcdr/features/default/a"))
+	assert.Equal(t, "dcdr", hashKey("dcdr"))
 }
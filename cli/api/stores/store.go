@@ -1,10 +1,20 @@
 package stores
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+)
 
+// ErrConflict returned by `SetCAS` when a key was modified after it was read.
+var ErrConflict = errors.New("feature was modified by another writer. fetch it and try again")
+
+// KVByte a key and its value. `Version` changes with every write to
+// the key and is 0 for keys that do not exist.
 type KVByte struct {
-	Key   string
-	Bytes []byte
+	Key     string
+	Bytes   []byte
+	Version uint64
 }
 
 func (kv *KVByte) String() string {
@@ -18,8 +28,31 @@ type IFace interface {
 	Get(key string) (*KVByte, error)
 	Delete(key string) error
 	Set(key string, bts []byte) error
+	// SetCAS sets `key` only if its version still matches `version`,
+	// returning `ErrConflict` otherwise. A version of 0 only creates keys.
+	SetCAS(key string, bts []byte, version uint64) error
 	Register(func(kvb KVBytes))
 	Watch() error
 	Updated(kvs interface{})
 	Close()
 }
+
+// Migrator implemented by stores whose layout has changed between
+// versions. `Migrate` moves the keys within `namespace` written in an
+// earlier layout and returns how many were moved.
+type Migrator interface {
+	Migrate(namespace string) (int, error)
+}
+
+// HashVersion a hash of `bts` for stores without native versions. 0 is
+// reserved for missing keys.
+func HashVersion(bts []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(bts)
+
+	if v := h.Sum64(); v != 0 {
+		return v
+	}
+
+	return 1
+}
@@ -47,7 +47,7 @@ func (c *CLI) Commands() []climax.Command {
 
 
 	Lists feature flags. Use --prefix to match flags by a prefix and --scope to match
-	only flags within a given scope.`,
+	only flags within a given scope. --tag and --owner match flags by their metadata.`,
 
 			Flags: []climax.Flag{
 				{
@@ -64,6 +64,18 @@ func (c *CLI) Commands() []climax.Command {
 					Help:     `List only flags within a scope.`,
 					Variable: true,
 				},
+				{
+					Name:     "tag",
+					Usage:    `--tag="<tag>"`,
+					Help:     `List only flags with a tag.`,
+					Variable: true,
+				},
+				{
+					Name:     "owner",
+					Usage:    `--owner="<owner>"`,
+					Help:     `List only flags with an owner.`,
+					Variable: true,
+				},
 			},
 
 			Examples: []climax.Example{
@@ -75,10 +87,55 @@ func (c *CLI) Commands() []climax.Command {
 
 			Handle: c.Ctrl.List,
 		},
+		{
+			Name:  "get",
+			Brief: "show a feature flag and how it resolves",
+			Usage: `-name flag_name [-scope a,b,c] [-id 123]`,
+			Help: `
+
+
+	Prints the stored flag in each of the given scopes and the default scope, the
+	effective value after scope resolution and which scope it resolved from. Scopes
+	are given in priority order. Use --id to check whether an id falls within the
+	flag's percentile.`,
+
+			Flags: []climax.Flag{
+				{
+					Name:     "name",
+					Short:    "n",
+					Usage:    `--name="flag_name"`,
+					Help:     `the name of the flag to show`,
+					Variable: true,
+				},
+				{
+					Name:     "scope",
+					Short:    "s",
+					Usage:    `--scope="scope-a,scope-b"`,
+					Help:     `comma delimited scopes in priority order`,
+					Variable: true,
+				},
+				{
+					Name:     "id",
+					Short:    "i",
+					Usage:    `--id=123`,
+					Help:     `id to evaluate the flag for`,
+					Variable: true,
+				},
+			},
+
+			Examples: []climax.Example{
+				{
+					Usecase:     `-n flag_name -s user-groups/beta,country-codes/us --id 123`,
+					Description: `Shows how flag_name resolves for id 123 in the beta user group and us`,
+				},
+			},
+
+			Handle: c.Ctrl.Get,
+		},
 		{
 			Name:  "set",
 			Brief: "create or update a feature flag",
-			Usage: `set -name flag_name -value [0.0-1.0|true/false] -comment "flag description"`,
+			Usage: `set -name flag_name -value [0.0-1.0|true/false] [-type string|integer|json] -comment "flag description"`,
 			Help: `
 
 
@@ -100,6 +157,35 @@ func (c *CLI) Commands() []climax.Command {
 	fmt.Printf("%t", d.IsAvailableForID("new-signup-flow", <unint64>))
 	=> true
 
+	Percentile and boolean types are inferred from --value, as are JSON objects
+	and arrays. String and integer flags require an explicit --type when they
+	are created. Updates without --type parse --value as the existing type.
+
+	$ dcdr set -n checkout-variant -v blue -t string
+	$ dcdr set -n max-upload-mb -v 25 -t integer
+	$ dcdr set -n search-config -v '{"boost": 2, "fields": ["title"]}' -t json
+
+	Variant flags allocate ids between named variants by relative weight. Clients
+	read the allocated variant with Variant(feature, id).
+
+	$ dcdr set -n checkout-experiment -v "control:50,blue:25,green:25" -t variant
+
+	Boolean and percentile flags can carry targeting rules. Rules are separated
+	by semicolons and must all match the EvalContext passed to IsAvailableFor.
+	Supported operators are in, not_in, ==, !=, >, >=, <, <= and segment, which
+	matches against the JSON array stored in the named feature.
+
+	$ dcdr set -n new-signup-flow -v true -r "country in us,ca; app_version >= 5.2"
+	$ dcdr set -n new-signup-flow -r "user_id segment beta-testers"
+	$ dcdr set -n new-signup-flow --clear-rules
+
+	Flags can record an owner, tags, and an expiry date. These are kept when
+	omitted from later updates and removed with --clear-owner, --clear-tags,
+	and --clear-expires.
+
+	$ dcdr set -n new-signup-flow -o growth --tags signup,experiment --expires 2026-03-01
+	$ dcdr set -n new-signup-flow --clear-owner --clear-expires
+
 	If the audit repo has been configured in config.hcl, dcdr
 	will export the full feature set and write it to <Git:RepoPath> and then
 	attempt to commit and push the changeset to <Git:RepoURL>. If the commit is successful
@@ -121,6 +207,26 @@ func (c *CLI) Commands() []climax.Command {
 					Help:     `the value of the flag`,
 					Variable: true,
 				},
+				{
+					Name:     "type",
+					Short:    "t",
+					Usage:    `--type=percentile|boolean|string|integer|json|variant`,
+					Help:     `an optional type, required for string, integer and variant flags`,
+					Variable: true,
+				},
+				{
+					Name:     "rules",
+					Short:    "r",
+					Usage:    `--rules="country in us,ca; app_version >= 5.2"`,
+					Help:     `optional targeting rules for boolean and percentile flags`,
+					Variable: true,
+				},
+				{
+					Name:     "clear-rules",
+					Usage:    `--clear-rules`,
+					Help:     `remove existing targeting rules`,
+					Variable: false,
+				},
 				{
 					Name:     "comment",
 					Short:    "c",
@@ -135,6 +241,43 @@ func (c *CLI) Commands() []climax.Command {
 					Help:     `an optional scope to nest the flag within`,
 					Variable: true,
 				},
+				{
+					Name:     "owner",
+					Short:    "o",
+					Usage:    `--owner="team-or-user"`,
+					Help:     `an optional owner of the flag`,
+					Variable: true,
+				},
+				{
+					Name:     "clear-owner",
+					Usage:    `--clear-owner`,
+					Help:     `remove the existing owner`,
+					Variable: false,
+				},
+				{
+					Name:     "tags",
+					Usage:    `--tags="tag-a,tag-b"`,
+					Help:     `optional comma separated tags`,
+					Variable: true,
+				},
+				{
+					Name:     "clear-tags",
+					Usage:    `--clear-tags`,
+					Help:     `remove the existing tags`,
+					Variable: false,
+				},
+				{
+					Name:     "expires",
+					Usage:    `--expires=YYYY-MM-DD`,
+					Help:     `an optional date after which the flag should be removed`,
+					Variable: true,
+				},
+				{
+					Name:     "clear-expires",
+					Usage:    `--clear-expires`,
+					Help:     `remove the existing expiry date`,
+					Variable: false,
+				},
 			},
 
 			Examples: []climax.Example{
@@ -146,6 +289,14 @@ func (c *CLI) Commands() []climax.Command {
 					Usecase:     `-n "flag_name" -v false -c "the flag desc"`,
 					Description: `sets a boolean flag to false`,
 				},
+				{
+					Usecase:     `-n "flag_name" -v variant-a -t string`,
+					Description: `sets a string flag to 'variant-a'`,
+				},
+				{
+					Usecase:     `-n "flag_name" -v "control:50,treatment:50" -t variant`,
+					Description: `splits ids evenly between two variants`,
+				},
 			},
 
 			Handle: c.Ctrl.Set,
@@ -188,6 +339,292 @@ func (c *CLI) Commands() []climax.Command {
 
 			Handle: c.Ctrl.Delete,
 		},
+		{
+			Name:  "history",
+			Brief: "show the change history of a feature flag",
+			Usage: `-name flag_name [-scope flag_scope]`,
+			Help: `
+
+
+	Walks the audit repository log and lists every change to a flag's value along
+	with its author, date and commit SHA. Requires a configured repository.`,
+
+			Flags: []climax.Flag{
+				{
+					Name:     "name",
+					Short:    "n",
+					Usage:    `--name="flag_name"`,
+					Help:     `the name of the flag`,
+					Variable: true,
+				},
+				{
+					Name:     "scope",
+					Short:    "s",
+					Usage:    `--scope="flag scope"`,
+					Help:     `the scope of the flag. defaults to 'default'`,
+					Variable: true,
+				},
+			},
+
+			Examples: []climax.Example{
+				{
+					Usecase:     `-n flag_name -s user-groups/beta`,
+					Description: `Lists changes to flag_name in the user-groups/beta scope`,
+				},
+			},
+
+			Handle: c.Ctrl.History,
+		},
+		{
+			Name:  "rollback",
+			Brief: "restore feature flags to a previous commit",
+			Usage: `-sha commit_sha [-name flag_name] [-scope flag_scope]`,
+			Help: `
+
+
+	Restores flags to their state at the given audit repository commit. When a
+	--name is given only that flag is restored, otherwise every flag in the
+	namespace is. The rollback is committed and pushed like any other change.`,
+
+			Flags: []climax.Flag{
+				{
+					Name:     "sha",
+					Usage:    `--sha="commit_sha"`,
+					Help:     `the commit to restore`,
+					Variable: true,
+				},
+				{
+					Name:     "name",
+					Short:    "n",
+					Usage:    `--name="flag_name"`,
+					Help:     `only restore this flag`,
+					Variable: true,
+				},
+				{
+					Name:     "scope",
+					Short:    "s",
+					Usage:    `--scope="flag scope"`,
+					Help:     `the scope of the flag to restore. defaults to 'default'`,
+					Variable: true,
+				},
+			},
+
+			Examples: []climax.Example{
+				{
+					Usecase:     `--sha 3a1f9c2 -n flag_name`,
+					Description: `Restores flag_name in the default scope to its value at 3a1f9c2`,
+				},
+				{
+					Usecase:     `--sha 3a1f9c2`,
+					Description: `Restores every flag to its state at 3a1f9c2`,
+				},
+			},
+
+			Handle: c.Ctrl.Rollback,
+		},
+		{
+			Name:  "reconcile",
+			Brief: "apply the features in the audit repo to the store",
+			Usage: `[--dry-run] [--interval 30s]`,
+			Help: `
+
+
+	Pulls the audit repository and makes the K/V store match its decider.json,
+	creating, updating and deleting flags as needed. This allows the repository to
+	be the source of truth with changes made through reviewed pull requests.
+	Nothing is applied if the store's info/current_sha is not in the repository's
+	history. Use --interval to keep reconciling on a loop.`,
+
+			Flags: []climax.Flag{
+				{
+					Name:     "dry-run",
+					Usage:    `--dry-run`,
+					Help:     `print the changes without applying them`,
+					Variable: false,
+				},
+				{
+					Name:     "interval",
+					Usage:    `--interval=30s`,
+					Help:     `reconcile continuously at this interval`,
+					Variable: true,
+				},
+			},
+
+			Examples: []climax.Example{
+				{
+					Usecase:     `--dry-run`,
+					Description: `Prints the changes needed for the store to match the repo`,
+				},
+				{
+					Usecase:     `--interval 1m`,
+					Description: `Reconciles the store every minute`,
+				},
+			},
+
+			Handle: c.Ctrl.Reconcile,
+		},
+		{
+			Name:  "schedule",
+			Brief: "schedule changes to a feature flag",
+			Usage: `create|list|cancel [-n flag-name] [-s scope] [--steps "<time>=<value>;..."] [--from 0.0 --to 1.0 --over 7d --every 6h]`,
+			Help: `
+
+
+	Plans changes to the value of a flag that dcdr scheduler applies once they are
+	due. A schedule is either a list of steps or a linear ramp of a percentile.
+	Creating a schedule replaces any existing schedule for the flag.
+
+	$ dcdr schedule create -n new-signup-flow --steps "2026-03-01=0.1;2026-03-02T09:00:00Z=0.5"
+	$ dcdr schedule create -n new-signup-flow --from 0.0 --to 1.0 --over 7d --every 6h
+	$ dcdr schedule list
+	$ dcdr schedule cancel -n new-signup-flow
+
+	Times are dates (midnight UTC) or RFC3339 timestamps. Step values are parsed
+	as the flag's type. Ramps start now unless --start is given.`,
+
+			Flags: []climax.Flag{
+				{
+					Name:     "name",
+					Short:    "n",
+					Usage:    `--name="flag-name"`,
+					Help:     `the name of the flag to schedule`,
+					Variable: true,
+				},
+				{
+					Name:     "scope",
+					Short:    "s",
+					Usage:    `--scope="flag-scope"`,
+					Help:     `the scope of the flag`,
+					Variable: true,
+				},
+				{
+					Name:     "steps",
+					Usage:    `--steps="2026-03-01=0.1;2026-03-02=0.5"`,
+					Help:     `semicolon delimited <time>=<value> steps`,
+					Variable: true,
+				},
+				{
+					Name:     "from",
+					Usage:    `--from=0.0`,
+					Help:     `the starting percentile of a ramp`,
+					Variable: true,
+				},
+				{
+					Name:     "to",
+					Usage:    `--to=1.0`,
+					Help:     `the final percentile of a ramp`,
+					Variable: true,
+				},
+				{
+					Name:     "over",
+					Usage:    `--over=7d`,
+					Help:     `the duration of a ramp`,
+					Variable: true,
+				},
+				{
+					Name:     "every",
+					Usage:    `--every=6h`,
+					Help:     `the time between ramp steps. defaults to 1h`,
+					Variable: true,
+				},
+				{
+					Name:     "start",
+					Usage:    `--start=2026-03-01T09:00:00Z`,
+					Help:     `when a ramp starts. defaults to now`,
+					Variable: true,
+				},
+			},
+
+			Examples: []climax.Example{
+				{
+					Usecase:     `create -n new-signup-flow --from 0.0 --to 1.0 --over 7d --every 6h`,
+					Description: `Ramps new-signup-flow to 100% over a week`,
+				},
+				{
+					Usecase:     `list`,
+					Description: `Lists pending schedules`,
+				},
+			},
+
+			Handle: c.Ctrl.Schedule,
+		},
+		{
+			Name:  "scheduler",
+			Brief: "apply scheduled feature flag changes",
+			Usage: `[--interval 1m]`,
+			Help: `
+
+
+	Applies schedule steps once they are due, committing each change to the audit
+	repo. When several steps are due only the latest is applied. Replicas can be
+	run for availability: each interval a replica takes or renews a lock in the
+	store and only the lock holder applies steps. The lock is held for three
+	intervals so another replica takes over if the holder stops, and is renewed
+	before each feature is set. SIGINT and SIGTERM release the lock on shutdown.`,
+
+			Flags: []climax.Flag{
+				{
+					Name:     "interval",
+					Usage:    `--interval=1m`,
+					Help:     `how often to apply due steps. defaults to 1m`,
+					Variable: true,
+				},
+			},
+
+			Examples: []climax.Example{
+				{
+					Usecase:     `--interval 30s`,
+					Description: `Applies due steps every 30 seconds`,
+				},
+			},
+
+			Handle: c.Ctrl.Scheduler,
+		},
+		{
+			Name:  "diff",
+			Brief: "compare two sets of feature flags",
+			Usage: `<from> <to> [--json]`,
+			Help: `
+
+
+	Compares the flags in two sources and prints the keys that were added, removed
+	or changed. Sources are formatted as <kind>:<value> where kind is one of:
+
+	  scope:<scope>          flags within a scope of the current namespace
+	  namespace:<namespace>  every flag within a namespace
+	  file:<path>            a snapshot such as the watcher's decider.json or
+	                         the output of dcdr export in any format
+	  sha:<sha>              the flags committed to the audit repository at a SHA
+
+	Sources without a kind are treated as scopes. Scopes are compared by flag name
+	and all other sources by <scope>/<name>.`,
+
+			Flags: []climax.Flag{
+				{
+					Name:     "json",
+					Usage:    `--json`,
+					Help:     `print the diff as JSON`,
+					Variable: false,
+				},
+			},
+
+			Examples: []climax.Example{
+				{
+					Usecase:     `default user-groups/beta`,
+					Description: `Compares the default scope to the beta user group`,
+				},
+				{
+					Usecase:     `namespace:staging namespace:production --json`,
+					Description: `Compares staging and production as JSON`,
+				},
+				{
+					Usecase:     `sha:3a1f9c2 file:/etc/dcdr/decider.json`,
+					Description: `Compares an audit repo commit to a watcher's snapshot`,
+				},
+			},
+
+			Handle: c.Ctrl.Diff,
+		},
 		{
 			Name:  "init",
 			Brief: "init the audit repository",
@@ -229,6 +666,20 @@ func (c *CLI) Commands() []climax.Command {
 
 			Handle: c.Ctrl.Init,
 		},
+		{
+			Name:  "migrate",
+			Brief: "migrate features written by earlier versions",
+			Usage: `dcdr migrate`,
+			Help: `
+
+	Moves features written by earlier versions of the configured store into its current
+	layout. Redis namespaces were stored as a string per key and are now kept in a hash
+	named after the namespace. Existing hash fields are not overwritten, and stores that
+	have not changed layout have nothing to migrate. Stop writers running earlier versions
+	before migrating.`,
+
+			Handle: c.Ctrl.Migrate,
+		},
 		{
 			Name:  "info",
 			Brief: "display config info",
@@ -263,21 +714,261 @@ func (c *CLI) Commands() []climax.Command {
 
 			Handle: c.Ctrl.Serve,
 		},
+		{
+			Name:  "apply",
+			Brief: "apply a manifest of feature flags in a single commit",
+			Usage: `-f flags.yaml [--format json|flat-json|yaml|dotenv] [--dry-run]`,
+			Help: `
+
+
+	Validates every flag in a manifest, prints the plan of flags to create and
+	update, applies them all, and records a single commit. Nothing is applied if
+	any flag has an invalid type or value or would change the type of an existing
+	flag. Manifests use the same formats as dcdr export and the format is chosen
+	from the file extension unless --format is given.`,
+
+			Flags: []climax.Flag{
+				{
+					Name:     "file",
+					Short:    "f",
+					Usage:    `--file=flags.yaml`,
+					Help:     `the manifest to apply`,
+					Variable: true,
+				},
+				{
+					Name:     "format",
+					Usage:    `--format=json|flat-json|yaml|dotenv`,
+					Help:     `the manifest format`,
+					Variable: true,
+				},
+				{
+					Name:     "dry-run",
+					Usage:    `--dry-run`,
+					Help:     `print the plan without applying it`,
+					Variable: false,
+				},
+			},
+
+			Examples: []climax.Example{
+				{
+					Usecase:     `-f flags.yaml --dry-run`,
+					Description: `Validates flags.yaml and prints the plan`,
+				},
+			},
+
+			Handle: c.Ctrl.Apply,
+		},
+		{
+			Name:  "stale",
+			Brief: "report and clean up stale feature flags",
+			Usage: `[--days 30] [--evaluations evaluations.json] [--delete]`,
+			Help: `
+
+
+	Reports flags that are past their expiry, fully rolled out (true or 1.0 in
+	every scope without rules) for longer than --days, or, when --evaluations is
+	given, have no recorded evaluations. Rollouts are dated from the audit repo
+	history or from when flags were last updated when no repo is configured.
+
+	--evaluations reads a JSON object of flag names to evaluation counts, such as
+	the file written by StatsClient.WriteEvaluations() in your services.
+
+	With --delete every reported flag is deleted and the deletions are recorded
+	in a single commit.`,
+
+			Flags: []climax.Flag{
+				{
+					Name:     "days",
+					Short:    "d",
+					Usage:    `--days=30`,
+					Help:     `days a flag must be fully rolled out to be stale. defaults to 30`,
+					Variable: true,
+				},
+				{
+					Name:     "evaluations",
+					Short:    "e",
+					Usage:    `--evaluations=evaluations.json`,
+					Help:     `evaluation counts used to find unused flags`,
+					Variable: true,
+				},
+				{
+					Name:     "delete",
+					Usage:    `--delete`,
+					Help:     `delete the stale flags`,
+					Variable: false,
+				},
+			},
+
+			Examples: []climax.Example{
+				{
+					Usecase:     `--days 14`,
+					Description: `Lists flags expired or fully rolled out for two weeks`,
+				},
+				{
+					Usecase:     `-e evaluations.json --delete`,
+					Description: `Deletes stale and unused flags`,
+				},
+			},
+
+			Handle: c.Ctrl.Stale,
+		},
+		{
+			Name:  "refs",
+			Brief: "find feature flag references in Go source",
+			Usage: `[path...] [--json]`,
+			Help: `
+
+
+	Parses the Go files within each path, defaulting to the current directory, and
+	finds calls to client methods such as IsAvailable, IsAvailableForID, ScaleValue
+	and FeatureExists with a literal flag name. The references are compared to the
+	flags in the namespace to report flags that are never referenced and
+	references to flags that do not exist. Exits with 1 when undefined flags are
+	referenced. vendor, testdata and hidden directories are skipped.`,
+
+			Flags: []climax.Flag{
+				{
+					Name:     "json",
+					Usage:    `--json`,
+					Help:     `print the references as JSON`,
+					Variable: false,
+				},
+			},
+
+			Examples: []climax.Example{
+				{
+					Usecase:     `./cmd ./internal`,
+					Description: `Reports unused and undefined flags for two directories`,
+				},
+			},
+
+			Handle: c.Ctrl.Refs,
+		},
+		{
+			Name:  "generate",
+			Brief: "generate typed accessors for feature flags",
+			Usage: `go [-o flags_gen.go] [-p flags] [-f snapshot.json] [--format json|flat-json|yaml|dotenv]`,
+			Help: `
+
+
+	Generates a Go package with a key constant and a typed function for each flag
+	in the namespace, or in a file written by dcdr export, so misspelled flag names
+	become compile errors. Each function calls the client method for the flag's
+	type and is documented with the flag's comment.
+
+	  boolean     func NewSignupFlow(c client.IFace) bool
+	  percentile  func CheckoutRollout(c client.IFace, id uint64) bool
+	  rules       func Targeted(c client.IFace, ctx client.EvalContext) bool
+	  string      func Greeting(c client.IFace, defaultValue string) string
+	  integer     func Limit(c client.IFace, defaultValue int64) int64
+	  json        func Config(c client.IFace, v interface{}) error
+	  variant     func Experiment(c client.IFace, id uint64) string`,
+
+			Flags: []climax.Flag{
+				{
+					Name:     "output",
+					Short:    "o",
+					Usage:    `--output=flags_gen.go`,
+					Help:     `the file to write. defaults to STDOUT`,
+					Variable: true,
+				},
+				{
+					Name:     "package",
+					Short:    "p",
+					Usage:    `--package=flags`,
+					Help:     `the generated package name. defaults to flags`,
+					Variable: true,
+				},
+				{
+					Name:     "file",
+					Short:    "f",
+					Usage:    `--file=snapshot.json`,
+					Help:     `generate from an exported snapshot instead of the namespace`,
+					Variable: true,
+				},
+				{
+					Name:     "format",
+					Usage:    `--format=json|flat-json|yaml|dotenv`,
+					Help:     `the snapshot format`,
+					Variable: true,
+				},
+			},
+
+			Examples: []climax.Example{
+				{
+					Usecase:     `go -o flags/flags_gen.go`,
+					Description: `Generates accessors for the namespace`,
+				},
+				{
+					Usecase:     `go -f flags.yaml -p features`,
+					Description: `Generates accessors for a manifest to STDOUT`,
+				},
+			},
+
+			Handle: c.Ctrl.Generate,
+		},
+		{
+			Name:  "export",
+			Brief: "export feature flags to STDOUT",
+			Usage: `[-s scope] [--format json|flat-json|yaml|dotenv]`,
+			Help: `
+
+
+	Writes every flag in the namespace, or a single --scope, to STDOUT including
+	types, comments, rules and who last updated them. Exports can be read back in
+	with dcdr import using the same --format.
+
+	  json       flags nested by scope and name (default)
+	  flat-json  flags keyed by <scope>/<name>
+	  yaml       flags nested by scope and name
+	  dotenv     one variable per flag preceded by a metadata comment`,
+
+			Flags: []climax.Flag{
+				{
+					Name:     "scope",
+					Short:    "s",
+					Usage:    `--scope="some-scope"`,
+					Help:     `only export flags within this scope`,
+					Variable: true,
+				},
+				{
+					Name:     "format",
+					Short:    "f",
+					Usage:    `--format=json|flat-json|yaml|dotenv`,
+					Help:     `the output format. defaults to json`,
+					Variable: true,
+				},
+			},
+
+			Examples: []climax.Example{
+				{
+					Usecase:     `--format yaml > flags.yaml`,
+					Description: `Exports all flags as YAML`,
+				},
+				{
+					Usecase:     `-s user-groups/beta --format dotenv`,
+					Description: `Exports the beta user group as environment variables`,
+				},
+			},
+
+			Handle: c.Ctrl.Export,
+		},
 		{
 			Name:  "import",
-			Brief: "import json from STDIN",
-			Usage: ``,
+			Brief: "import feature flags from STDIN",
+			Usage: `[-s scope] [--format json|flat-json|yaml|dotenv]`,
 			Help: `
 
-	Imports JSON feature flags from a flat JSON KV structure such as...
+	Imports feature flags written by dcdr export or from a flat JSON KV structure
+	such as...
 
 	{
 		"some-feature":true,
 		"some-other-feature": 0.5
 	}
 
-	These KV pairs are set into the <Namespace>/default scope unless a --scope
-	param is provided.`,
+	Keys without a scope are set into the <Namespace>/default scope unless a
+	--scope param is provided. The --format defaults to flat-json.`,
 
 			Flags: []climax.Flag{
 				{
@@ -287,6 +978,20 @@ func (c *CLI) Commands() []climax.Command {
 					Help:     `scope to import the KVs into`,
 					Variable: true,
 				},
+				{
+					Name:     "format",
+					Short:    "f",
+					Usage:    `--format=json|flat-json|yaml|dotenv`,
+					Help:     `the input format. defaults to flat-json`,
+					Variable: true,
+				},
+			},
+
+			Examples: []climax.Example{
+				{
+					Usecase:     `--format yaml < flags.yaml`,
+					Description: `Imports flags exported as YAML`,
+				},
 			},
 
 			Handle: c.Ctrl.Import,
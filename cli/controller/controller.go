@@ -2,7 +2,13 @@ package controller
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"errors"
 
@@ -12,7 +18,9 @@ import (
 
 	"github.com/tucnak/climax"
 	"github.com/vsco/dcdr/cli/api"
+	"github.com/vsco/dcdr/cli/generate"
 	"github.com/vsco/dcdr/cli/printer"
+	"github.com/vsco/dcdr/cli/refs"
 	"github.com/vsco/dcdr/cli/ui"
 	"github.com/vsco/dcdr/client"
 	"github.com/vsco/dcdr/config"
@@ -23,79 +31,886 @@ import (
 const filePerms = 0775
 
 var (
-	errInvalidFeatureType = errors.New("invalid -value format. use -value=[0.0-1.0] or [true|false]")
+	errInvalidFeatureType = errors.New("invalid -value format. use -value=[0.0-1.0] or [true|false] or provide a -type")
+	errInvalidType        = errors.New("invalid -type. use -type=[percentile|boolean|string|integer|json|variant]")
+	errInvalidTypedValue  = errors.New("invalid -value for the provided -type")
+	errInvalidStoredValue = errors.New("invalid -value for the type of the existing feature")
 	errInvalidRange       = errors.New("invalid -value for percentile. use -value=[0.0-1.0]")
 	errNameRequired       = errors.New("-name is required")
+	errInvalidID          = errors.New("invalid -id. use a positive integer")
+	errSHARequired        = errors.New("-sha is required")
+	errInvalidInterval    = errors.New("invalid -interval. use a duration like 30s or 5m")
+	errDiffSources        = errors.New("two sources are required. use dcdr diff <from> <to>")
+	errFileRequired       = errors.New("-file is required")
+	errInvalidExpiry      = errors.New("invalid -expires. use YYYY-MM-DD or RFC3339")
+	errInvalidDays        = errors.New("invalid -days. use a positive integer")
+	errGenerateLanguage   = errors.New("unsupported language. use dcdr generate go")
+	errScheduleAction     = errors.New("unsupported action. use dcdr schedule create|list|cancel")
+	errScheduleRequired   = errors.New("-steps or a -from and -to ramp is required")
+	errInvalidSteps       = errors.New("invalid -steps. use -steps=\"<YYYY-MM-DD or RFC3339>=<value>;...\"")
+	errInvalidRamp        = errors.New("invalid ramp. use -from=0.0 -to=1.0 -over=7d -every=6h")
+	errInvalidStart       = errors.New("invalid -start. use YYYY-MM-DD or RFC3339")
 )
 
+const (
+	// defaultRampInterval the time between ramp steps when -every is omitted.
+	defaultRampInterval = time.Hour
+	// defaultSchedulerInterval how often `dcdr scheduler` applies due steps.
+	defaultSchedulerInterval = time.Minute
+	// schedulerLockIntervals the number of intervals the scheduler lock
+	// is held for, allowing a missed renewal before another replica takes over.
+	schedulerLockIntervals = 3
+)
+
+// defaultStaleDays how long a feature must be fully rolled out before
+// `dcdr stale` reports it.
+const defaultStaleDays = 30
+
 // Controller handler for CLI commands
 type Controller struct {
 	Config *config.Config
 	Client api.ClientIFace
 }
 
-// New creates a `Controller`
-func New(cfg *config.Config, kv api.ClientIFace) (cc *Controller) {
-	cc = &Controller{
-		Config: cfg,
-		Client: kv,
+// New creates a `Controller`
+func New(cfg *config.Config, kv api.ClientIFace) (cc *Controller) {
+	cc = &Controller{
+		Config: cfg,
+		Client: kv,
+	}
+
+	return
+}
+
+func (cc *Controller) List(ctx climax.Context) int {
+	pf, _ := ctx.Get("prefix")
+	scope, _ := ctx.Get("scope")
+	tag, _ := ctx.Get("tag")
+	owner, _ := ctx.Get("owner")
+
+	if pf != "" && scope == "" {
+		scope = models.DefaultScope
+	}
+
+	features, err := cc.Client.List(pf, scope)
+
+	if err != nil {
+		printer.SayErr("%v", err)
+		return 1
+	}
+
+	features = FilterFeatures(features, tag, owner)
+
+	if len(features) == 0 {
+		printer.Say("no feature flags found in namespace: %s",
+			cc.Client.Namespace())
+		return 1
+	}
+
+	ui.New().DrawFeatures(features)
+
+	return 0
+}
+
+// FilterFeatures returns the features tagged with `tag` and owned by
+// `owner`. Empty filters match every feature.
+func FilterFeatures(fts models.Features, tag string, owner string) models.Features {
+	filtered := models.Features{}
+
+	for _, ft := range fts {
+		if (tag == "" || ft.HasTag(tag)) && (owner == "" || ft.Owner == owner) {
+			filtered = append(filtered, ft)
+		}
+	}
+
+	return filtered
+}
+
+// Get prints the stored `Feature` in each of the provided scopes and
+// how it resolves, including whether `--id` falls within its percentile.
+func (cc *Controller) Get(ctx climax.Context) int {
+	name, _ := ctx.Get("name")
+	scp, _ := ctx.Get("scope")
+	idv, _ := ctx.Get("id")
+
+	if name == "" {
+		printer.SayErr("%v", errNameRequired)
+		return 1
+	}
+
+	var id uint64
+	var err error
+
+	if idv != "" {
+		id, err = strconv.ParseUint(idv, 10, 64)
+
+		if err != nil {
+			printer.SayErr("%v", errInvalidID)
+			return 1
+		}
+	}
+
+	scopes := ParseScopes(scp)
+	fts, err := cc.Resolve(name, scopes)
+
+	if err != nil {
+		printer.SayErr("%v", err)
+		return 1
+	}
+
+	if len(fts) == 0 {
+		printer.SayErr("%s not found in scopes: %s", name,
+			strings.Join(append(scopes, models.DefaultScope), ", "))
+		return 1
+	}
+
+	c, err := client.New(&config.Config{})
+
+	if err != nil {
+		printer.SayErr("%v", err)
+		return 1
+	}
+
+	c.SetFeatureMap(api.FeaturesToFeatureMap(fts))
+	detail := c.WithScopes(scopes...).Evaluate(name, id)
+
+	ui.New().DrawFeatures(fts)
+	ui.New().DrawResolution(detail, append(scopes, models.DefaultScope), idv != "")
+
+	return 0
+}
+
+// Resolve fetches the stored `Feature` for `name` in each of `scopes`
+// and the default scope in priority order, skipping scopes where it
+// is not set.
+func (cc *Controller) Resolve(name string, scopes []string) (models.Features, error) {
+	var fts models.Features
+
+	for _, scope := range append(scopes, models.DefaultScope) {
+		var ft *models.Feature
+
+		err := cc.Client.Get(fmt.Sprintf("%s/%s/%s", models.FeatureScope, scope, name), &ft)
+
+		if errors.Is(err, api.ErrNotFound) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		fts = append(fts, *ft)
+	}
+
+	return fts, nil
+}
+
+// ParseScopes splits a comma delimited list of scopes in priority order.
+func ParseScopes(scp string) []string {
+	var scopes []string
+
+	for _, s := range strings.Split(scp, ",") {
+		if s = strings.TrimSpace(s); s != "" && s != models.DefaultScope {
+			scopes = append(scopes, s)
+		}
+	}
+
+	return scopes
+}
+
+func (cc *Controller) Set(ctx climax.Context) int {
+	ft, err := cc.ParseContext(ctx)
+
+	if err != nil {
+		printer.SayErr("parse error: %v", err)
+		return 1
+	}
+
+	err = cc.Client.Set(ft)
+
+	if err != nil {
+		printer.SayErr("set error: %v", err)
+		return 1
+	}
+
+	printer.Say("set flag '%s'", ft.ScopedKey())
+
+	return cc.CommitFeatures(ft, false)
+}
+
+func (cc *Controller) Delete(ctx climax.Context) int {
+	name, _ := ctx.Get("name")
+	scope, _ := ctx.Get("scope")
+
+	if name == "" {
+		printer.Say("name is required")
+		return 1
+	}
+
+	if scope == "" {
+		scope = models.DefaultScope
+	}
+
+	err := cc.Client.Delete(name, scope)
+
+	if err != nil {
+		printer.SayErr("%v", err)
+		return 1
+	}
+
+	printer.Say("deleted flag %s/%s/%s",
+		cc.Config.Namespace, scope, name)
+
+	ft := &models.Feature{
+		Key:       name,
+		Scope:     scope,
+		UpdatedBy: cc.Config.Username,
+	}
+
+	return cc.CommitFeatures(ft, true)
+}
+
+func (cc *Controller) CommitFeatures(ft *models.Feature, deleted bool) int {
+	if !cc.Config.GitEnabled() {
+		return 0
+	}
+
+	printer.Say("committing changes")
+	sha, err := api.CommitFeatures(cc.Client, cc.Config, ft, deleted)
+
+	return cc.committed(sha, err)
+}
+
+// CommitChanges commits all changes to the audit repo with `msg`.
+func (cc *Controller) CommitChanges(msg string) int {
+	if !cc.Config.GitEnabled() {
+		return 0
+	}
+
+	printer.Say("committing changes")
+	sha, err := api.CommitChanges(cc.Client, cc.Config, msg)
+
+	return cc.committed(sha, err)
+}
+
+func (cc *Controller) committed(sha string, err error) int {
+	if sha != "" {
+		printer.Say("set info/current_sha: %s", sha)
+	}
+
+	if err != nil {
+		printer.SayErr("%v", err)
+		return 1
+	}
+
+	if cc.Config.PushEnabled() {
+		printer.Say("pushed commit to origin")
+	}
+
+	return 0
+}
+
+// History prints each change to a feature recorded in the audit repo.
+func (cc *Controller) History(ctx climax.Context) int {
+	name, _ := ctx.Get("name")
+	scope, _ := ctx.Get("scope")
+
+	if name == "" {
+		printer.SayErr("%v", errNameRequired)
+		return 1
+	}
+
+	if scope == "" {
+		scope = models.DefaultScope
+	}
+
+	revs, err := cc.Client.History(name, scope)
+
+	if err != nil {
+		printer.SayErr("%v", err)
+		return 1
+	}
+
+	if len(revs) == 0 {
+		printer.Say("no history found for %s/%s", scope, name)
+		return 0
+	}
+
+	ui.New().DrawHistory(revs)
+
+	return 0
+}
+
+// Rollback restores a feature or the whole namespace to its state at
+// `--sha` and commits the result.
+func (cc *Controller) Rollback(ctx climax.Context) int {
+	sha, _ := ctx.Get("sha")
+	name, _ := ctx.Get("name")
+	scope, _ := ctx.Get("scope")
+
+	if sha == "" {
+		printer.SayErr("%v", errSHARequired)
+		return 1
+	}
+
+	if scope == "" {
+		scope = models.DefaultScope
+	}
+
+	changes, err := cc.Client.Rollback(sha, name, scope)
+
+	for _, change := range changes {
+		if change.Deleted {
+			printer.Say("deleted flag '%s'", change.Feature.ScopedKey())
+		} else {
+			printer.Say("restored flag '%s' to %v", change.Feature.ScopedKey(), change.Feature.Value)
+		}
+	}
+
+	if err != nil {
+		printer.SayErr("%v", err)
+		return 1
+	}
+
+	if len(changes) == 0 {
+		printer.Say("nothing to roll back")
+		return 0
+	}
+
+	target := cc.Config.Namespace
+
+	if name != "" {
+		target = fmt.Sprintf("%s/%s", scope, name)
+	}
+
+	return cc.CommitChanges(fmt.Sprintf("%s rolled back %s to %s", cc.Config.Username, target, sha))
+}
+
+// Reconcile applies the features committed to the audit repo to the
+// store. With `--interval` it keeps reconciling until interrupted.
+func (cc *Controller) Reconcile(ctx climax.Context) int {
+	dryRun := ctx.Is("dry-run")
+	ivl, _ := ctx.Get("interval")
+
+	if ivl == "" {
+		return cc.reconcile(dryRun, printer.Say, printer.SayErr)
+	}
+
+	interval, err := time.ParseDuration(ivl)
+
+	if err != nil || interval <= 0 {
+		printer.SayErr("%v", errInvalidInterval)
+		return 1
+	}
+
+	printer.Logf("reconciling namespace %s every %s", cc.Config.Namespace, interval)
+
+	for {
+		cc.reconcile(dryRun, printer.Logf, printer.LogErrf)
+		time.Sleep(interval)
+	}
+}
+
+func (cc *Controller) reconcile(dryRun bool, say func(string, ...interface{}), sayErr func(string, ...interface{})) int {
+	changes, err := cc.Client.Reconcile(dryRun)
+
+	var prefix string
+
+	if dryRun {
+		prefix = "(dry run) "
+	}
+
+	for _, change := range changes {
+		if change.Deleted {
+			say("%sdelete flag '%s'", prefix, change.Feature.ScopedKey())
+		} else {
+			say("%sset flag '%s' to %v", prefix, change.Feature.ScopedKey(), change.Feature.Value)
+		}
+	}
+
+	if err != nil {
+		sayErr("%v", err)
+		return 1
+	}
+
+	if len(changes) == 0 {
+		say("store is up to date with %s", cc.Config.Git.RepoPath)
+	}
+
+	return 0
+}
+
+// Diff prints the keys added, removed, or changed between two sources.
+func (cc *Controller) Diff(ctx climax.Context) int {
+	if len(ctx.Args) != 2 {
+		printer.SayErr("%v", errDiffSources)
+		return 1
+	}
+
+	from, err := api.ParseSource(ctx.Args[0])
+
+	if err != nil {
+		printer.SayErr("%v", err)
+		return 1
+	}
+
+	to, err := api.ParseSource(ctx.Args[1])
+
+	if err != nil {
+		printer.SayErr("%v", err)
+		return 1
+	}
+
+	diff, err := cc.Client.Diff(from, to)
+
+	if err != nil {
+		printer.SayErr("%v", err)
+		return 1
+	}
+
+	if ctx.Is("json") {
+		bts, err := json.MarshalIndent(diff, "", "  ")
+
+		if err != nil {
+			printer.SayErr("%v", err)
+			return 1
+		}
+
+		fmt.Println(string(bts))
+
+		return 0
+	}
+
+	if diff.Empty() {
+		printer.Say("%s and %s are the same", from, to)
+		return 0
+	}
+
+	ui.New().DrawDiff(diff)
+
+	return 0
+}
+
+// Apply validates a manifest of features, prints the plan, applies
+// every change, and commits them together.
+func (cc *Controller) Apply(ctx climax.Context) int {
+	fp, _ := ctx.Get("file")
+	f, _ := ctx.Get("format")
+
+	if fp == "" {
+		printer.SayErr("%v", errFileRequired)
+		return 1
+	}
+
+	if f == "" {
+		f = formatForFile(fp)
+	}
+
+	format, err := api.ParseFormat(f)
+
+	if err != nil {
+		printer.SayErr("%v", err)
+		return 1
+	}
+
+	bts, err := ioutil.ReadFile(fp)
+
+	if err != nil {
+		printer.SayErr("%v", err)
+		return 1
+	}
+
+	return cc.ApplyManifest(bts, format, ctx.Is("dry-run"))
+}
+
+// ApplyManifest applies the features decoded from `bts`. Nothing is
+// applied if any feature is invalid.
+func (cc *Controller) ApplyManifest(bts []byte, format api.Format, dryRun bool) int {
+	fts, err := api.DecodeFeatures(bts, format, models.DefaultScope)
+
+	if err != nil {
+		printer.SayErr("%v", err)
+		return 1
+	}
+
+	for i := range fts {
+		fts[i].UpdatedBy = cc.Config.Username
+	}
+
+	changes, err := cc.Client.PlanApply(fts)
+
+	if errs, ok := err.(api.ManifestErrors); ok {
+		for _, e := range errs {
+			printer.SayErr("%v", e)
+		}
+
+		printer.SayErr("no changes applied")
+		return 1
+	}
+
+	if err != nil {
+		printer.SayErr("%v", err)
+		return 1
+	}
+
+	if len(changes) == 0 {
+		printer.Say("nothing to apply")
+		return 0
+	}
+
+	ui.New().DrawPlan(changes)
+
+	if dryRun {
+		return 0
+	}
+
+	err = cc.Client.ApplyAll(changes)
+
+	if err != nil {
+		printer.SayErr("%v", err)
+		printer.SayErr("no changes applied")
+		return 1
+	}
+
+	printer.Say("applied %d changes", len(changes))
+
+	return cc.CommitChanges(applyMessage(cc.Config.Username, changes))
+}
+
+// applyMessage a single commit message describing every change.
+func applyMessage(user string, changes []api.Change) string {
+	lines := []string{fmt.Sprintf("%s applied %d changes", user, len(changes)), ""}
+
+	for _, change := range changes {
+		lines = append(lines, fmt.Sprintf("set %s to %v", change.Feature.ScopedKey(), change.Feature.Value))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Stale prints features that are expired, fully rolled out for longer
+// than `--days`, or have no evaluations in `--evaluations`. With
+// `--delete` they are deleted and committed together.
+func (cc *Controller) Stale(ctx climax.Context) int {
+	dv, _ := ctx.Get("days")
+	ep, _ := ctx.Get("evaluations")
+
+	days := defaultStaleDays
+
+	if dv != "" {
+		d, err := strconv.Atoi(dv)
+
+		if err != nil || d <= 0 {
+			printer.SayErr("%v", errInvalidDays)
+			return 1
+		}
+
+		days = d
+	}
+
+	opts := api.StaleOptions{
+		Now:        time.Now(),
+		RolloutAge: time.Duration(days) * 24 * time.Hour,
+	}
+
+	if ep != "" {
+		evals, err := ReadEvaluations(ep)
+
+		if err != nil {
+			printer.SayErr("%v", err)
+			return 1
+		}
+
+		opts.Evaluations = evals
+	}
+
+	stale, err := cc.Client.Stale(opts)
+
+	if err != nil {
+		printer.SayErr("%v", err)
+		return 1
+	}
+
+	if len(stale) == 0 {
+		printer.Say("no stale feature flags found in namespace: %s", cc.Client.Namespace())
+		return 0
+	}
+
+	ui.New().DrawStale(stale)
+
+	if !ctx.Is("delete") {
+		return 0
+	}
+
+	return cc.DeleteStale(stale)
+}
+
+// DeleteStale deletes each of `stale` and commits the deletions together.
+// Features deleted before an error are still committed.
+func (cc *Controller) DeleteStale(stale []api.StaleFeature) int {
+	var deleted []string
+	status := 0
+
+	for _, sf := range stale {
+		err := cc.Client.Delete(sf.Key, sf.GetScope())
+
+		if err != nil {
+			printer.SayErr("could not delete %s: %v", sf.ScopedKey(), err)
+			status = 1
+			break
+		}
+
+		printer.Say("deleted flag '%s'", sf.ScopedKey())
+		deleted = append(deleted, sf.ScopedKey())
+	}
+
+	if len(deleted) == 0 {
+		return status
+	}
+
+	if cc.CommitChanges(staleMessage(cc.Config.Username, deleted)) != 0 {
+		return 1
+	}
+
+	return status
+}
+
+// staleMessage a single commit message listing every deleted key.
+func staleMessage(user string, deleted []string) string {
+	lines := []string{fmt.Sprintf("%s deleted %d stale features", user, len(deleted)), ""}
+
+	for _, key := range deleted {
+		lines = append(lines, fmt.Sprintf("deleted %s", key))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// ReadEvaluations reads a JSON object of feature names to evaluation
+// counts, such as the file written by `client.StatsClient.WriteEvaluations`.
+func ReadEvaluations(fp string) (map[string]uint64, error) {
+	bts, err := ioutil.ReadFile(fp)
+
+	if err != nil {
+		return nil, err
+	}
+
+	evals := make(map[string]uint64)
+	err = json.Unmarshal(bts, &evals)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not parse %s: %v", fp, err)
+	}
+
+	return evals, nil
+}
+
+// Refs scans the Go source in each path argument for feature references
+// and prints flags that are unused and references to undefined flags.
+func (cc *Controller) Refs(ctx climax.Context) int {
+	paths := ctx.Args
+
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	found, err := refs.Scan(paths...)
+
+	if err != nil {
+		printer.SayErr("%v", err)
+		return 1
+	}
+
+	fts, err := cc.Client.List("", "")
+
+	if err != nil {
+		printer.SayErr("%v", err)
+		return 1
+	}
+
+	report := refs.Compare(found, fts)
+
+	if ctx.Is("json") {
+		bts, err := json.MarshalIndent(report, "", "  ")
+
+		if err != nil {
+			printer.SayErr("%v", err)
+			return 1
+		}
+
+		fmt.Println(string(bts))
+	} else if len(report.Unused) == 0 && len(report.Undefined) == 0 {
+		printer.Say("found %d references. every flag is referenced and defined", len(found))
+	} else {
+		ui.New().DrawRefs(report)
+	}
+
+	if len(report.Undefined) > 0 {
+		return 1
+	}
+
+	return 0
+}
+
+// Generate writes typed Go accessors for the features in the namespace,
+// or in the snapshot at `--file`, to `--output` or STDOUT.
+func (cc *Controller) Generate(ctx climax.Context) int {
+	out, _ := ctx.Get("output")
+	pkg, _ := ctx.Get("package")
+	fp, _ := ctx.Get("file")
+	f, _ := ctx.Get("format")
+
+	if len(ctx.Args) != 1 || ctx.Args[0] != "go" {
+		printer.SayErr("%v", errGenerateLanguage)
+		return 1
+	}
+
+	var fts models.Features
+	var err error
+
+	if fp != "" {
+		fts, err = readFeatures(fp, f)
+	} else {
+		fts, err = cc.Client.List("", "")
+	}
+
+	if err != nil {
+		printer.SayErr("%v", err)
+		return 1
+	}
+
+	bts, err := generate.Go(fts, pkg)
+
+	if err != nil {
+		printer.SayErr("%v", err)
+		return 1
+	}
+
+	if out == "" {
+		os.Stdout.Write(bts)
+		return 0
+	}
+
+	err = ioutil.WriteFile(out, bts, 0644)
+
+	if err != nil {
+		printer.SayErr("%v", err)
+		return 1
+	}
+
+	printer.Say("wrote %s", out)
+
+	return 0
+}
+
+// readFeatures decodes the features exported to `fp` in format `f`,
+// which defaults to the format matching its extension.
+func readFeatures(fp string, f string) (models.Features, error) {
+	if f == "" {
+		f = formatForFile(fp)
+	}
+
+	format, err := api.ParseFormat(f)
+
+	if err != nil {
+		return nil, err
+	}
+
+	bts, err := ioutil.ReadFile(fp)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return api.DecodeFeatures(bts, format, models.DefaultScope)
+}
+
+// Schedule creates, lists, or cancels scheduled changes to a feature.
+func (cc *Controller) Schedule(ctx climax.Context) int {
+	if len(ctx.Args) != 1 {
+		printer.SayErr("%v", errScheduleAction)
+		return 1
 	}
 
-	return
+	switch ctx.Args[0] {
+	case "create":
+		return cc.createSchedule(ctx)
+	case "list":
+		return cc.listSchedules()
+	case "cancel":
+		return cc.cancelSchedule(ctx)
+	default:
+		printer.SayErr("%v", errScheduleAction)
+		return 1
+	}
 }
 
-func (cc *Controller) List(ctx climax.Context) int {
-	pf, _ := ctx.Get("prefix")
+func (cc *Controller) createSchedule(ctx climax.Context) int {
+	name, _ := ctx.Get("name")
 	scope, _ := ctx.Get("scope")
 
-	if pf != "" && scope == "" {
+	if name == "" {
+		printer.SayErr("%v", errNameRequired)
+		return 1
+	}
+
+	if scope == "" {
 		scope = models.DefaultScope
 	}
 
-	features, err := cc.Client.List(pf, scope)
+	var ft *models.Feature
+	err := cc.Client.Get(fmt.Sprintf("%s/%s/%s", models.FeatureScope, scope, name), &ft)
 
 	if err != nil {
 		printer.SayErr("%v", err)
 		return 1
 	}
 
-	if len(features) == 0 {
-		printer.Say("no feature flags found in namespace: %s",
-			cc.Client.Namespace())
+	steps, err := ParseScheduleContext(ctx, ft.FeatureType, time.Now().UTC())
+
+	if err != nil {
+		printer.SayErr("%v", err)
 		return 1
 	}
 
-	ui.New().DrawFeatures(features)
+	s := models.NewSchedule(name, scope, cc.Config.Namespace, cc.Config.Username, steps)
+	err = cc.Client.SetSchedule(s)
+
+	if err != nil {
+		printer.SayErr("%v", err)
+		return 1
+	}
+
+	printer.Say("scheduled %d steps for '%s'", len(s.Steps), s.FeatureKey())
+	ui.New().DrawSchedule(s)
 
 	return 0
 }
 
-func (cc *Controller) Set(ctx climax.Context) int {
-	ft, err := cc.ParseContext(ctx)
+func (cc *Controller) listSchedules() int {
+	schedules, err := cc.Client.Schedules()
 
 	if err != nil {
-		printer.SayErr("parse error: %v", err)
+		printer.SayErr("%v", err)
 		return 1
 	}
 
-	err = cc.Client.Set(ft)
-
-	if err != nil {
-		printer.SayErr("set error: %v", err)
-		return 1
+	if len(schedules) == 0 {
+		printer.Say("no schedules found in namespace: %s", cc.Client.Namespace())
+		return 0
 	}
 
-	printer.Say("set flag '%s'", ft.ScopedKey())
+	ui.New().DrawSchedules(schedules)
 
-	return cc.CommitFeatures(ft, false)
+	return 0
 }
 
-func (cc *Controller) Delete(ctx climax.Context) int {
+func (cc *Controller) cancelSchedule(ctx climax.Context) int {
 	name, _ := ctx.Get("name")
 	scope, _ := ctx.Get("scope")
 
 	if name == "" {
-		printer.Say("name is required")
+		printer.SayErr("%v", errNameRequired)
 		return 1
 	}
 
@@ -103,58 +918,232 @@ func (cc *Controller) Delete(ctx climax.Context) int {
 		scope = models.DefaultScope
 	}
 
-	err := cc.Client.Delete(name, scope)
+	err := cc.Client.DeleteSchedule(name, scope)
 
 	if err != nil {
 		printer.SayErr("%v", err)
 		return 1
 	}
 
-	printer.Say("deleted flag %s/%s/%s",
-		cc.Config.Namespace, scope, name)
+	printer.Say("cancelled schedule for %s/%s/%s", cc.Config.Namespace, scope, name)
 
-	ft := &models.Feature{
-		Key:       name,
-		Scope:     scope,
-		UpdatedBy: cc.Config.Username,
+	return 0
+}
+
+// ParseScheduleContext builds the steps of a schedule from `-steps` or
+// a linear ramp from `-from` to `-to` starting at `-start` or `now`.
+// Step values are parsed as the feature type `ft`.
+func ParseScheduleContext(ctx climax.Context, ft models.FeatureType, now time.Time) ([]models.ScheduleStep, error) {
+	steps, _ := ctx.Get("steps")
+	from, _ := ctx.Get("from")
+	to, _ := ctx.Get("to")
+
+	if steps != "" {
+		return ParseSteps(steps, ft)
 	}
 
-	return cc.CommitFeatures(ft, true)
+	if from == "" || to == "" {
+		return nil, errScheduleRequired
+	}
+
+	start, _ := ctx.Get("start")
+	over, _ := ctx.Get("over")
+	every, _ := ctx.Get("every")
+
+	f, ferr := strconv.ParseFloat(from, 64)
+	t, terr := strconv.ParseFloat(to, 64)
+	o, oerr := ParseDuration(over)
+
+	if ferr != nil || terr != nil || oerr != nil {
+		return nil, errInvalidRamp
+	}
+
+	e := defaultRampInterval
+
+	if every != "" {
+		d, err := ParseDuration(every)
+
+		if err != nil {
+			return nil, errInvalidRamp
+		}
+
+		e = d
+	}
+
+	if start != "" {
+		st, err := models.ParseExpiry(start)
+
+		if err != nil {
+			return nil, errInvalidStart
+		}
+
+		now = st
+	}
+
+	rs, err := models.Ramp(f, t, now, o, e)
+
+	if err != nil {
+		return nil, errInvalidRamp
+	}
+
+	return rs, nil
 }
 
-func (cc *Controller) CommitFeatures(ft *models.Feature, deleted bool) int {
-	if cc.Config.GitEnabled() {
-		printer.Say("committing changes")
-		err := cc.Client.Commit(ft, deleted)
+// ParseSteps parses semicolon delimited `<time>=<value>` steps with
+// values of the feature type `ft`.
+//
+// 2026-03-01=0.1;2026-03-02T09:00:00Z=0.5
+func ParseSteps(s string, ft models.FeatureType) ([]models.ScheduleStep, error) {
+	var steps []models.ScheduleStep
+
+	for _, part := range strings.Split(s, ";") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+
+		kv := strings.SplitN(part, "=", 2)
+
+		if len(kv) != 2 {
+			return nil, errInvalidSteps
+		}
+
+		at, err := models.ParseExpiry(strings.TrimSpace(kv[0]))
 
 		if err != nil {
-			printer.SayErr("%v", err)
-			return 1
+			return nil, errInvalidSteps
 		}
 
-		sha, err := cc.Client.UpdateCurrentSHA()
-		printer.Say("set info/current_sha: %s", sha)
+		v, typ := models.ParseValueForFeatureType(strings.TrimSpace(kv[1]), ft)
+
+		if typ == models.Invalid {
+			return nil, errInvalidTypedValue
+		}
+
+		steps = append(steps, models.ScheduleStep{At: at, Value: v})
+	}
+
+	if len(steps) == 0 {
+		return nil, errInvalidSteps
+	}
+
+	return steps, nil
+}
+
+// ParseDuration parses a Go duration or a whole number of days such as `7d`.
+func ParseDuration(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
 
 		if err != nil {
-			printer.SayErr("%v", err)
+			return 0, err
+		}
+
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+
+	return time.ParseDuration(s)
+}
+
+// Scheduler applies due schedule steps every `--interval` until
+// interrupted. Replicas share a lock in the store so only one applies
+// steps at a time.
+func (cc *Controller) Scheduler(ctx climax.Context) int {
+	ivl, _ := ctx.Get("interval")
+	interval := defaultSchedulerInterval
+
+	if ivl != "" {
+		d, err := time.ParseDuration(ivl)
+
+		if err != nil || d <= 0 {
+			printer.SayErr("%v", errInvalidInterval)
 			return 1
 		}
 
-		if cc.Config.PushEnabled() {
-			printer.Say("pushing commit to origin")
-			err = cc.Client.Push()
+		interval = d
+	}
+
+	host, _ := os.Hostname()
+	owner := fmt.Sprintf("%s:%d", host, os.Getpid())
 
-			if err != nil {
-				printer.SayErr("%v", err)
+	printer.Logf("scheduling namespace %s every %s as %s", cc.Config.Namespace, interval, owner)
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(stop)
+
+	return cc.SchedulerLoop(owner, interval, stop)
+}
+
+// SchedulerLoop calls `RunScheduler` every `interval` until `stop`
+// receives a signal, then releases the scheduler lock so that another
+// replica can take over without waiting for it to expire.
+func (cc *Controller) SchedulerLoop(owner string, interval time.Duration, stop <-chan os.Signal) int {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		cc.RunScheduler(owner, interval*schedulerLockIntervals, time.Now().UTC())
+
+		select {
+		case sig := <-stop:
+			printer.Logf("received %s. releasing scheduler lock", sig)
+
+			if err := cc.Client.ReleaseLock(api.SchedulerLock, owner); err != nil {
+				printer.LogErrf("could not release scheduler lock: %v", err)
 				return 1
 			}
+
+			return 0
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunScheduler applies the steps due at `now` and commits each change
+// if `owner` holds or can take the scheduler lock for `ttl`. The lock is
+// renewed before each feature is set so a replica that loses it stops.
+func (cc *Controller) RunScheduler(owner string, ttl time.Duration, now time.Time) int {
+	ok, err := cc.Client.AcquireLock(api.SchedulerLock, owner, ttl, now)
+
+	if err != nil {
+		printer.LogErrf("could not acquire scheduler lock: %v", err)
+		return 1
+	}
+
+	if !ok {
+		return 0
+	}
+
+	changes, err := cc.Client.RunSchedules(owner, ttl, now)
+
+	for i := range changes {
+		ft := &changes[i].Feature
+		printer.Logf("set flag '%s' to %v", ft.ScopedKey(), ft.Value)
+
+		if _, cerr := api.CommitFeatures(cc.Client, cc.Config, ft, false); cerr != nil {
+			printer.LogErrf("could not commit %s: %v", ft.ScopedKey(), cerr)
 		}
+	}
 
+	if err != nil {
+		printer.LogErrf("%v", err)
+		return 1
 	}
 
 	return 0
 }
 
+func formatForFile(fp string) string {
+	switch strings.ToLower(path.Ext(fp)) {
+	case ".yaml", ".yml":
+		return string(api.YAMLFormat)
+	case ".env":
+		return string(api.DotenvFormat)
+	default:
+		return string(api.JSONFormat)
+	}
+}
+
 func (cc *Controller) Init(ctx climax.Context) int {
 	if _, err := os.Stat(config.Path()); os.IsNotExist(err) {
 		err = os.MkdirAll(path.Dir(config.Path()), filePerms)
@@ -200,41 +1189,133 @@ func (cc *Controller) Init(ctx climax.Context) int {
 	return 0
 }
 
-func (cc *Controller) Import(ctx climax.Context) int {
-	bts, err := ioutil.ReadAll(os.Stdin)
+// Export writes the namespace or a single scope to STDOUT in `--format`.
+func (cc *Controller) Export(ctx climax.Context) int {
+	scope, _ := ctx.Get("scope")
+	f, _ := ctx.Get("format")
+
+	if f == "" {
+		f = string(api.JSONFormat)
+	}
+
+	format, err := api.ParseFormat(f)
+
+	if err != nil {
+		printer.SayErr("%v", err)
+		return 1
+	}
+
+	fts, err := cc.Client.List("", scope)
 
 	if err != nil {
 		printer.SayErr("%v", err)
 		return 1
 	}
 
-	var kvs map[string]interface{}
-	err = json.Unmarshal(bts, &kvs)
+	if scope != "" {
+		var scoped models.Features
+
+		for _, ft := range fts {
+			if ft.GetScope() == scope {
+				scoped = append(scoped, ft)
+			}
+		}
+
+		fts = scoped
+	}
+
+	bts, err := api.EncodeFeatures(fts, format)
 
 	if err != nil {
 		printer.SayErr("%v", err)
 		return 1
 	}
 
+	os.Stdout.Write(bts)
+
+	return 0
+}
+
+// Import reads features from STDIN in `--format` and sets them. Keys
+// without a scope are set into `--scope` or the default scope.
+func (cc *Controller) Import(ctx climax.Context) int {
 	scope, _ := ctx.Get("scope")
+	f, _ := ctx.Get("format")
 
 	if scope == "" {
 		scope = models.DefaultScope
 	}
 
-	for k, v := range kvs {
-		f := models.NewFeature(k, v, "", "", scope, cc.Config.Namespace)
-		err = cc.Client.Set(f)
+	if f == "" {
+		f = string(api.FlatJSONFormat)
+	}
+
+	format, err := api.ParseFormat(f)
+
+	if err != nil {
+		printer.SayErr("%v", err)
+		return 1
+	}
+
+	bts, err := ioutil.ReadAll(os.Stdin)
+
+	if err != nil {
+		printer.SayErr("%v", err)
+		return 1
+	}
+
+	return cc.ImportFeatures(bts, format, scope)
+}
+
+// ImportFeatures sets the features decoded from `bts` and commits them.
+func (cc *Controller) ImportFeatures(bts []byte, format api.Format, scope string) int {
+	fts, err := api.DecodeFeatures(bts, format, scope)
+
+	if err != nil {
+		printer.SayErr("%v", err)
+		return 1
+	}
+
+	for i := range fts {
+		ft := &fts[i]
+		ft.Namespace = cc.Config.Namespace
+		ft.UpdatedBy = cc.Config.Username
+
+		err = cc.Client.Set(ft)
 
 		if err != nil {
-			printer.SayErr("%v", err)
+			printer.SayErr("%s: %v", ft.ScopedKey(), err)
 			return 1
 		}
 
-		printer.Say("set %s to %+v", k, v)
+		printer.Say("set %s to %+v", ft.ScopedKey(), ft.Value)
+	}
+
+	if len(fts) == 0 {
+		return 0
+	}
+
+	return cc.CommitChanges(fmt.Sprintf("%s imported %d features", cc.Config.Username, len(fts)))
+}
+
+// Migrate moves features written by earlier versions of the configured
+// store into its current layout.
+func (cc *Controller) Migrate(ctx climax.Context) int {
+	n, err := cc.Client.Migrate()
+
+	if err != nil {
+		printer.SayErr("%v", err)
+		return 1
 	}
 
-	return 1
+	if n == 0 {
+		printer.Say("nothing to migrate in namespace: %s", cc.Client.Namespace())
+		return 0
+	}
+
+	printer.Say("migrated %d keys in namespace: %s", n, cc.Client.Namespace())
+
+	return 0
 }
 
 func (cc *Controller) Info(ctx climax.Context) int {
@@ -253,6 +1334,10 @@ func (cc *Controller) Serve(ctx climax.Context) int {
 
 	s := server.New(cc.Config, c)
 
+	if cc.Config.Server.WriteAPI {
+		s.API = cc.Client
+	}
+
 	printer.Logf("pid: %d serving %s on %s", os.Getpid(),
 		cc.Config.Server.Endpoint, cc.Config.Server.Host)
 
@@ -279,6 +1364,11 @@ func (cc *Controller) ParseContext(ctx climax.Context) (*models.Feature, error)
 	val, _ := ctx.Get("value")
 	cmt, _ := ctx.Get("comment")
 	scp, _ := ctx.Get("scope")
+	typ, _ := ctx.Get("type")
+	rls, _ := ctx.Get("rules")
+	owner, _ := ctx.Get("owner")
+	tags, hasTags := ctx.Get("tags")
+	exp, _ := ctx.Get("expires")
 
 	if name == "" {
 		return nil, errNameRequired
@@ -287,11 +1377,43 @@ func (cc *Controller) ParseContext(ctx climax.Context) (*models.Feature, error)
 	var v interface{}
 	var ft models.FeatureType
 
-	if val != "" {
-		v, ft = models.ParseValueAndFeatureType(val)
+	if typ != "" {
+		ft = models.ParseFeatureType(typ)
 
 		if ft == models.Invalid {
-			return nil, errInvalidFeatureType
+			return nil, errInvalidType
+		}
+	}
+
+	if val != "" {
+		if ft != "" {
+			v, ft = models.ParseValueForFeatureType(val, ft)
+
+			if ft == models.Invalid {
+				return nil, errInvalidTypedValue
+			}
+		} else {
+			// values for existing features are parsed as their stored
+			// type rather than inferred so `-value 1` stays an integer
+			existing, err := api.ExistingFeatureType(cc.Client, name, scp)
+
+			if err != nil {
+				return nil, err
+			}
+
+			if existing != "" {
+				v, ft = models.ParseValueForFeatureType(val, existing)
+
+				if ft == models.Invalid {
+					return nil, errInvalidStoredValue
+				}
+			} else {
+				v, ft = models.ParseValueAndFeatureType(val)
+
+				if ft == models.Invalid {
+					return nil, errInvalidFeatureType
+				}
+			}
 		}
 
 		if ft == models.Percentile {
@@ -304,5 +1426,49 @@ func (cc *Controller) ParseContext(ctx climax.Context) (*models.Feature, error)
 	f := models.NewFeature(name, v, cmt, cc.Config.Username, scp, cc.Config.Namespace)
 	f.FeatureType = ft
 
+	if rls != "" {
+		rules, err := models.ParseRules(rls)
+
+		if err != nil {
+			return nil, err
+		}
+
+		f.Rules = rules
+	}
+
+	if ctx.Is("clear-rules") {
+		f.Rules = models.Rules{}
+	}
+
+	f.Owner = owner
+
+	if ctx.Is("clear-owner") {
+		f.Owner = ""
+		f.ClearOwner = true
+	}
+
+	if hasTags {
+		f.Tags = models.ParseTags(tags)
+	}
+
+	if ctx.Is("clear-tags") {
+		f.Tags = []string{}
+	}
+
+	if exp != "" {
+		t, err := models.ParseExpiry(exp)
+
+		if err != nil {
+			return nil, errInvalidExpiry
+		}
+
+		f.ExpiresAt = &t
+	}
+
+	if ctx.Is("clear-expires") {
+		f.ExpiresAt = nil
+		f.ClearExpiry = true
+	}
+
 	return f, nil
 }
@@ -1,10 +1,22 @@
 package controller
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
 	"testing"
+	"time"
 
+	"github.com/DataDog/datadog-go/v5/statsd"
 	"github.com/stretchr/testify/assert"
 	"github.com/tucnak/climax"
+	"github.com/vsco/dcdr/cli/api"
+	"github.com/vsco/dcdr/cli/repo"
+	"github.com/vsco/dcdr/client"
 	"github.com/vsco/dcdr/config"
 	"github.com/vsco/dcdr/models"
 )
@@ -15,9 +27,22 @@ const (
 )
 
 type MockClient struct {
-	Features models.Features
-	Feature  *models.Feature
-	Error    error
+	Features  models.Features
+	Stales    []api.StaleFeature
+	Deleted   []string
+	Schedule  *models.Schedule
+	Scheduled []models.Schedule
+	LockHeld  bool
+	Released  []string
+	Committed []string
+	Saved     []models.Feature
+	Migrated  int
+	Diffs     *api.DiffResult
+	Feature   *models.Feature
+	Revisions []api.FeatureRevision
+	Changes   []api.Change
+	Message   string
+	Error     error
 }
 
 func NewMockClient(f *models.Feature, fts models.Features, err error) (m *MockClient) {
@@ -31,14 +56,34 @@ func NewMockClient(f *models.Feature, fts models.Features, err error) (m *MockCl
 }
 
 func (m *MockClient) Get(key string, v interface{}) error {
-	return m.Error
+	if m.Error != nil {
+		return m.Error
+	}
+
+	for _, ft := range m.Features {
+		if fmt.Sprintf("%s/%s/%s", models.FeatureScope, ft.GetScope(), ft.Key) == key {
+			bts, _ := json.Marshal(ft)
+
+			return json.Unmarshal(bts, v)
+		}
+	}
+
+	return api.KeyNotFoundError(key)
 }
 
 func (m *MockClient) Set(ft *models.Feature) error {
+	if m.Error == nil {
+		m.Saved = append(m.Saved, *ft)
+	}
+
 	return m.Error
 }
 
 func (m *MockClient) Delete(key string, scope string) error {
+	if m.Error == nil {
+		m.Deleted = append(m.Deleted, fmt.Sprintf("%s/%s", scope, key))
+	}
+
 	return m.Error
 }
 
@@ -55,9 +100,90 @@ func (m *MockClient) GetInfo() (*models.Info, error) {
 }
 
 func (m *MockClient) Commit(ft *models.Feature, deleted bool) error {
+	if m.Error == nil {
+		m.Committed = append(m.Committed, ft.ScopedKey())
+	}
+
+	return m.Error
+}
+
+func (m *MockClient) CommitMessage(msg string) error {
+	m.Message = msg
+
+	return m.Error
+}
+
+func (m *MockClient) History(key string, scope string) ([]api.FeatureRevision, error) {
+	return m.Revisions, m.Error
+}
+
+func (m *MockClient) Rollback(sha string, key string, scope string) ([]api.Change, error) {
+	return m.Changes, m.Error
+}
+
+func (m *MockClient) Reconcile(dryRun bool) ([]api.Change, error) {
+	return m.Changes, m.Error
+}
+
+func (m *MockClient) Diff(from api.Source, to api.Source) (*api.DiffResult, error) {
+	return m.Diffs, m.Error
+}
+
+func (m *MockClient) PlanApply(fts models.Features) ([]api.Change, error) {
+	if m.Error != nil {
+		return nil, m.Error
+	}
+
+	var changes []api.Change
+
+	for _, ft := range fts {
+		ft.Namespace = m.Namespace()
+		changes = append(changes, api.Change{Feature: ft})
+	}
+
+	return changes, nil
+}
+
+func (m *MockClient) ApplyAll(changes []api.Change) error {
+	return m.Error
+}
+
+func (m *MockClient) Stale(opts api.StaleOptions) ([]api.StaleFeature, error) {
+	return m.Stales, m.Error
+}
+
+func (m *MockClient) SetSchedule(s *models.Schedule) error {
+	m.Schedule = s
+
 	return m.Error
 }
 
+func (m *MockClient) Schedules() ([]models.Schedule, error) {
+	return m.Scheduled, m.Error
+}
+
+func (m *MockClient) DeleteSchedule(key string, scope string) error {
+	return m.Error
+}
+
+func (m *MockClient) RunSchedules(owner string, ttl time.Duration, now time.Time) ([]api.Change, error) {
+	return m.Changes, m.Error
+}
+
+func (m *MockClient) AcquireLock(name string, owner string, ttl time.Duration, now time.Time) (bool, error) {
+	return !m.LockHeld, m.Error
+}
+
+func (m *MockClient) ReleaseLock(name string, owner string) error {
+	m.Released = append(m.Released, owner)
+
+	return m.Error
+}
+
+func (m *MockClient) Migrate() (int, error) {
+	return m.Migrated, m.Error
+}
+
 func (m *MockClient) Push() error {
 	return m.Error
 }
@@ -106,6 +232,25 @@ func TestListFeatures(t *testing.T) {
 	assert.Equal(t, Success, code)
 }
 
+func TestFilterFeatures(t *testing.T) {
+	a := models.Feature{Key: "a", Metadata: models.Metadata{Owner: "growth", Tags: []string{"checkout"}}}
+	b := models.Feature{Key: "b", Metadata: models.Metadata{Owner: "search", Tags: []string{"checkout", "ranking"}}}
+	c := models.Feature{Key: "c"}
+	fts := models.Features{a, b, c}
+
+	assert.Equal(t, fts, FilterFeatures(fts, "", ""))
+	assert.Equal(t, models.Features{a, b}, FilterFeatures(fts, "checkout", ""))
+	assert.Equal(t, models.Features{b}, FilterFeatures(fts, "checkout", "search"))
+	assert.Empty(t, FilterFeatures(fts, "missing", ""))
+
+	ctl := New(config.DefaultConfig(), NewMockClient(nil, fts, nil))
+	ctx := climax.Context{
+		Variable: map[string]string{"owner": "nobody"},
+	}
+
+	assert.Equal(t, Error, ctl.List(ctx))
+}
+
 func TestSet(t *testing.T) {
 	cfg := config.DefaultConfig()
 	fts := models.Features{
@@ -125,3 +270,641 @@ func TestSet(t *testing.T) {
 
 	assert.Equal(t, Success, code)
 }
+
+func TestParseContextWithType(t *testing.T) {
+	cfg := config.DefaultConfig()
+	ctl := New(cfg, NewMockClient(nil, nil, nil))
+
+	ctx := climax.Context{
+		Variable: map[string]string{"name": "test", "value": "variant-a", "type": "string"},
+	}
+
+	ft, err := ctl.ParseContext(ctx)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.String, ft.FeatureType)
+	assert.Equal(t, "variant-a", ft.Value)
+}
+
+func TestParseContextVariant(t *testing.T) {
+	cfg := config.DefaultConfig()
+	ctl := New(cfg, NewMockClient(nil, nil, nil))
+
+	ctx := climax.Context{
+		Variable: map[string]string{"name": "test", "value": "control:90,treatment:10", "type": "variant"},
+	}
+
+	ft, err := ctl.ParseContext(ctx)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.Variant, ft.FeatureType)
+	assert.Equal(t, models.WeightedVariants{{Name: "control", Weight: 90}, {Name: "treatment", Weight: 10}}, ft.Value)
+}
+
+func TestParseContextRules(t *testing.T) {
+	cfg := config.DefaultConfig()
+	ctl := New(cfg, NewMockClient(nil, nil, nil))
+
+	ctx := climax.Context{
+		Variable: map[string]string{"name": "test", "value": "true", "rules": "country in us,ca"},
+	}
+
+	ft, err := ctl.ParseContext(ctx)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.Rules{{Attribute: "country", Operator: models.In, Values: []string{"us", "ca"}}}, ft.Rules)
+
+	ctx.Variable["rules"] = "country"
+	_, err = ctl.ParseContext(ctx)
+	assert.Equal(t, models.ErrInvalidRule, err)
+
+	ctx = climax.Context{
+		Variable:    map[string]string{"name": "test"},
+		NonVariable: map[string]bool{"clear-rules": true},
+	}
+
+	ft, err = ctl.ParseContext(ctx)
+	assert.NoError(t, err)
+	assert.NotNil(t, ft.Rules)
+	assert.False(t, ft.Targeted())
+}
+
+func TestParseContextMetadata(t *testing.T) {
+	cfg := config.DefaultConfig()
+	ctl := New(cfg, NewMockClient(nil, nil, nil))
+
+	ctx := climax.Context{
+		Variable: map[string]string{"name": "test", "owner": "growth", "tags": "b,a", "expires": "2026-03-01"},
+	}
+
+	ft, err := ctl.ParseContext(ctx)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "growth", ft.Owner)
+	assert.Equal(t, []string{"a", "b"}, ft.Tags)
+	assert.Equal(t, "2026-03-01", ft.ExpiresAt.Format(models.ExpiryDateFormat))
+
+	ctx = climax.Context{
+		Variable: map[string]string{"name": "test"},
+	}
+
+	ft, err = ctl.ParseContext(ctx)
+	assert.NoError(t, err)
+	assert.Nil(t, ft.Tags)
+	assert.Nil(t, ft.ExpiresAt)
+
+	ctx.Variable["tags"] = ""
+	ft, err = ctl.ParseContext(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{}, ft.Tags)
+
+	ctx = climax.Context{
+		Variable:    map[string]string{"name": "test", "owner": "growth", "tags": "a", "expires": "2026-03-01"},
+		NonVariable: map[string]bool{"clear-owner": true, "clear-tags": true, "clear-expires": true},
+	}
+
+	ft, err = ctl.ParseContext(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, ft.Owner)
+	assert.True(t, ft.ClearOwner)
+	assert.Equal(t, []string{}, ft.Tags)
+	assert.Nil(t, ft.ExpiresAt)
+	assert.True(t, ft.ClearExpiry)
+
+	ctx.Variable["expires"] = "soon"
+	_, err = ctl.ParseContext(ctx)
+	assert.Equal(t, errInvalidExpiry, err)
+}
+
+func TestParseContextExistingType(t *testing.T) {
+	cfg := config.DefaultConfig()
+	existing := models.NewFeature("count", int64(10), "", "", "", "dcdr")
+	existing.FeatureType = models.Integer
+	ctl := New(cfg, NewMockClient(nil, models.Features{*existing}, nil))
+
+	for v, expected := range map[string]int64{"5": 5, "1": 1} {
+		ctx := climax.Context{
+			Variable: map[string]string{"name": "count", "value": v},
+		}
+
+		ft, err := ctl.ParseContext(ctx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.Integer, ft.FeatureType)
+		assert.Equal(t, expected, ft.Value)
+	}
+
+	ctx := climax.Context{
+		Variable: map[string]string{"name": "count", "value": "true"},
+	}
+
+	_, err := ctl.ParseContext(ctx)
+	assert.Equal(t, errInvalidStoredValue, err)
+
+	// features in other scopes are still inferred
+	ctx.Variable["scope"] = "user-groups/beta"
+	ft, err := ctl.ParseContext(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, models.Boolean, ft.FeatureType)
+}
+
+func TestParseContextInvalidType(t *testing.T) {
+	cfg := config.DefaultConfig()
+	ctl := New(cfg, NewMockClient(nil, nil, nil))
+
+	ctx := climax.Context{
+		Variable: map[string]string{"name": "test", "value": "1.5", "type": "integer"},
+	}
+
+	_, err := ctl.ParseContext(ctx)
+	assert.Equal(t, errInvalidTypedValue, err)
+
+	ctx.Variable["type"] = "float"
+	_, err = ctl.ParseContext(ctx)
+	assert.Equal(t, errInvalidType, err)
+
+	ctx.Variable = map[string]string{"name": "test", "value": "variant-a"}
+	_, err = ctl.ParseContext(ctx)
+	assert.Equal(t, errInvalidFeatureType, err)
+}
+
+func TestGet(t *testing.T) {
+	cfg := config.DefaultConfig()
+	fts := models.Features{
+		*models.NewFeature("test", 0.5, "", "", models.DefaultScope, "dcdr"),
+		*models.NewFeature("test", 1.0, "", "", "user-groups/beta", "dcdr"),
+	}
+	ctl := New(cfg, NewMockClient(nil, fts, nil))
+
+	ctx := climax.Context{
+		Variable: map[string]string{"name": "test", "scope": "user-groups/beta, country-codes/us", "id": "123"},
+	}
+
+	assert.Equal(t, Success, ctl.Get(ctx))
+
+	ctx.Variable["id"] = "nope"
+	assert.Equal(t, Error, ctl.Get(ctx))
+
+	ctx.Variable = map[string]string{"name": "missing"}
+	assert.Equal(t, Error, ctl.Get(ctx))
+
+	ctx.Variable = map[string]string{}
+	assert.Equal(t, Error, ctl.Get(ctx))
+}
+
+func TestResolve(t *testing.T) {
+	cfg := config.DefaultConfig()
+	fts := models.Features{
+		*models.NewFeature("test", 0.5, "", "", models.DefaultScope, "dcdr"),
+		*models.NewFeature("test", 1.0, "", "", "user-groups/beta", "dcdr"),
+	}
+	ctl := New(cfg, NewMockClient(nil, fts, nil))
+
+	scopes := ParseScopes("country-codes/us, user-groups/beta,default")
+	assert.Equal(t, []string{"country-codes/us", "user-groups/beta"}, scopes)
+
+	stored, err := ctl.Resolve("test", scopes)
+	assert.NoError(t, err)
+	assert.Len(t, stored, 2)
+	assert.Equal(t, "user-groups/beta", stored[0].Scope)
+	assert.Equal(t, models.DefaultScope, stored[1].Scope)
+
+	ctl = New(cfg, NewMockClient(nil, nil, errors.New("boom")))
+	_, err = ctl.Resolve("test", scopes)
+	assert.Error(t, err)
+}
+
+func TestHistory(t *testing.T) {
+	cfg := config.DefaultConfig()
+	revs := []api.FeatureRevision{
+		{Revision: repo.Revision{SHA: "3a1f9c2e", Author: "dcdr"}, Value: 0.5},
+		{Revision: repo.Revision{SHA: "9b2c1d4f", Author: "dcdr"}, Deleted: true},
+	}
+	c := NewMockClient(nil, nil, nil)
+	c.Revisions = revs
+	ctl := New(cfg, c)
+
+	ctx := climax.Context{
+		Variable: map[string]string{"name": "test"},
+	}
+
+	assert.Equal(t, Success, ctl.History(ctx))
+
+	ctx.Variable = map[string]string{}
+	assert.Equal(t, Error, ctl.History(ctx))
+}
+
+func TestRollback(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Git.RepoPath = "/tmp/dcdr-audit"
+	cfg.Username = "rollbacker"
+
+	c := NewMockClient(nil, nil, nil)
+	c.Changes = []api.Change{
+		{Feature: *models.NewFeature("test", 0.5, "", "", "", "dcdr")},
+	}
+	ctl := New(cfg, c)
+
+	ctx := climax.Context{
+		Variable: map[string]string{"sha": "3a1f9c2", "name": "test"},
+	}
+
+	assert.Equal(t, Success, ctl.Rollback(ctx))
+	assert.Equal(t, "rollbacker rolled back default/test to 3a1f9c2", c.Message)
+
+	ctx.Variable = map[string]string{"sha": "3a1f9c2"}
+	assert.Equal(t, Success, ctl.Rollback(ctx))
+	assert.Equal(t, "rollbacker rolled back dcdr to 3a1f9c2", c.Message)
+
+	ctx.Variable = map[string]string{"name": "test"}
+	assert.Equal(t, Error, ctl.Rollback(ctx))
+}
+
+func TestReconcile(t *testing.T) {
+	cfg := config.DefaultConfig()
+	c := NewMockClient(nil, nil, nil)
+	c.Changes = []api.Change{
+		{Feature: *models.NewFeature("test", 0.5, "", "", "", "dcdr")},
+		{Feature: *models.NewFeature("old", true, "", "", "", "dcdr"), Deleted: true},
+	}
+	ctl := New(cfg, c)
+
+	ctx := climax.Context{
+		Variable:    map[string]string{},
+		NonVariable: map[string]bool{"dry-run": true},
+	}
+
+	assert.Equal(t, Success, ctl.Reconcile(ctx))
+
+	ctx.Variable["interval"] = "soon"
+	assert.Equal(t, Error, ctl.Reconcile(ctx))
+
+	c.Error = api.ErrRepoBehind
+	ctx.Variable = map[string]string{}
+	assert.Equal(t, Error, ctl.Reconcile(ctx))
+}
+
+func TestDiff(t *testing.T) {
+	cfg := config.DefaultConfig()
+	c := NewMockClient(nil, nil, nil)
+	c.Diffs = &api.DiffResult{
+		Added:   map[string]interface{}{"new": true},
+		Removed: map[string]interface{}{},
+		Changed: map[string]api.ValueChange{"flag": {From: 0.1, To: 0.5}},
+	}
+	ctl := New(cfg, c)
+
+	ctx := climax.Context{
+		Args:        []string{"default", "user-groups/beta"},
+		Variable:    map[string]string{},
+		NonVariable: map[string]bool{},
+	}
+
+	assert.Equal(t, Success, ctl.Diff(ctx))
+
+	ctx.NonVariable["json"] = true
+	assert.Equal(t, Success, ctl.Diff(ctx))
+
+	ctx.Args = []string{"default"}
+	assert.Equal(t, Error, ctl.Diff(ctx))
+
+	ctx.Args = []string{"default", "bogus:thing"}
+	assert.Equal(t, Error, ctl.Diff(ctx))
+}
+
+func TestImportFeatures(t *testing.T) {
+	cfg := config.DefaultConfig()
+	mc := NewMockClient(nil, nil, nil)
+	ctl := New(cfg, mc)
+	code := ctl.ImportFeatures([]byte(`{"default": {"a": {"feature_type": "boolean", "value": true, "updated_by": "someone"}}}`), api.JSONFormat, models.DefaultScope)
+	assert.Equal(t, Success, code)
+	assert.Equal(t, cfg.Username, mc.Saved[0].UpdatedBy)
+
+	code = ctl.ImportFeatures([]byte(`{"some-feature": true, "user-groups/beta/other": 0.5}`), api.FlatJSONFormat, models.DefaultScope)
+	assert.Equal(t, Success, code)
+
+	code = ctl.ImportFeatures([]byte(`{"x": 1.5}`), api.FlatJSONFormat, models.DefaultScope)
+	assert.Equal(t, Error, code)
+
+	code = ctl.ImportFeatures([]byte(`{"some-feature": true}`), api.YAMLFormat, models.DefaultScope)
+	assert.Equal(t, Error, code)
+
+	ctl = New(cfg, NewMockClient(nil, nil, api.ErrTypeChange))
+	code = ctl.ImportFeatures([]byte(`{"some-feature": true}`), api.FlatJSONFormat, models.DefaultScope)
+	assert.Equal(t, Error, code)
+}
+
+func TestMigrate(t *testing.T) {
+	cfg := config.DefaultConfig()
+	mc := NewMockClient(nil, nil, nil)
+	ctl := New(cfg, mc)
+
+	assert.Equal(t, Success, ctl.Migrate(climax.Context{}))
+
+	mc.Migrated = 2
+	assert.Equal(t, Success, ctl.Migrate(climax.Context{}))
+
+	ctl = New(cfg, NewMockClient(nil, nil, errors.New("down")))
+	assert.Equal(t, Error, ctl.Migrate(climax.Context{}))
+}
+
+func TestExport(t *testing.T) {
+	cfg := config.DefaultConfig()
+	fts := models.Features{
+		*models.NewFeature("test", 0.5, "", "", models.DefaultScope, "dcdr"),
+	}
+	ctl := New(cfg, NewMockClient(nil, fts, nil))
+
+	ctx := climax.Context{
+		Variable: map[string]string{"format": "dotenv"},
+	}
+
+	assert.Equal(t, Success, ctl.Export(ctx))
+
+	ctx.Variable["format"] = "xml"
+	assert.Equal(t, Error, ctl.Export(ctx))
+}
+
+func TestApplyManifest(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Git.RepoPath = "/tmp/dcdr-audit"
+	cfg.Username = "alice"
+
+	manifest := []byte(`
+default:
+  new-feature:
+    feature_type: boolean
+    value: true
+user-groups/beta:
+  rollout: 0.5
+`)
+
+	c := NewMockClient(nil, nil, nil)
+	ctl := New(cfg, c)
+
+	assert.Equal(t, Success, ctl.ApplyManifest(manifest, api.YAMLFormat, true))
+	assert.Equal(t, "", c.Message)
+
+	assert.Equal(t, Success, ctl.ApplyManifest(manifest, api.YAMLFormat, false))
+	assert.Equal(t, "alice applied 2 changes\n\nset dcdr/features/default/new-feature to true\nset dcdr/features/user-groups/beta/rollout to 0.5", c.Message)
+
+	c.Error = api.ManifestErrors{api.ErrInvalidRange}
+	assert.Equal(t, Error, ctl.ApplyManifest(manifest, api.YAMLFormat, false))
+
+	assert.Equal(t, "yaml", formatForFile("flags.yml"))
+	assert.Equal(t, "dotenv", formatForFile("flags.env"))
+	assert.Equal(t, "json", formatForFile("flags.json"))
+}
+
+func TestStale(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Git.RepoPath = "/tmp/dcdr-audit"
+	cfg.Username = "alice"
+
+	c := NewMockClient(nil, nil, nil)
+	c.Stales = []api.StaleFeature{
+		{Feature: *models.NewFeature("done", true, "", "", "", "dcdr"), Reasons: []api.StaleReason{api.RolledOut}},
+		{Feature: *models.NewFeature("old", false, "", "", "user-groups/beta", "dcdr"), Reasons: []api.StaleReason{api.Expired}},
+	}
+	ctl := New(cfg, c)
+
+	ctx := climax.Context{
+		Variable:    map[string]string{"days": "14"},
+		NonVariable: map[string]bool{},
+	}
+
+	assert.Equal(t, Success, ctl.Stale(ctx))
+	assert.Empty(t, c.Deleted)
+
+	ctx.NonVariable["delete"] = true
+	assert.Equal(t, Success, ctl.Stale(ctx))
+	assert.Equal(t, []string{"default/done", "user-groups/beta/old"}, c.Deleted)
+	assert.Equal(t, "alice deleted 2 stale features\n\ndeleted dcdr/features/default/done\ndeleted dcdr/features/user-groups/beta/old", c.Message)
+
+	ctx.Variable["days"] = "-1"
+	assert.Equal(t, Error, ctl.Stale(ctx))
+
+	ctx.Variable["days"] = "14"
+	ctx.Variable["evaluations"] = "/does/not/exist.json"
+	assert.Equal(t, Error, ctl.Stale(ctx))
+}
+
+func TestReadEvaluations(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "evaluations.json")
+	ioutil.WriteFile(fp, []byte(`{"done": 0, "used": 12}`), 0644)
+
+	evals, err := ReadEvaluations(fp)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]uint64{"done": 0, "used": 12}, evals)
+
+	ioutil.WriteFile(fp, []byte(`[]`), 0644)
+
+	_, err = ReadEvaluations(fp)
+	assert.Error(t, err)
+}
+
+func TestReadWrittenEvaluations(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "evaluations.json")
+
+	sc, err := client.NewStatsClient(config.TestConfig(), &statsd.NoOpClient{})
+	assert.NoError(t, err)
+
+	sc.IsAvailable("used")
+	sc.IsAvailable("used")
+	sc.Variant("tested", 1)
+	assert.NoError(t, sc.WriteEvaluations(fp))
+
+	evals, err := ReadEvaluations(fp)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]uint64{"used": 2, "tested": 1}, evals)
+}
+
+func TestRefs(t *testing.T) {
+	dir := t.TempDir()
+	fp := filepath.Join(dir, "app.go")
+	ioutil.WriteFile(fp, []byte("package app\n\nfunc f() { dcdr.IsAvailable(\"new-signup\") }\n"), 0644)
+
+	c := NewMockClient(nil, models.Features{
+		*models.NewFeature("new-signup", true, "", "", "", "dcdr"),
+		*models.NewFeature("old", true, "", "", "", "dcdr"),
+	}, nil)
+	ctl := New(config.DefaultConfig(), c)
+
+	ctx := climax.Context{
+		Args:     []string{dir},
+		Variable: map[string]string{},
+	}
+
+	assert.Equal(t, Success, ctl.Refs(ctx))
+
+	c.Features = models.Features{*models.NewFeature("old", true, "", "", "", "dcdr")}
+	assert.Equal(t, Error, ctl.Refs(ctx))
+
+	ctx.Args = []string{filepath.Join(dir, "missing")}
+	assert.Equal(t, Error, ctl.Refs(ctx))
+}
+
+func TestGenerate(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "flags_gen.go")
+
+	c := NewMockClient(nil, models.Features{
+		*models.NewFeature("new-signup", true, "", "", "", "dcdr"),
+	}, nil)
+	ctl := New(config.DefaultConfig(), c)
+
+	ctx := climax.Context{
+		Args:     []string{"go"},
+		Variable: map[string]string{"output": out, "package": "features"},
+	}
+
+	assert.Equal(t, Success, ctl.Generate(ctx))
+
+	bts, err := ioutil.ReadFile(out)
+	assert.NoError(t, err)
+	assert.Contains(t, string(bts), "package features")
+	assert.Contains(t, string(bts), "func NewSignup(c client.IFace) bool")
+
+	manifest := filepath.Join(dir, "flags.yaml")
+	ioutil.WriteFile(manifest, []byte("default:\n  rollout: 0.5\n"), 0644)
+	ctx.Variable["file"] = manifest
+
+	assert.Equal(t, Success, ctl.Generate(ctx))
+
+	bts, _ = ioutil.ReadFile(out)
+	assert.Contains(t, string(bts), "func Rollout(c client.IFace, id uint64) bool")
+	assert.NotContains(t, string(bts), "NewSignup")
+
+	ctx.Args = []string{"ruby"}
+	assert.Equal(t, Error, ctl.Generate(ctx))
+}
+
+func TestParseSteps(t *testing.T) {
+	steps, err := ParseSteps("2026-03-02=0.5; 2026-03-01T09:00:00Z=0.1", models.Percentile)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []models.ScheduleStep{
+		{At: time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC), Value: 0.5},
+		{At: time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC), Value: 0.1},
+	}, steps)
+
+	steps, err = ParseSteps("2026-03-01=10", models.Integer)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), steps[0].Value)
+
+	_, err = ParseSteps("2026-03-01", models.Percentile)
+	assert.Equal(t, errInvalidSteps, err)
+
+	_, err = ParseSteps("tomorrow=0.5", models.Percentile)
+	assert.Equal(t, errInvalidSteps, err)
+
+	_, err = ParseSteps("2026-03-01=maybe", models.Boolean)
+	assert.Equal(t, errInvalidTypedValue, err)
+}
+
+func TestParseScheduleContext(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	ctx := climax.Context{
+		Variable: map[string]string{"from": "0", "to": "1", "over": "7d", "every": "1d"},
+	}
+
+	steps, err := ParseScheduleContext(ctx, models.Percentile, now)
+	assert.NoError(t, err)
+	assert.Len(t, steps, 8)
+	assert.Equal(t, now, steps[0].At)
+	assert.Equal(t, now.Add(7*24*time.Hour), steps[7].At)
+
+	ctx.Variable["start"] = "2026-04-01"
+	steps, _ = ParseScheduleContext(ctx, models.Percentile, now)
+	assert.Equal(t, time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC), steps[0].At)
+
+	ctx.Variable["over"] = "a week"
+	_, err = ParseScheduleContext(ctx, models.Percentile, now)
+	assert.Equal(t, errInvalidRamp, err)
+
+	_, err = ParseScheduleContext(climax.Context{Variable: map[string]string{}}, models.Percentile, now)
+	assert.Equal(t, errScheduleRequired, err)
+}
+
+func TestSchedule(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Username = "alice"
+
+	c := NewMockClient(nil, models.Features{*models.NewFeature("rollout", 0.0, "", "", "", "dcdr")}, nil)
+	ctl := New(cfg, c)
+
+	ctx := climax.Context{
+		Args:     []string{"create"},
+		Variable: map[string]string{"name": "rollout", "steps": "2026-03-01=0.5"},
+	}
+
+	assert.Equal(t, Success, ctl.Schedule(ctx))
+	assert.Equal(t, "alice", c.Schedule.CreatedBy)
+	assert.Equal(t, "dcdr/schedules/default/rollout", c.Schedule.ScopedKey())
+
+	ctx.Variable["name"] = "missing"
+	assert.Equal(t, Error, ctl.Schedule(ctx))
+
+	ctx.Args = []string{"list"}
+	assert.Equal(t, Success, ctl.Schedule(ctx))
+
+	c.Scheduled = []models.Schedule{*c.Schedule}
+	assert.Equal(t, Success, ctl.Schedule(ctx))
+
+	ctx.Args = []string{"cancel"}
+	assert.Equal(t, Success, ctl.Schedule(ctx))
+
+	ctx.Args = []string{"pause"}
+	assert.Equal(t, Error, ctl.Schedule(ctx))
+}
+
+func TestRunScheduler(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Git.RepoPath = "/tmp/dcdr-audit"
+
+	ft := models.NewFeature("rollout", 0.5, "", "alice", "", "dcdr")
+	c := NewMockClient(nil, nil, nil)
+	c.Changes = []api.Change{{Feature: *ft}}
+	ctl := New(cfg, c)
+
+	c.LockHeld = true
+	assert.Equal(t, Success, ctl.RunScheduler("a", time.Minute, time.Now()))
+	assert.Empty(t, c.Committed)
+
+	c.LockHeld = false
+	assert.Equal(t, Success, ctl.RunScheduler("a", time.Minute, time.Now()))
+	assert.Equal(t, []string{"dcdr/features/default/rollout"}, c.Committed)
+
+	c.Error = errors.New("store down")
+	assert.Equal(t, Error, ctl.RunScheduler("a", time.Minute, time.Now()))
+}
+
+func TestSchedulerLoop(t *testing.T) {
+	cfg := config.DefaultConfig()
+	c := NewMockClient(nil, nil, nil)
+	ctl := New(cfg, c)
+
+	stop := make(chan os.Signal, 1)
+	stop <- syscall.SIGTERM
+
+	assert.Equal(t, Success, ctl.SchedulerLoop("a", time.Hour, stop))
+	assert.Equal(t, []string{"a"}, c.Released)
+
+	c.Error = errors.New("store down")
+	stop <- syscall.SIGINT
+
+	assert.Equal(t, Error, ctl.SchedulerLoop("a", time.Hour, stop))
+}
+
+func TestParseDuration(t *testing.T) {
+	d, err := ParseDuration("7d")
+	assert.NoError(t, err)
+	assert.Equal(t, 7*24*time.Hour, d)
+
+	d, err = ParseDuration("90m")
+	assert.NoError(t, err)
+	assert.Equal(t, 90*time.Minute, d)
+
+	_, err = ParseDuration("xd")
+	assert.Error(t, err)
+}
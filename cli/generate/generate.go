@@ -0,0 +1,247 @@
+package generate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/vsco/dcdr/models"
+)
+
+// DefaultPackage the package name used when none is provided.
+const DefaultPackage = "flags"
+
+// ErrNoFeatures returned when there are no features to generate.
+var ErrNoFeatures = errors.New("no features to generate")
+
+// Accessor a typed function wrapping a `client.IFace` method for a feature.
+type Accessor struct {
+	Name    string
+	Key     string
+	Type    models.FeatureType
+	Comment []string
+	Params  string
+	Returns string
+	Call    string
+}
+
+var goTemplate = template.Must(template.New("go").Parse(`// Code generated by dcdr generate go. DO NOT EDIT.
+
+package {{ .Package }}
+
+import "github.com/vsco/dcdr/client"
+
+// Feature flag keys.
+const (
+{{- range .Accessors }}
+	{{ .Name }}Key = {{ printf "%q" .Key }}
+{{- end }}
+)
+{{ range .Accessors }}
+// {{ .Name }} reads the {{ .Type }} flag {{ printf "%q" .Key }}.
+{{- if .Comment }}
+//
+{{- range .Comment }}
+{{ if . }}// {{ . }}{{ else }}//{{ end }}
+{{- end }}
+{{- end }}
+func {{ .Name }}({{ .Params }}) {{ .Returns }} {
+	return {{ .Call }}
+}
+{{ end }}`))
+
+// Go renders a Go source file in package `pkg` with an exported key
+// constant and accessor function for each feature. Features set in
+// several scopes share an accessor whose comment is taken from the
+// default scope where possible.
+func Go(fts models.Features, pkg string) ([]byte, error) {
+	accessors, err := Accessors(fts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if pkg == "" {
+		pkg = DefaultPackage
+	}
+
+	var buf bytes.Buffer
+
+	err = goTemplate.Execute(&buf, struct {
+		Package   string
+		Accessors []Accessor
+	}{pkg, accessors})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return format.Source(buf.Bytes())
+}
+
+// Accessors builds an `Accessor` for each feature key sorted by key.
+// An error is returned if a key is stored with incompatible types in
+// different scopes or if two keys map to the same Go identifier.
+func Accessors(fts models.Features) ([]Accessor, error) {
+	scopes := make(map[string]models.Features)
+
+	for _, ft := range fts {
+		scopes[ft.Key] = append(scopes[ft.Key], ft)
+	}
+
+	if len(scopes) == 0 {
+		return nil, ErrNoFeatures
+	}
+
+	keys := make([]string, 0, len(scopes))
+
+	for key := range scopes {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	var accessors []Accessor
+	idents := make(map[string]string)
+
+	for _, key := range keys {
+		a, err := accessor(key, scopes[key])
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ident := range []string{a.Name, a.Name + "Key"} {
+			if other, ok := idents[ident]; ok {
+				return nil, fmt.Errorf("%s and %s both generate %s", other, key, ident)
+			}
+
+			idents[ident] = key
+		}
+
+		accessors = append(accessors, a)
+	}
+
+	return accessors, nil
+}
+
+func accessor(key string, fts models.Features) (Accessor, error) {
+	a := Accessor{
+		Name: Identifier(key),
+		Key:  key,
+	}
+
+	var rules bool
+	types := make(map[models.FeatureType]bool)
+
+	for _, ft := range fts {
+		if ft.FeatureType == "" {
+			ft.FeatureType = models.TypeOfValue(ft.Value)
+		}
+
+		types[ft.FeatureType] = true
+		rules = rules || len(ft.Rules) > 0
+
+		if ft.Comment != "" && (a.Comment == nil || ft.GetScope() == models.DefaultScope) {
+			a.Comment = commentLines(ft.Comment)
+		}
+	}
+
+	q := fmt.Sprintf("%q", key)
+
+	switch {
+	case onlyTypes(types, models.Boolean, models.Percentile) && rules:
+		a.Type = models.Boolean
+		a.Params, a.Returns = "c client.IFace, ctx client.EvalContext", "bool"
+		a.Call = fmt.Sprintf("c.IsAvailableFor(%s, ctx)", q)
+	case onlyTypes(types, models.Boolean):
+		a.Type = models.Boolean
+		a.Params, a.Returns = "c client.IFace", "bool"
+		a.Call = fmt.Sprintf("c.IsAvailable(%s)", q)
+	case onlyTypes(types, models.Percentile):
+		a.Type = models.Percentile
+		a.Params, a.Returns = "c client.IFace, id uint64", "bool"
+		a.Call = fmt.Sprintf("c.IsAvailableForID(%s, id)", q)
+	case onlyTypes(types, models.Boolean, models.Percentile):
+		a.Type = models.Percentile
+		a.Params, a.Returns = "c client.IFace, id uint64", "bool"
+		a.Call = fmt.Sprintf("c.IsAvailableFor(%s, client.EvalContext{ID: id})", q)
+	case onlyTypes(types, models.String):
+		a.Type = models.String
+		a.Params, a.Returns = "c client.IFace, defaultValue string", "string"
+		a.Call = fmt.Sprintf("c.StringValue(%s, defaultValue)", q)
+	case onlyTypes(types, models.Integer):
+		a.Type = models.Integer
+		a.Params, a.Returns = "c client.IFace, defaultValue int64", "int64"
+		a.Call = fmt.Sprintf("c.IntValue(%s, defaultValue)", q)
+	case onlyTypes(types, models.JSON):
+		a.Type = models.JSON
+		a.Params, a.Returns = "c client.IFace, v interface{}", "error"
+		a.Call = fmt.Sprintf("c.JSONValue(%s, v)", q)
+	case onlyTypes(types, models.Variant):
+		a.Type = models.Variant
+		a.Params, a.Returns = "c client.IFace, id uint64", "string"
+		a.Call = fmt.Sprintf("c.Variant(%s, id)", q)
+	default:
+		return a, fmt.Errorf("%s has incompatible types in different scopes", key)
+	}
+
+	return a, nil
+}
+
+func commentLines(cmt string) []string {
+	lines := strings.Split(strings.TrimSpace(cmt), "\n")
+
+	for i := range lines {
+		lines[i] = strings.TrimSpace(lines[i])
+	}
+
+	return lines
+}
+
+// onlyTypes checks if every type in `types` is one of `allowed`.
+func onlyTypes(types map[models.FeatureType]bool, allowed ...models.FeatureType) bool {
+	for t := range types {
+		found := false
+
+		for _, a := range allowed {
+			found = found || t == a
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Identifier converts a feature key such as `new-signup_flow` into an
+// exported Go identifier such as `NewSignupFlow`. Keys beginning with a
+// digit are prefixed with `Flag`.
+func Identifier(key string) string {
+	parts := strings.FieldsFunc(key, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var b strings.Builder
+
+	for _, p := range parts {
+		rs := []rune(p)
+		rs[0] = unicode.ToUpper(rs[0])
+		b.WriteString(string(rs))
+	}
+
+	name := b.String()
+
+	if name == "" || unicode.IsDigit([]rune(name)[0]) {
+		name = "Flag" + name
+	}
+
+	return name
+}
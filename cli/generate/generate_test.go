@@ -0,0 +1,71 @@
+package generate
+
+import (
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vsco/dcdr/models"
+)
+
+func TestIdentifier(t *testing.T) {
+	assert.Equal(t, "NewSignupFlow", Identifier("new-signup-flow"))
+	assert.Equal(t, "CheckoutRollout", Identifier("checkout_rollout"))
+	assert.Equal(t, "Flag2faEnabled", Identifier("2fa.enabled"))
+	assert.Equal(t, "Flag", Identifier("--"))
+}
+
+func TestGo(t *testing.T) {
+	targeted := models.NewFeature("targeted", true, "", "", "", "dcdr")
+	targeted.Rules = models.Rules{{Attribute: "country", Operator: models.In, Values: []string{"us"}}}
+
+	fts := models.Features{
+		*models.NewFeature("new-signup-flow", true, "new signup flow\n\nweb only", "", "", "dcdr"),
+		*models.NewFeature("checkout-rollout", 0.5, "", "", "", "dcdr"),
+		*models.NewFeature("mixed", true, "", "", "", "dcdr"),
+		*models.NewFeature("mixed", 0.5, "", "", "user-groups/beta", "dcdr"),
+		*targeted,
+	}
+
+	str := models.NewFeature("greeting", "hello", "beta greeting", "", "user-groups/beta", "dcdr")
+	str.FeatureType = models.String
+	fts = append(fts, *str)
+
+	bts, err := Go(fts, "")
+	assert.NoError(t, err)
+
+	src := string(bts)
+
+	_, err = parser.ParseFile(token.NewFileSet(), "flags_gen.go", bts, parser.ParseComments)
+	assert.NoError(t, err)
+
+	assert.Contains(t, src, "// Code generated by dcdr generate go. DO NOT EDIT.\n\npackage flags\n")
+	assert.Contains(t, src, `NewSignupFlowKey   = "new-signup-flow"`)
+	assert.Contains(t, src, "// NewSignupFlow reads the boolean flag \"new-signup-flow\".\n//\n// new signup flow\n//\n// web only\nfunc NewSignupFlow(c client.IFace) bool {\n\treturn c.IsAvailable(\"new-signup-flow\")\n}")
+	assert.Contains(t, src, "func CheckoutRollout(c client.IFace, id uint64) bool {\n\treturn c.IsAvailableForID(\"checkout-rollout\", id)\n}")
+	assert.Contains(t, src, "func Mixed(c client.IFace, id uint64) bool {\n\treturn c.IsAvailableFor(\"mixed\", client.EvalContext{ID: id})\n}")
+	assert.Contains(t, src, "func Targeted(c client.IFace, ctx client.EvalContext) bool {\n\treturn c.IsAvailableFor(\"targeted\", ctx)\n}")
+	assert.Contains(t, src, "// beta greeting\nfunc Greeting(c client.IFace, defaultValue string) string {\n\treturn c.StringValue(\"greeting\", defaultValue)\n}")
+
+	bts, err = Go(fts, "features")
+	assert.NoError(t, err)
+	assert.Contains(t, string(bts), "package features\n")
+}
+
+func TestGoErrors(t *testing.T) {
+	_, err := Go(models.Features{}, "")
+	assert.Equal(t, ErrNoFeatures, err)
+
+	_, err = Go(models.Features{
+		*models.NewFeature("new-signup", true, "", "", "", "dcdr"),
+		*models.NewFeature("new_signup", true, "", "", "", "dcdr"),
+	}, "")
+	assert.EqualError(t, err, "new-signup and new_signup both generate NewSignup")
+
+	str := models.NewFeature("mixed", "a", "", "", "user-groups/beta", "dcdr")
+	str.FeatureType = models.String
+
+	_, err = Go(models.Features{*models.NewFeature("mixed", true, "", "", "", "dcdr"), *str}, "")
+	assert.EqualError(t, err, "mixed has incompatible types in different scopes")
+}
@@ -0,0 +1,187 @@
+package refs
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vsco/dcdr/models"
+)
+
+// Methods the `client.IFace` methods taking a feature name as their
+// first argument.
+var Methods = map[string]bool{
+	"IsAvailable":      true,
+	"IsAvailableForID": true,
+	"IsAvailableFor":   true,
+	"Evaluate":         true,
+	"EvaluateFor":      true,
+	"ScaleValue":       true,
+	"StringValue":      true,
+	"IntValue":         true,
+	"JSONValue":        true,
+	"Variant":          true,
+	"FeatureExists":    true,
+	"Subscribe":        true,
+}
+
+// Ref a call to one of `Methods` with a literal feature name.
+type Ref struct {
+	Feature  string         `json:"feature"`
+	Method   string         `json:"method"`
+	Position token.Position `json:"position"`
+}
+
+// Report features defined in the store but never referenced and
+// references to features that are not defined.
+type Report struct {
+	Refs      []Ref    `json:"refs"`
+	Unused    []string `json:"unused"`
+	Undefined []Ref    `json:"undefined"`
+}
+
+// Scan parses the Go files in each of `paths`, walking directories
+// recursively, and returns the feature references found sorted by
+// position. Hidden, `vendor`, and `testdata` directories are skipped.
+// Calls are matched by method name so any receiver is considered.
+func Scan(paths ...string) ([]Ref, error) {
+	var refs []Ref
+	fset := token.NewFileSet()
+
+	for _, p := range paths {
+		err := filepath.Walk(p, func(fp string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if info.IsDir() {
+				if fp != p && skipDir(info.Name()) {
+					return filepath.SkipDir
+				}
+
+				return nil
+			}
+
+			if !strings.HasSuffix(fp, ".go") {
+				return nil
+			}
+
+			fr, err := ScanFile(fset, fp)
+
+			if err != nil {
+				return err
+			}
+
+			refs = append(refs, fr...)
+
+			return nil
+		})
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(refs, func(i, j int) bool {
+		a, b := refs[i].Position, refs[j].Position
+
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+
+		return a.Offset < b.Offset
+	})
+
+	return refs, nil
+}
+
+// ScanFile parses the Go file at `fp` and returns its feature references.
+func ScanFile(fset *token.FileSet, fp string) ([]Ref, error) {
+	f, err := parser.ParseFile(fset, fp, nil, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var refs []Ref
+
+	ast.Inspect(f, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+
+		if !ok || len(call.Args) == 0 {
+			return true
+		}
+
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+
+		if !ok || !Methods[sel.Sel.Name] {
+			return true
+		}
+
+		lit, ok := call.Args[0].(*ast.BasicLit)
+
+		if !ok || lit.Kind != token.STRING {
+			return true
+		}
+
+		name, err := strconv.Unquote(lit.Value)
+
+		if err != nil {
+			return true
+		}
+
+		refs = append(refs, Ref{
+			Feature:  name,
+			Method:   sel.Sel.Name,
+			Position: fset.Position(lit.Pos()),
+		})
+
+		return true
+	})
+
+	return refs, nil
+}
+
+// Compare cross references `refs` against the features in the store.
+func Compare(refs []Ref, fts models.Features) *Report {
+	defined := make(map[string]bool)
+	used := make(map[string]bool)
+
+	for _, ft := range fts {
+		defined[ft.Key] = true
+	}
+
+	r := &Report{
+		Refs:      refs,
+		Unused:    []string{},
+		Undefined: []Ref{},
+	}
+
+	for _, ref := range refs {
+		used[ref.Feature] = true
+
+		if !defined[ref.Feature] {
+			r.Undefined = append(r.Undefined, ref)
+		}
+	}
+
+	for key := range defined {
+		if !used[key] {
+			r.Unused = append(r.Unused, key)
+		}
+	}
+
+	sort.Strings(r.Unused)
+
+	return r
+}
+
+func skipDir(name string) bool {
+	return name == "vendor" || name == "testdata" ||
+		strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")
+}
@@ -0,0 +1,92 @@
+package refs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vsco/dcdr/models"
+)
+
+const source = `package app
+
+import "github.com/vsco/dcdr/client"
+
+const dynamic = "dynamic"
+
+func handle(dcdr client.IFace, id uint64) {
+	if dcdr.IsAvailable("new-signup") {
+	}
+
+	if dcdr.IsAvailableForID("rollout", id) {
+	}
+
+	dcdr.ScaleValue("limit", 0, 10)
+	dcdr.FeatureExists(dynamic)
+	strings.Contains("not-a-flag", "x")
+}
+`
+
+func writeFile(t *testing.T, fp string, src string) {
+	if err := os.MkdirAll(filepath.Dir(fp), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(fp, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestScan(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "app", "app.go"), source)
+	writeFile(t, filepath.Join(dir, "app", "README.md"), `dcdr.IsAvailable("docs")`)
+	writeFile(t, filepath.Join(dir, "vendor", "dep", "dep.go"), "package dep\n\nfunc f() { c.IsAvailable(\"vendored\") }\n")
+
+	refs, err := Scan(dir)
+
+	assert.NoError(t, err)
+	assert.Len(t, refs, 3)
+	assert.Equal(t, "new-signup", refs[0].Feature)
+	assert.Equal(t, "IsAvailable", refs[0].Method)
+	assert.Equal(t, 8, refs[0].Position.Line)
+	assert.Equal(t, "rollout", refs[1].Feature)
+	assert.Equal(t, "IsAvailableForID", refs[1].Method)
+	assert.Equal(t, "limit", refs[2].Feature)
+	assert.Equal(t, "ScaleValue", refs[2].Method)
+
+	refs, err = Scan(filepath.Join(dir, "app", "app.go"))
+
+	assert.NoError(t, err)
+	assert.Len(t, refs, 3)
+}
+
+func TestScanParseError(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "bad.go"), "package bad\n\nfunc {")
+
+	_, err := Scan(dir)
+
+	assert.Error(t, err)
+}
+
+func TestCompare(t *testing.T) {
+	refs := []Ref{
+		{Feature: "new-signup", Method: "IsAvailable"},
+		{Feature: "missing", Method: "ScaleValue"},
+	}
+
+	fts := models.Features{
+		*models.NewFeature("new-signup", true, "", "", "", "dcdr"),
+		*models.NewFeature("new-signup", false, "", "", "user-groups/beta", "dcdr"),
+		*models.NewFeature("old", true, "", "", "", "dcdr"),
+	}
+
+	r := Compare(refs, fts)
+
+	assert.Equal(t, []string{"old"}, r.Unused)
+	assert.Equal(t, []Ref{refs[1]}, r.Undefined)
+	assert.Equal(t, refs, r.Refs)
+}
@@ -1,18 +1,38 @@
 package repo
 
 import (
+	"errors"
 	"os"
 	"os/exec"
+	"regexp"
 
 	"fmt"
 	"strings"
 
 	"io/ioutil"
+	"strconv"
+	"time"
 
 	"github.com/vsco/dcdr/cli/printer"
 	"github.com/vsco/dcdr/config"
 )
 
+// ErrInvalidRevision returned for revisions that are not a SHA or ref.
+var ErrInvalidRevision = errors.New("revisions must be a commit SHA or ref name")
+
+// revisionPattern SHAs and refs such as "HEAD~2". Revisions may not start
+// with "-" so they cannot be read as options by git.
+var revisionPattern = regexp.MustCompile(`^[0-9A-Za-z_][0-9A-Za-z_./~^@-]*$`)
+
+// ValidRevision checks that `sha` is a commit SHA or ref name.
+func ValidRevision(sha string) error {
+	if !revisionPattern.MatchString(sha) || strings.Contains(sha, "..") {
+		return ErrInvalidRevision
+	}
+
+	return nil
+}
+
 type IFace interface {
 	Init()
 	Clone() error
@@ -23,6 +43,17 @@ type IFace interface {
 	Push() error
 	Pull() error
 	CurrentSHA() (string, error)
+	Log() ([]Revision, error)
+	Show(sha string) ([]byte, error)
+	Contains(sha string) (bool, error)
+}
+
+// Revision a commit to the audit repo.
+type Revision struct {
+	SHA     string
+	Author  string
+	Date    time.Time
+	Message string
 }
 
 const DefaultPerms = 0755
@@ -182,6 +213,87 @@ func (g *Git) Commit(bts []byte, msg string) error {
 	return nil
 }
 
+// Log returns the revisions of the features file, newest first.
+func (g *Git) Log() ([]Revision, error) {
+	cmd := exec.Command(GitExec(), "log", "--format=%H%x1f%an%x1f%at%x1f%s", "--", config.OutputFileName)
+	cmd.Dir = g.Config.Git.RepoPath
+	bts, err := cmd.Output()
+
+	if err != nil {
+		return nil, fmt.Errorf("could not read log from %s", g.Config.Git.RepoPath)
+	}
+
+	var revs []Revision
+
+	for _, line := range strings.Split(strings.TrimSpace(string(bts[:])), "\n") {
+		pts := strings.SplitN(line, "\x1f", 4)
+
+		if len(pts) != 4 {
+			continue
+		}
+
+		ts, err := strconv.ParseInt(pts[2], 10, 64)
+
+		if err != nil {
+			return nil, err
+		}
+
+		revs = append(revs, Revision{
+			SHA:     pts[0],
+			Author:  pts[1],
+			Date:    time.Unix(ts, 0),
+			Message: pts[3],
+		})
+	}
+
+	return revs, nil
+}
+
+// Show returns the contents of the features file at `sha`.
+func (g *Git) Show(sha string) ([]byte, error) {
+	if err := ValidRevision(sha); err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(GitExec(), "show", fmt.Sprintf("%s:%s", sha, config.OutputFileName))
+	cmd.Dir = g.Config.Git.RepoPath
+	bts, err := cmd.Output()
+
+	if err != nil {
+		return nil, fmt.Errorf("could not find %s at %s", config.OutputFileName, sha)
+	}
+
+	return bts, nil
+}
+
+// Contains checks if `sha` is in the history of the current HEAD.
+func (g *Git) Contains(sha string) (bool, error) {
+	if err := ValidRevision(sha); err != nil {
+		return false, err
+	}
+
+	cmd := exec.Command(GitExec(), "cat-file", "-e", sha+"^{commit}")
+	cmd.Dir = g.Config.Git.RepoPath
+
+	if cmd.Run() != nil {
+		return false, nil
+	}
+
+	cmd = exec.Command(GitExec(), "merge-base", "--is-ancestor", sha, "HEAD")
+	cmd.Dir = g.Config.Git.RepoPath
+	err := cmd.Run()
+
+	if err == nil {
+		return true, nil
+	}
+
+	if exit, ok := err.(*exec.ExitError); ok && exit.ExitCode() == 1 {
+		return false, nil
+	}
+
+	return false, fmt.Errorf("could not find %s in %s", sha, g.Config.Git.RepoPath)
+}
+
 func (g *Git) Push() error {
 	cmd := exec.Command(GitExec(), "push", "origin", "master")
 	cmd.Dir = g.Config.Git.RepoPath
@@ -0,0 +1,17 @@
+package repo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidRevision(t *testing.T) {
+	for _, sha := range []string{"HEAD", "HEAD~2", "HEAD^", "master", "origin/master", "3f2a9c1", "v1.0.0"} {
+		assert.NoError(t, ValidRevision(sha), sha)
+	}
+
+	for _, sha := range []string{"", "--output=/tmp/x", "-p", "a..b", "a b", "a:b", "HEAD;rm"} {
+		assert.Equal(t, ErrInvalidRevision, ValidRevision(sha), sha)
+	}
+}
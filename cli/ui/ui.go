@@ -2,13 +2,23 @@ package ui
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/rodaine/table"
+	"github.com/vsco/dcdr/cli/api"
+	"github.com/vsco/dcdr/cli/refs"
+	"github.com/vsco/dcdr/client"
 	"github.com/vsco/dcdr/config"
 	"github.com/vsco/dcdr/models"
 )
 
+const (
+	shortSHALen = 7
+	dateFmt     = "2006-01-02 15:04:05"
+)
+
 var (
 	headerFmt = color.New(color.FgGreen, color.Underline).SprintfFunc()
 	columnFmt = color.New(color.FgYellow).SprintfFunc()
@@ -24,12 +34,192 @@ func New() (u *UI) {
 
 func (u *UI) DrawFeatures(features models.Features) {
 	color.NoColor = false
-	tbl := table.New("Name", "Type", "Value", "Scope", "Updated By", "Comment").
+	tbl := table.New("Name", "Type", "Value", "Scope", "Rules", "Owner", "Tags", "Expires", "Updated", "Updated By", "Comment").
 		WithHeaderFormatter(headerFmt).
 		WithFirstColumnFormatter(columnFmt)
 
 	for _, feature := range features {
-		tbl.AddRow(feature.Key, feature.FeatureType, feature.Value, feature.Scope, feature.UpdatedBy, feature.Comment)
+		tbl.AddRow(feature.Key, feature.FeatureType, feature.Value, feature.Scope, feature.Rules,
+			feature.Owner, strings.Join(feature.Tags, ","), formatTime(feature.ExpiresAt, models.ExpiryDateFormat),
+			formatTime(feature.UpdatedAt, dateFmt), feature.UpdatedBy, feature.Comment)
+	}
+
+	tbl.Print()
+}
+
+func formatTime(t *time.Time, layout string) string {
+	if t == nil {
+		return ""
+	}
+
+	return t.Format(layout)
+}
+
+// DrawResolution draws the effective value of a feature after scope
+// resolution. The id result is only drawn when `withID` is true.
+func (u *UI) DrawResolution(detail client.EvaluationDetail, scopes []string, withID bool) {
+	color.NoColor = false
+	tbl := table.New("Resolution", "Value").
+		WithHeaderFormatter(headerFmt).
+		WithFirstColumnFormatter(columnFmt)
+
+	tbl.AddRow("Scopes", strings.Join(scopes, " > "))
+	tbl.AddRow("Resolved Scope", detail.Scope)
+	tbl.AddRow("Type", detail.FeatureType)
+	tbl.AddRow("Effective Value", detail.Value)
+
+	if withID {
+		tbl.AddRow("Enabled", detail.Enabled)
+		tbl.AddRow("Reason", detail.Reason)
+	}
+
+	tbl.Print()
+}
+
+// DrawHistory draws the value of a feature at each revision that changed it.
+func (u *UI) DrawHistory(revs []api.FeatureRevision) {
+	color.NoColor = false
+	tbl := table.New("SHA", "Date", "Author", "Value", "Message").
+		WithHeaderFormatter(headerFmt).
+		WithFirstColumnFormatter(columnFmt)
+
+	for _, rev := range revs {
+		var value interface{} = rev.Value
+
+		if rev.Deleted {
+			value = "(deleted)"
+		}
+
+		sha := rev.SHA
+
+		if len(sha) > shortSHALen {
+			sha = sha[:shortSHALen]
+		}
+
+		tbl.AddRow(sha, rev.Date.Format(dateFmt), rev.Author, value, rev.Message)
+	}
+
+	tbl.Print()
+}
+
+// DrawDiff draws each added, removed, or changed key in `diff`.
+func (u *UI) DrawDiff(diff *api.DiffResult) {
+	color.NoColor = false
+	tbl := table.New("Key", "Change", diff.From, diff.To).
+		WithHeaderFormatter(headerFmt).
+		WithFirstColumnFormatter(columnFmt)
+
+	for _, k := range diff.Keys() {
+		if v, ok := diff.Added[k]; ok {
+			tbl.AddRow(k, "added", "", v)
+		} else if v, ok := diff.Removed[k]; ok {
+			tbl.AddRow(k, "removed", v, "")
+		} else {
+			tbl.AddRow(k, "changed", diff.Changed[k].From, diff.Changed[k].To)
+		}
+	}
+
+	tbl.Print()
+}
+
+// DrawPlan draws the features that will be created, updated, or deleted.
+func (u *UI) DrawPlan(changes []api.Change) {
+	color.NoColor = false
+	tbl := table.New("Action", "Name", "Scope", "Type", "From", "To").
+		WithHeaderFormatter(headerFmt).
+		WithFirstColumnFormatter(columnFmt)
+
+	for _, change := range changes {
+		ft := change.Feature
+		var from interface{}
+
+		if change.Previous != nil {
+			from = change.Previous.MapValue()
+		}
+
+		switch {
+		case change.Deleted:
+			tbl.AddRow("delete", ft.Key, ft.GetScope(), ft.FeatureType, from, "")
+		case change.Previous == nil:
+			tbl.AddRow("create", ft.Key, ft.GetScope(), ft.FeatureType, "", ft.MapValue())
+		default:
+			tbl.AddRow("update", ft.Key, ft.GetScope(), ft.FeatureType, from, ft.MapValue())
+		}
+	}
+
+	tbl.Print()
+}
+
+// DrawStale draws each stale feature and the reasons it is stale.
+func (u *UI) DrawStale(stale []api.StaleFeature) {
+	color.NoColor = false
+	tbl := table.New("Name", "Scope", "Value", "Owner", "Reasons", "Rolled Out", "Expires").
+		WithHeaderFormatter(headerFmt).
+		WithFirstColumnFormatter(columnFmt)
+
+	for _, sf := range stale {
+		reasons := make([]string, len(sf.Reasons))
+
+		for i, r := range sf.Reasons {
+			reasons[i] = string(r)
+		}
+
+		tbl.AddRow(sf.Key, sf.GetScope(), sf.Value, sf.Owner, strings.Join(reasons, ", "),
+			formatTime(sf.RolledOutAt, dateFmt), formatTime(sf.ExpiresAt, models.ExpiryDateFormat))
+	}
+
+	tbl.Print()
+}
+
+// DrawRefs draws the unused flags and references to undefined flags in `r`.
+func (u *UI) DrawRefs(r *refs.Report) {
+	color.NoColor = false
+	tbl := table.New("Name", "Status", "Method", "Location").
+		WithHeaderFormatter(headerFmt).
+		WithFirstColumnFormatter(columnFmt)
+
+	for _, key := range r.Unused {
+		tbl.AddRow(key, "unused", "", "")
+	}
+
+	for _, ref := range r.Undefined {
+		tbl.AddRow(ref.Feature, "undefined", ref.Method, ref.Position)
+	}
+
+	tbl.Print()
+}
+
+// DrawSchedule draws each pending step of `s`.
+func (u *UI) DrawSchedule(s *models.Schedule) {
+	color.NoColor = false
+	tbl := table.New("Name", "Scope", "At", "Value").
+		WithHeaderFormatter(headerFmt).
+		WithFirstColumnFormatter(columnFmt)
+
+	for _, step := range s.Steps {
+		tbl.AddRow(s.Key, s.GetScope(), step.At.Format(dateFmt), step.Value)
+	}
+
+	tbl.Print()
+}
+
+// DrawSchedules draws the next step and remaining steps of each schedule.
+func (u *UI) DrawSchedules(schedules []models.Schedule) {
+	color.NoColor = false
+	tbl := table.New("Name", "Scope", "Next", "Value", "Steps", "Ends", "Created By").
+		WithHeaderFormatter(headerFmt).
+		WithFirstColumnFormatter(columnFmt)
+
+	for _, s := range schedules {
+		next := s.Next()
+
+		if next == nil {
+			continue
+		}
+
+		last := s.Steps[len(s.Steps)-1]
+		tbl.AddRow(s.Key, s.GetScope(), next.At.Format(dateFmt), next.Value, len(s.Steps),
+			last.At.Format(dateFmt), s.CreatedBy)
 	}
 
 	tbl.Print()
@@ -44,6 +234,13 @@ func (u *UI) DrawConfig(cfg *config.Config) {
 
 	tbl.AddRow("Watcher", "OutputPath", cfg.Watcher.OutputPath, "File path to watch and read from")
 
+	if cfg.Watcher.HTTPEnabled() || cfg.Watcher.StreamEnabled() {
+		tbl.AddRow("Watcher", "Type", cfg.Watcher.Type, "Client watcher type (file|http|stream)")
+		tbl.AddRow("Watcher", "URL", cfg.Watcher.URL, "dcdr server URL to poll")
+		tbl.AddRow("Watcher", "PollInterval", cfg.Watcher.PollInterval, "Poll interval ('5s')")
+		tbl.AddRow("Watcher", "Heartbeat", cfg.Watcher.Heartbeat, "Stream heartbeat when not advertised ('15s')")
+	}
+
 	tbl.AddRow("Server", "Endpoint", cfg.Server.Endpoint, "The path to serve (GET '/dcdr.json')")
 	tbl.AddRow("Server", "Host", cfg.Server.Host, "The server host (:8000")
 	tbl.AddRow("Server", "JSONRoot", cfg.Server.JSONRoot, "JSON root node ('dcdr')")
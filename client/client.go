@@ -1,8 +1,12 @@
 package client
 
 import (
+	"encoding/json"
+	"fmt"
 	"hash/crc32"
+	"math"
 	"strconv"
+	"sync"
 
 	"os"
 
@@ -16,31 +20,94 @@ import (
 type IFace interface {
 	IsAvailable(feature string) bool
 	IsAvailableForID(feature string, id uint64) bool
+	IsAvailableFor(feature string, ctx EvalContext) bool
+	Evaluate(feature string, id uint64) EvaluationDetail
+	EvaluateFor(feature string, ctx EvalContext) EvaluationDetail
 	ScaleValue(feature string, min float64, max float64) float64
+	StringValue(feature string, defaultValue string) string
+	IntValue(feature string, defaultValue int64) int64
+	JSONValue(feature string, v interface{}) error
+	Variant(feature string, id uint64) string
 	UpdateFeatures(bts []byte)
 	FeatureExists(feature string) bool
+	FeatureType(feature string) models.FeatureType
 	Features() models.FeatureScopes
 	FeatureMap() *models.FeatureMap
 	SetFeatureMap(fm *models.FeatureMap) *Client
 	ScopedMap() *models.FeatureMap
+	UnmergedMap() *models.FeatureMap
 	Scopes() []string
 	Info() *models.Info
 	WithScopes(scopes ...string) *Client
+	OnChange(fn func(old, new models.FeatureScopes)) func()
+	Subscribe(feature string, fn func(old, new interface{})) func()
+	OnUpdate(fn func(fm *models.FeatureMap)) func()
 }
 
 // Client handles access to the `FeatureMap`
 type Client struct {
+	source    *source
+	merged    *merged
+	config    *config.Config
+	watcher   watcher.IFace
+	scopes    []string
+	listeners *listeners
+}
+
+// source the `FeatureMap` shared between a `Client` and the scoped
+// clients created from it with `WithScopes`.
+type source struct {
+	sync.RWMutex
+	featureMap *models.FeatureMap
+}
+
+// merged the features of `featureMap` merged with a client's scopes.
+type merged struct {
+	sync.Mutex
 	featureMap *models.FeatureMap
-	config     *config.Config
-	watcher    watcher.IFace
 	features   models.FeatureScopes
-	scopes     []string
 }
 
-// New creates a new Client with a custom Config
+// New creates a new Client with a custom Config. The `Watcher` is
+// selected by `Watcher.Type`, either polling or streaming from a
+// `dcdr server` or watching the `Watcher.OutputPath` file. Clients
+// watching a server are scoped to `Watcher.Scopes`, the only scopes the
+// server sends.
 func New(cfg *config.Config) (c *Client, err error) {
 	c = &Client{
-		config: cfg,
+		source:    &source{},
+		merged:    &merged{},
+		config:    cfg,
+		listeners: newListeners(),
+	}
+
+	if c.config.Watcher.HTTPEnabled() || c.config.Watcher.StreamEnabled() {
+		interval, err := c.config.Watcher.PollDuration()
+
+		if err != nil {
+			return c, err
+		}
+
+		w := c.config.Watcher
+		c.scopes = w.Scopes
+
+		if w.StreamEnabled() {
+			heartbeat, err := w.HeartbeatDuration()
+
+			if err != nil {
+				return c, err
+			}
+
+			c.watcher = watcher.NewStream(w.URL, w.Scopes, interval).
+				WithToken(w.Token).
+				WithHeartbeat(heartbeat)
+		} else {
+			c.watcher = watcher.NewHTTP(w.URL, w.Scopes, interval).WithToken(w.Token)
+		}
+
+		_, err = c.Watch()
+
+		return c, err
 	}
 
 	if c.config.Watcher.OutputPath != "" {
@@ -67,7 +134,10 @@ func NewDefault() (c *Client, err error) {
 // WithScopes creates a new Client from `c` that is "scoped"
 // to the provided scopes argument. `scopes` are provided in priority order.
 // For example, when given WithScopes("a", "b", "c"). Keys found in "a"
-// will override the same keys found in "b" and so on for "c".
+// will override the same keys found in "b" and so on for "c". Scoped
+// clients share the `FeatureMap` and callbacks of `c` so they see every
+// update. Clients watching a `dcdr server` only have the scopes in
+// `Watcher.Scopes`.
 func (c *Client) WithScopes(scopes ...string) *Client {
 	if len(scopes) == 0 {
 		return c
@@ -80,9 +150,11 @@ func (c *Client) WithScopes(scopes ...string) *Client {
 	newScopes := append(scopes, c.scopes...)
 
 	newClient := &Client{
-		featureMap: c.FeatureMap(),
-		scopes:     newScopes,
-		config:     c.config,
+		source:    c.source,
+		merged:    &merged{},
+		scopes:    newScopes,
+		config:    c.config,
+		listeners: c.listeners,
 	}
 
 	newClient.MergeScopes()
@@ -92,12 +164,33 @@ func (c *Client) WithScopes(scopes ...string) *Client {
 
 // MergeScopes delegates merging to the underlying `FeatureMap`
 func (c *Client) MergeScopes() {
+	fm := c.currentMap()
+
+	c.merged.Lock()
+	defer c.merged.Unlock()
 
-	if c.featureMap != nil {
-		c.features = c.featureMap.Dcdr.MergedScopes(c.scopes...)
+	c.merge(fm)
+}
+
+// merge caches the features of `fm` merged with the client's scopes.
+// Callers must hold the `merged` lock.
+func (c *Client) merge(fm *models.FeatureMap) {
+	c.merged.featureMap = fm
+	c.merged.features = nil
+
+	if fm != nil {
+		c.merged.features = fm.Dcdr.MergedScopes(c.scopes...)
 	}
 }
 
+// currentMap the shared `FeatureMap`, which is nil until one is set.
+func (c *Client) currentMap() *models.FeatureMap {
+	c.source.RLock()
+	defer c.source.RUnlock()
+
+	return c.source.featureMap
+}
+
 // Scopes `scopes` accessor
 func (c *Client) Scopes() []string {
 	return c.scopes
@@ -106,15 +199,21 @@ func (c *Client) Scopes() []string {
 // SetFeatureMap assigns a `FeatureMap` and merges the current
 // scopes. When git is enabled a new `FeatureMap` will not be
 // assigned unless its `CurrentSHA` is different from the one
-// currently found in `CurrentSHA()`.
+// currently found in `CurrentSHA()`. The map is shared with clients
+// created with `WithScopes`. Callbacks registered with `OnChange` and
+// `Subscribe` are called once the map is assigned.
 func (c *Client) SetFeatureMap(fm *models.FeatureMap) *Client {
 	if c.config.GitEnabled() && c.Info().CurrentSHA == fm.Dcdr.CurrentSHA() {
 		return c
 	}
 
-	c.featureMap = fm
+	c.source.Lock()
+	prev := c.source.featureMap
+	c.source.featureMap = fm
+	c.source.Unlock()
 
 	c.MergeScopes()
+	c.listeners.notify(prev, fm)
 
 	return c
 }
@@ -122,25 +221,44 @@ func (c *Client) SetFeatureMap(fm *models.FeatureMap) *Client {
 // FeatureMap `featureMap` accessor. Returns an empty `FeatureMap`
 // if the `featureMap` is nil.
 func (c *Client) FeatureMap() *models.FeatureMap {
-	if c.featureMap != nil {
-		return c.featureMap
+	if fm := c.currentMap(); fm != nil {
+		return fm
 	}
 
 	return models.EmptyFeatureMap()
 }
 
-// ScopedMap a `FeatureMap` containing only merged features and `Info`.
+// ScopedMap a `FeatureMap` containing only merged features, their
+// types and rules keyed by name, and `Info`.
 func (c *Client) ScopedMap() *models.FeatureMap {
 	fm := models.EmptyFeatureMap()
 	fm.Dcdr.FeatureScopes = c.Features()
+	fm.Dcdr.Types = c.FeatureMap().Dcdr.MergedTypes(c.scopes...)
+	fm.Dcdr.Rules = c.FeatureMap().Dcdr.MergedRules(c.scopes...)
 	fm.Dcdr.Info = c.FeatureMap().Dcdr.Info
 
 	return fm
 }
 
-// Features `features` accessor
+// UnmergedMap returns the `FeatureMap` restricted to the client's
+// scopes and the 'default' scope without merging them.
+func (c *Client) UnmergedMap() *models.FeatureMap {
+	return c.FeatureMap().Dcdr.SelectScopes(c.scopes...)
+}
+
+// Features the features of the current `FeatureMap` merged with the
+// client's scopes. They are merged again once the map is replaced.
 func (c *Client) Features() models.FeatureScopes {
-	return c.features
+	fm := c.currentMap()
+
+	c.merged.Lock()
+	defer c.merged.Unlock()
+
+	if c.merged.featureMap != fm {
+		c.merge(fm)
+	}
+
+	return c.merged.features
 }
 
 // Info accessor for the underlying `Info` from `FeatureMap`
@@ -148,6 +266,31 @@ func (c *Client) Info() *models.Info {
 	return c.FeatureMap().Dcdr.Info
 }
 
+// FeatureType returns the type recorded for `feature` in the scope it
+// resolves from. Returns `Invalid` if the feature does not exist.
+func (c *Client) FeatureType(feature string) models.FeatureType {
+	val, exists := c.Features()[feature]
+
+	if !exists {
+		return models.Invalid
+	}
+
+	return c.FeatureMap().Dcdr.TypeOf(c.featurePath(feature), val)
+}
+
+// rules returns the targeting rules recorded for `feature` in the
+// scope it resolves from.
+func (c *Client) rules(feature string) models.Rules {
+	return c.FeatureMap().Dcdr.Rules[c.featurePath(feature)]
+}
+
+// featurePath the "<scope>/<name>" path `feature` resolves from.
+func (c *Client) featurePath(feature string) string {
+	scope, _ := c.FeatureMap().Dcdr.ResolveScope(feature, c.scopes...)
+
+	return scope + "/" + feature
+}
+
 // FeatureExists checks the existence of a key
 func (c *Client) FeatureExists(feature string) bool {
 	_, exists := c.Features()[feature]
@@ -156,29 +299,39 @@ func (c *Client) FeatureExists(feature string) bool {
 }
 
 // IsAvailable used to check features with boolean values. Returns false
-// if a non-boolean type `feature` is passed.
+// if a non-boolean type `feature` or a feature with targeting rules,
+// which require `IsAvailableFor`, is passed.
 func (c *Client) IsAvailable(feature string) bool {
 	val, exists := c.Features()[feature]
 
 	switch val.(type) {
 	case bool:
-		return exists && val.(bool)
+		return exists && val.(bool) && len(c.rules(feature)) == 0
 	default:
 		return false
 	}
 }
 
-// IsAvailableForID used to check features with float values between 0.0-1.0.
-// Returns false if a non-percentile type `feature` is passed.
+// IsAvailableForID used to check percentile features. Returns false if a
+// non-percentile type `feature`, including an integer, or a feature with
+// targeting rules is passed.
 func (c *Client) IsAvailableForID(feature string, id uint64) bool {
 	val, exists := c.Features()[feature]
 
-	switch val.(type) {
-	case float64, int:
-		return exists && c.withinPercentile(id, val.(float64), feature)
-	default:
+	if !exists || c.FeatureType(feature) != models.Percentile || len(c.rules(feature)) > 0 {
 		return false
 	}
+
+	f, ok := val.(float64)
+
+	return ok && c.withinPercentile(id, f, feature)
+}
+
+// IsAvailableFor used to check targeted features against `ctx`. Features
+// with targeting rules are only available when every rule matches. Boolean
+// values are returned as is and percentile values are bucketed by `ctx.ID`.
+func (c *Client) IsAvailableFor(feature string, ctx EvalContext) bool {
+	return c.EvaluateFor(feature, ctx).Enabled
 }
 
 // UpdateFeatures creates and assigns a new `FeatureMap` from a
@@ -202,16 +355,100 @@ func (c *Client) UpdateFeatures(bts []byte) {
 func (c *Client) ScaleValue(feature string, min float64, max float64) float64 {
 	val, exists := c.Features()[feature]
 
-	if !exists {
+	if !exists || c.FeatureType(feature) != models.Percentile {
 		return min
 	}
 
-	switch val.(type) {
-	case float64, int:
-		return min + (max-min)*val.(float64)
-	default:
+	f, ok := val.(float64)
+
+	if !ok {
 		return min
 	}
+
+	return min + (max-min)*f
+}
+
+// StringValue returns the value of a string `feature` or `defaultValue`
+// if the feature is missing or not a string.
+func (c *Client) StringValue(feature string, defaultValue string) string {
+	val, exists := c.Features()[feature]
+
+	if !exists {
+		return defaultValue
+	}
+
+	switch v := val.(type) {
+	case string:
+		return v
+	default:
+		return defaultValue
+	}
+}
+
+// IntValue returns the value of an integer `feature` or `defaultValue`
+// if the feature is missing or not a whole number.
+func (c *Client) IntValue(feature string, defaultValue int64) int64 {
+	val, exists := c.Features()[feature]
+
+	if !exists {
+		return defaultValue
+	}
+
+	switch v := val.(type) {
+	case float64:
+		if v != math.Trunc(v) {
+			return defaultValue
+		}
+
+		return int64(v)
+	case int64:
+		return v
+	case int:
+		return int64(v)
+	default:
+		return defaultValue
+	}
+}
+
+// JSONValue decodes the value of a JSON `feature` into `v`. Returns
+// an error if the feature is missing or cannot be decoded into `v`.
+//
+// Given the K/V dcdr/features/default/search => {"boost": 2}
+// var cfg struct { Boost int `json:"boost"` }
+// JSONValue("search", &cfg) => cfg.Boost == 2
+func (c *Client) JSONValue(feature string, v interface{}) error {
+	val, exists := c.Features()[feature]
+
+	if !exists {
+		return fmt.Errorf("%s not found", feature)
+	}
+
+	bts, err := json.Marshal(val)
+
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(bts, v)
+}
+
+// Variant returns the name of the variant `id` is allocated to for a
+// variant `feature`. Bucketing uses the same CRC32 hash as `IsAvailableForID`.
+// Returns an empty string if the feature is missing or not a variant.
+func (c *Client) Variant(feature string, id uint64) string {
+	val, exists := c.Features()[feature]
+
+	if !exists {
+		return ""
+	}
+
+	wv, ok := models.WeightedVariantsFromValue(val)
+
+	if !ok {
+		return ""
+	}
+
+	return wv.Bucket(c.bucket(id, feature))
 }
 
 // Watch initializes the `Watcher`, registers the `UpdateFeatures`
@@ -236,10 +473,13 @@ func (c *Client) Watch() (*Client, error) {
 }
 
 func (c *Client) withinPercentile(id uint64, val float64, feature string) bool {
-	uid := c.crc(id, feature)
 	percentage := uint32(val * 100)
 
-	return uid%100 < percentage
+	return c.bucket(id, feature) < percentage
+}
+
+func (c *Client) bucket(id uint64, feature string) uint32 {
+	return c.crc(id, feature) % 100
 }
 
 func (c *Client) crc(id uint64, feature string) uint32 {
@@ -2,6 +2,8 @@ package client
 
 import (
 	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
 
@@ -37,7 +39,9 @@ var JSONBytes = []byte(`{
     "features": {
       "ab": {
         "float": 0.5,
-        "bool": false
+        "bool": false,
+        "string": "b",
+        "json": {"size": 2}
       },
       "cc": {
         "cn": {
@@ -49,7 +53,11 @@ var JSONBytes = []byte(`{
         "float": 0,
         "bool_false": false,
         "bool": true,
-        "default_float": 0.5
+        "default_float": 0.5,
+        "string": "a",
+        "int": 10,
+        "json": {"size": 1, "name": "default"},
+        "variant": [{"name": "a", "weight": 0.5}, {"name": "b", "weight": 0.5}]
       }
     },
     "info": {
@@ -151,6 +159,28 @@ func TestIsAvailableForID(t *testing.T) {
 	assert.True(t, c.IsAvailableForID("default_float", 5))
 }
 
+func TestIntegerFeatures(t *testing.T) {
+	fm := models.EmptyFeatureMap()
+	fm.Dcdr.FeatureScopes[models.DefaultScope] = map[string]interface{}{
+		"count":   1.0,
+		"rollout": 1.0,
+	}
+	fm.Dcdr.AddType("default/count", models.Integer)
+	fm.Dcdr.AddType("default/rollout", models.Percentile)
+
+	c := NewTestClient().SetFeatureMap(fm)
+
+	assert.Equal(t, models.Integer, c.FeatureType("count"))
+	assert.False(t, c.IsAvailableForID("count", 5))
+	assert.Equal(t, float64(2), c.ScaleValue("count", 2, 10))
+	assert.Equal(t, int64(1), c.IntValue("count", 0))
+	assert.Equal(t, TypeMismatch, c.Evaluate("count", 5).Reason)
+
+	assert.Equal(t, models.Percentile, c.FeatureType("rollout"))
+	assert.True(t, c.IsAvailableForID("rollout", 5))
+	assert.Equal(t, InPercentile, c.Evaluate("rollout", 5).Reason)
+}
+
 func TestScaleValue(t *testing.T) {
 	m := MockFeatureMap()
 	c := NewTestClient().SetFeatureMap(m)
@@ -159,6 +189,65 @@ func TestScaleValue(t *testing.T) {
 	assert.Equal(t, float64(7.5), c.ScaleValue("default_float", 5, 10))
 }
 
+func TestStringValue(t *testing.T) {
+	m := MockFeatureMap()
+	c := NewTestClient().SetFeatureMap(m)
+
+	assert.Equal(t, "a", c.StringValue("string", "z"))
+	assert.Equal(t, "b", c.WithScopes("ab").StringValue("string", "z"))
+	assert.Equal(t, "z", c.StringValue("bool", "z"))
+	assert.Equal(t, "z", c.StringValue("nope", "z"))
+}
+
+func TestIntValue(t *testing.T) {
+	m := MockFeatureMap()
+	c := NewTestClient().SetFeatureMap(m)
+
+	assert.Equal(t, int64(10), c.IntValue("int", 1))
+	assert.Equal(t, int64(1), c.IntValue("default_float", 1))
+	assert.Equal(t, int64(1), c.IntValue("nope", 1))
+}
+
+func TestJSONValue(t *testing.T) {
+	m := MockFeatureMap()
+	c := NewTestClient().SetFeatureMap(m)
+
+	var v struct {
+		Size int    `json:"size"`
+		Name string `json:"name"`
+	}
+
+	err := c.JSONValue("json", &v)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, v.Size)
+	assert.Equal(t, "default", v.Name)
+
+	var scoped map[string]int
+	err = c.WithScopes("ab").JSONValue("json", &scoped)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"size": 2}, scoped)
+
+	assert.Error(t, c.JSONValue("nope", &v))
+}
+
+func TestVariant(t *testing.T) {
+	m := MockFeatureMap()
+	c := NewTestClient().SetFeatureMap(m)
+
+	for id := uint64(0); id < 100; id++ {
+		expected := "b"
+
+		if c.withinPercentile(id, 0.5, "variant") {
+			expected = "a"
+		}
+
+		assert.Equal(t, expected, c.Variant("variant", id))
+	}
+
+	assert.Equal(t, "", c.Variant("default_float", 1))
+	assert.Equal(t, "", c.Variant("nope", 1))
+}
+
 // ruby -e "require 'zlib';puts Zlib::crc32('some_feature123');"
 // => 1706325722
 // php -r "echo crc32('some_feature123');"
@@ -274,3 +363,51 @@ func TestWatch(t *testing.T) {
 	err = os.Remove(p)
 	assert.NoError(t, err)
 }
+
+func TestHTTPWatch(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"dcdr": {"info": {"current_sha": "abc"}, "features": {"bool": true}}}`))
+	}))
+	defer ts.Close()
+
+	cfg := config.TestConfig()
+	cfg.Watcher.Type = config.WatcherTypeHTTP
+	cfg.Watcher.URL = ts.URL
+	cfg.Watcher.PollInterval = "1h"
+
+	c, err := New(cfg)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "abc", c.Info().CurrentSHA)
+	assert.True(t, c.IsAvailable("bool"))
+
+	cfg.Watcher.PollInterval = "soon"
+	_, err = New(cfg)
+	assert.Error(t, err)
+}
+
+func TestHTTPWatchScopes(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("x-dcdr-raw-scopes", "true")
+		w.Write([]byte(`{"dcdr": {"info": {"current_sha": "abc"}, "features": {
+			"default": {"bool": false, "float": 0.1},
+			"cc": {"bool": true, "float": 0.2, "cn": {"float": 0.3}}}}}`))
+	}))
+	defer ts.Close()
+
+	cfg := config.TestConfig()
+	cfg.Watcher.Type = config.WatcherTypeHTTP
+	cfg.Watcher.URL = ts.URL
+	cfg.Watcher.Scopes = []string{"cc/cn", "cc"}
+	cfg.Watcher.PollInterval = "1h"
+
+	c, err := New(cfg)
+	assert.NoError(t, err)
+
+	assert.Equal(t, cfg.Watcher.Scopes, c.Scopes())
+	assert.True(t, c.Features()["bool"].(bool))
+	assert.Equal(t, 0.3, c.Features()["float"])
+
+	// scopes resolve on the client rather than on the server
+	assert.Equal(t, 0.2, c.WithScopes("cc").Features()["float"])
+}
@@ -0,0 +1,83 @@
+package client
+
+import (
+	"github.com/vsco/dcdr/models"
+)
+
+// Reason explains the result of an `EvaluationDetail`.
+type Reason string
+
+const (
+	// NotFound the feature does not exist in any of the client's scopes
+	NotFound Reason = "not_found"
+	// TypeMismatch the feature is not a boolean or percentile
+	TypeMismatch Reason = "type_mismatch"
+	// RulesNotMatched the feature's targeting rules did not match
+	RulesNotMatched Reason = "rules_not_matched"
+	// InPercentile the id falls within the percentile value
+	InPercentile Reason = "in_percentile"
+	// OutOfPercentile the id falls outside of the percentile value
+	OutOfPercentile Reason = "out_of_percentile"
+	// Default the stored boolean value was returned as is
+	Default Reason = "default"
+)
+
+// EvaluationDetail the result of evaluating a feature along with
+// the scope it was resolved from and the reason for the result.
+type EvaluationDetail struct {
+	Feature     string             `json:"feature"`
+	Enabled     bool               `json:"enabled"`
+	Value       interface{}        `json:"value"`
+	Scope       string             `json:"scope"`
+	FeatureType models.FeatureType `json:"feature_type"`
+	Reason      Reason             `json:"reason"`
+}
+
+// Evaluate evaluates `feature` for `id`. Booleans and percentiles are
+// evaluated the same as `IsAvailable` and `IsAvailableForID`.
+func (c *Client) Evaluate(feature string, id uint64) EvaluationDetail {
+	return c.EvaluateFor(feature, EvalContext{ID: id})
+}
+
+// EvaluateFor evaluates `feature` for `ctx` including targeting rules.
+func (c *Client) EvaluateFor(feature string, ctx EvalContext) EvaluationDetail {
+	detail := EvaluationDetail{
+		Feature: feature,
+		Reason:  NotFound,
+	}
+
+	val, exists := c.Features()[feature]
+
+	if !exists {
+		return detail
+	}
+
+	detail.Value = val
+	detail.FeatureType = c.FeatureType(feature)
+	detail.Scope, _ = c.FeatureMap().Dcdr.ResolveScope(feature, c.scopes...)
+
+	if rules := c.rules(feature); len(rules) > 0 && !c.MatchRules(rules, ctx) {
+		detail.Reason = RulesNotMatched
+		return detail
+	}
+
+	b, isBool := val.(bool)
+	f, isFloat := val.(float64)
+
+	switch {
+	case detail.FeatureType == models.Boolean && isBool:
+		detail.Enabled = b
+		detail.Reason = Default
+	case detail.FeatureType == models.Percentile && isFloat:
+		detail.Enabled = c.withinPercentile(ctx.ID, f, feature)
+		detail.Reason = OutOfPercentile
+
+		if detail.Enabled {
+			detail.Reason = InPercentile
+		}
+	default:
+		detail.Reason = TypeMismatch
+	}
+
+	return detail
+}
@@ -0,0 +1,60 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vsco/dcdr/models"
+)
+
+func TestEvaluate(t *testing.T) {
+	m := MockFeatureMap()
+	c := NewTestClient().SetFeatureMap(m)
+
+	d := c.Evaluate("nope", 1)
+	assert.Equal(t, NotFound, d.Reason)
+	assert.False(t, d.Enabled)
+
+	d = c.Evaluate("bool", 1)
+	assert.Equal(t, Default, d.Reason)
+	assert.Equal(t, models.Boolean, d.FeatureType)
+	assert.Equal(t, models.DefaultScope, d.Scope)
+	assert.True(t, d.Enabled)
+
+	d = c.Evaluate("string", 1)
+	assert.Equal(t, TypeMismatch, d.Reason)
+	assert.Equal(t, models.String, d.FeatureType)
+	assert.Equal(t, "a", d.Value)
+
+	d = c.Evaluate("float", 1)
+	assert.Equal(t, OutOfPercentile, d.Reason)
+	assert.Equal(t, models.Percentile, d.FeatureType)
+
+	d = c.Evaluate("default_float", 10)
+	assert.Equal(t, InPercentile, d.Reason)
+	assert.True(t, d.Enabled)
+}
+
+func TestEvaluateScoped(t *testing.T) {
+	m := MockFeatureMap()
+	c := NewTestClient().SetFeatureMap(m).WithScopes("cc/cn", "ab")
+
+	assert.Equal(t, "cc/cn", c.Evaluate("bool", 1).Scope)
+	assert.Equal(t, "ab", c.Evaluate("string", 1).Scope)
+	assert.Equal(t, models.DefaultScope, c.Evaluate("int", 1).Scope)
+	assert.Equal(t, models.Integer, c.Evaluate("int", 1).FeatureType)
+}
+
+func TestEvaluateForRules(t *testing.T) {
+	c := NewTestClient().SetFeatureMap(TargetedFeatureMap())
+
+	d := c.EvaluateFor("targeted", EvalContext{})
+	assert.Equal(t, RulesNotMatched, d.Reason)
+	assert.Equal(t, models.Boolean, d.FeatureType)
+
+	d = c.EvaluateFor("targeted", EvalContext{
+		Attributes: map[string]string{"country": "ca", "app_version": "5.2"},
+	})
+	assert.Equal(t, Default, d.Reason)
+	assert.True(t, d.Enabled)
+}
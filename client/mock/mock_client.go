@@ -61,6 +61,30 @@ func (d *Client) DisablePercentileFeature(feature string) {
 	d.SetPercentileFeature(feature, 0.0)
 }
 
+// SetStringFeature set a string feature to an arbitrary value
+func (d *Client) SetStringFeature(feature string, val string) {
+	d.Client.FeatureMap().Dcdr.Defaults()[feature] = val
+	d.MergeScopes()
+}
+
+// SetIntegerFeature set an integer feature to an arbitrary value
+func (d *Client) SetIntegerFeature(feature string, val int64) {
+	d.Client.FeatureMap().Dcdr.Defaults()[feature] = val
+	d.MergeScopes()
+}
+
+// SetJSONFeature set a JSON feature to an arbitrary decoded value
+func (d *Client) SetJSONFeature(feature string, val interface{}) {
+	d.Client.FeatureMap().Dcdr.Defaults()[feature] = val
+	d.MergeScopes()
+}
+
+// SetVariantFeature set a variant feature to the provided weighted variants
+func (d *Client) SetVariantFeature(feature string, val models.WeightedVariants) {
+	d.Client.FeatureMap().Dcdr.Defaults()[feature] = val
+	d.MergeScopes()
+}
+
 // Features `features` accessor
 func (d *Client) Features() models.FeatureScopes {
 	return d.Client.FeatureMap().Dcdr.Defaults()
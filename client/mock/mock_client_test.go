@@ -20,4 +20,9 @@ func TestMockClient(t *testing.T) {
 	assert.True(t, d.IsAvailableForID("float", 2))
 	d.DisablePercentileFeature("float")
 	assert.False(t, d.IsAvailableForID("float", 8))
+
+	d.SetStringFeature("string", "a")
+	assert.Equal(t, "a", d.StringValue("string", ""))
+	d.SetIntegerFeature("int", 4)
+	assert.Equal(t, int64(4), d.IntValue("int", 0))
 }
@@ -0,0 +1,164 @@
+package client
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/vsco/dcdr/models"
+)
+
+// EvalContext the subject a feature is evaluated for. `ID` is used
+// for percentile bucketing and `Attributes` are matched against
+// targeting `Rules`.
+type EvalContext struct {
+	ID         uint64
+	Attributes map[string]string
+}
+
+// Attribute returns the value of `name`. The "id" attribute falls
+// back to `ID` when it has not been explicitly provided.
+func (ctx EvalContext) Attribute(name string) (string, bool) {
+	if v, ok := ctx.Attributes[name]; ok {
+		return v, true
+	}
+
+	if name == "id" {
+		return strconv.FormatUint(ctx.ID, 10), true
+	}
+
+	return "", false
+}
+
+// MatchRules checks that every rule in `rules` matches `ctx`.
+func (c *Client) MatchRules(rules models.Rules, ctx EvalContext) bool {
+	for _, r := range rules {
+		if !c.matchRule(r, ctx) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (c *Client) matchRule(r models.Rule, ctx EvalContext) bool {
+	attr, ok := ctx.Attribute(r.Attribute)
+
+	if !ok {
+		return r.Operator == models.NotIn || r.Operator == models.Neq
+	}
+
+	switch r.Operator {
+	case models.In, models.Eq:
+		return contains(r.Values, attr)
+	case models.NotIn, models.Neq:
+		return !contains(r.Values, attr)
+	case models.Gt:
+		return compare(attr, r.Values[0]) > 0
+	case models.Gte:
+		return compare(attr, r.Values[0]) >= 0
+	case models.Lt:
+		return compare(attr, r.Values[0]) < 0
+	case models.Lte:
+		return compare(attr, r.Values[0]) <= 0
+	case models.InSegment:
+		for _, segment := range r.Values {
+			if c.inSegment(segment, attr) {
+				return true
+			}
+		}
+
+		return false
+	default:
+		return false
+	}
+}
+
+// inSegment checks for `attr` within the JSON array stored in the
+// `segment` feature.
+//
+// Given the K/V dcdr/features/default/beta-testers => ["1", "42"]
+// inSegment("beta-testers", "42") => true
+func (c *Client) inSegment(segment string, attr string) bool {
+	var members []interface{}
+
+	if err := c.JSONValue(segment, &members); err != nil {
+		return false
+	}
+
+	for _, m := range members {
+		switch v := m.(type) {
+		case string:
+			if v == attr {
+				return true
+			}
+		case float64:
+			if strconv.FormatFloat(v, 'f', -1, 64) == attr {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func contains(s []string, str string) bool {
+	for _, i := range s {
+		if i == str {
+			return true
+		}
+	}
+
+	return false
+}
+
+// compare compares dotted numeric versions such as "5.10" and "5.2"
+// part by part. Plain numbers are compared as single part versions.
+// Non-numeric parts fall back to string comparison.
+func compare(a string, b string) int {
+	as := strings.Split(a, ".")
+	bs := strings.Split(b, ".")
+
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var ap, bp string
+
+		if i < len(as) {
+			ap = as[i]
+		}
+
+		if i < len(bs) {
+			bp = bs[i]
+		}
+
+		if c := comparePart(ap, bp); c != 0 {
+			return c
+		}
+	}
+
+	return 0
+}
+
+func comparePart(a string, b string) int {
+	if a == "" {
+		a = "0"
+	}
+
+	if b == "" {
+		b = "0"
+	}
+
+	ai, aerr := strconv.ParseFloat(a, 64)
+	bi, berr := strconv.ParseFloat(b, 64)
+
+	if aerr != nil || berr != nil {
+		return strings.Compare(a, b)
+	}
+
+	switch {
+	case ai < bi:
+		return -1
+	case ai > bi:
+		return 1
+	default:
+		return 0
+	}
+}
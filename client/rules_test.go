@@ -0,0 +1,113 @@
+package client
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vsco/dcdr/models"
+)
+
+func TargetedFeatureMap() *models.FeatureMap {
+	fm := models.EmptyFeatureMap()
+	fm.Dcdr.FeatureScopes[models.DefaultScope] = map[string]interface{}{
+		"beta-testers": []interface{}{"1", float64(42)},
+		"targeted":     true,
+		"segmented":    1.0,
+		"bool":         true,
+	}
+	fm.Dcdr.AddType("default/segmented", models.Percentile)
+	fm.Dcdr.AddRules("default/targeted", models.Rules{
+		{Attribute: "country", Operator: models.In, Values: []string{"us", "ca"}},
+		{Attribute: "app_version", Operator: models.Gte, Values: []string{"5.2"}},
+	})
+	fm.Dcdr.AddRules("default/segmented", models.Rules{
+		{Attribute: "id", Operator: models.InSegment, Values: []string{"beta-testers"}},
+	})
+
+	return fm
+}
+
+func TestIsAvailableFor(t *testing.T) {
+	c := NewTestClient().SetFeatureMap(TargetedFeatureMap())
+
+	ctx := EvalContext{
+		Attributes: map[string]string{"country": "us", "app_version": "5.10"},
+	}
+
+	assert.True(t, c.IsAvailableFor("targeted", ctx))
+	assert.False(t, c.IsAvailable("targeted"))
+	assert.True(t, c.IsAvailableFor("bool", ctx))
+	assert.False(t, c.IsAvailableFor("nope", ctx))
+
+	ctx.Attributes["app_version"] = "5.1.9"
+	assert.False(t, c.IsAvailableFor("targeted", ctx))
+
+	ctx.Attributes = map[string]string{"app_version": "6"}
+	assert.False(t, c.IsAvailableFor("targeted", ctx))
+}
+
+func TestIsAvailableForSegment(t *testing.T) {
+	c := NewTestClient().SetFeatureMap(TargetedFeatureMap())
+
+	assert.True(t, c.IsAvailableFor("segmented", EvalContext{ID: 42}))
+	assert.True(t, c.IsAvailableFor("segmented", EvalContext{ID: 2, Attributes: map[string]string{"id": "1"}}))
+	assert.False(t, c.IsAvailableFor("segmented", EvalContext{ID: 2}))
+}
+
+func TestMatchRule(t *testing.T) {
+	c := NewTestClient()
+	ctx := EvalContext{Attributes: map[string]string{"platform": "ios", "build": "100"}}
+
+	cases := map[string]bool{
+		"platform == ios":       true,
+		"platform != ios":       false,
+		"platform not_in web":   true,
+		"missing not_in web":    true,
+		"missing in web":        false,
+		"build > 99":            true,
+		"build < 99":            false,
+		"build <= 100":          true,
+		"platform segment nope": false,
+	}
+
+	for v, expected := range cases {
+		r, err := models.ParseRule(v)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, c.matchRule(*r, ctx), v)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	assert.Equal(t, 1, compare("5.10", "5.2"))
+	assert.Equal(t, 0, compare("5.2", "5.2.0"))
+	assert.Equal(t, -1, compare("4", "5.2"))
+	assert.Equal(t, -1, compare("a", "b"))
+}
+
+func TestTargetedFeatureMapCompatibility(t *testing.T) {
+	bts, err := TargetedFeatureMap().ToJSON()
+	assert.NoError(t, err)
+
+	// readers unaware of rules decode the features alone and must still
+	// find plain values rather than nested scopes
+	var older struct {
+		Dcdr struct {
+			FeatureScopes map[string]map[string]interface{} `json:"features"`
+		} `json:"dcdr"`
+	}
+
+	assert.NoError(t, json.Unmarshal(bts, &older))
+	assert.Equal(t, true, older.Dcdr.FeatureScopes[models.DefaultScope]["targeted"])
+	assert.Equal(t, 1.0, older.Dcdr.FeatureScopes[models.DefaultScope]["segmented"])
+
+	fm, err := models.NewFeatureMap(bts)
+	assert.NoError(t, err)
+	assert.Equal(t, true, fm.Dcdr.MergedScopes()["targeted"])
+	assert.Empty(t, fm.Dcdr.InScope("default/targeted"))
+
+	c := NewTestClient().SetFeatureMap(fm)
+	assert.False(t, c.IsAvailable("targeted"))
+	assert.False(t, c.IsAvailableForID("segmented", 42))
+	assert.True(t, c.IsAvailableFor("segmented", EvalContext{ID: 42}))
+}
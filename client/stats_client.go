@@ -1,17 +1,24 @@
 package client
 
 import (
+	"encoding/json"
 	"strings"
+	"sync"
 
 	"github.com/DataDog/datadog-go/v5/statsd"
+	"github.com/vsco/dcdr/cli/api/ioutil2"
 	"github.com/vsco/dcdr/config"
 	"github.com/vsco/dcdr/models"
 )
 
-// StatsClient delegates `Client` methods with metrics.
+// StatsClient delegates `Client` methods with metrics. The number of
+// evaluations of each feature is also kept in memory and can be written
+// with `WriteEvaluations` to find unused features with `dcdr stale`.
 type StatsClient struct {
 	Client
-	stats statsd.ClientInterface
+	stats       statsd.ClientInterface
+	mu          sync.Mutex
+	evaluations map[string]uint64
 }
 
 // NewStatsClient creates a new client.
@@ -23,7 +30,6 @@ func NewStatsClient(cfg *config.Config, stats statsd.ClientInterface) (sc *Stats
 	c, err := New(cfg)
 
 	sc.Client = *c
-	sc.Client.Watch()
 
 	return
 }
@@ -41,7 +47,6 @@ func NewStatsDefault(stats statsd.ClientInterface) (sc *StatsClient, err error)
 	}
 
 	sc.Client = *c
-	sc.Client.Watch()
 
 	return
 }
@@ -62,11 +67,64 @@ func (sc *StatsClient) IsAvailableForID(feature string, id uint64) bool {
 	return enabled
 }
 
-// ScaleValue delegates `ScaleValue`.
+// IsAvailableFor delegates `IsAvailableFor` and increments the provided `feature` status.
+func (sc *StatsClient) IsAvailableFor(feature string, ctx EvalContext) bool {
+	enabled := sc.Client.IsAvailableFor(feature, ctx)
+	defer sc.Incr(feature, enabled, 1)
+
+	return enabled
+}
+
+// Evaluate delegates `Evaluate` and increments the `feature` status and reason.
+func (sc *StatsClient) Evaluate(feature string, id uint64) EvaluationDetail {
+	return sc.EvaluateFor(feature, EvalContext{ID: id})
+}
+
+// EvaluateFor delegates `EvaluateFor` and increments the `feature` status and reason.
+func (sc *StatsClient) EvaluateFor(feature string, ctx EvalContext) EvaluationDetail {
+	detail := sc.Client.EvaluateFor(feature, ctx)
+	defer sc.IncrReason(feature, detail.Reason, 1)
+	defer sc.Incr(feature, detail.Enabled, 1)
+
+	return detail
+}
+
+// Variant delegates `Variant` and increments the allocated variant for `feature`.
+func (sc *StatsClient) Variant(feature string, id uint64) string {
+	variant := sc.Client.Variant(feature, id)
+	defer sc.IncrVariant(feature, variant, 1)
+
+	return variant
+}
+
+// ScaleValue delegates `ScaleValue` and records an evaluation of `feature`.
 func (sc *StatsClient) ScaleValue(feature string, min float64, max float64) float64 {
+	sc.recordEvaluation(feature)
+
 	return sc.Client.ScaleValue(feature, min, max)
 }
 
+// StringValue delegates `StringValue` and records an evaluation of `feature`.
+func (sc *StatsClient) StringValue(feature string, defaultValue string) string {
+	sc.recordEvaluation(feature)
+
+	return sc.Client.StringValue(feature, defaultValue)
+}
+
+// IntValue delegates `IntValue` and records an evaluation of `feature`.
+func (sc *StatsClient) IntValue(feature string, defaultValue int64) int64 {
+	sc.recordEvaluation(feature)
+
+	return sc.Client.IntValue(feature, defaultValue)
+}
+
+// JSONValue delegates `JSONValue` and records an evaluation of `feature`.
+func (sc *StatsClient) JSONValue(feature string, v interface{}) error {
+	sc.recordEvaluation(feature)
+
+	return sc.Client.JSONValue(feature, v)
+}
+
 // UpdateFeatures delegates `UpdateFeatures`.
 func (sc *StatsClient) UpdateFeatures(bts []byte) {
 	sc.Client.UpdateFeatures(bts)
@@ -87,12 +145,65 @@ func (sc *StatsClient) Scopes() []string {
 	return sc.Client.Scopes()
 }
 
-// Incr increments the formatted `statKey`.
+// Evaluations returns the number of times each feature has been
+// evaluated by this client.
+func (sc *StatsClient) Evaluations() map[string]uint64 {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	counts := make(map[string]uint64, len(sc.evaluations))
+
+	for k, v := range sc.evaluations {
+		counts[k] = v
+	}
+
+	return counts
+}
+
+// WriteEvaluations atomically writes `Evaluations` as JSON to `path`
+// in the format read by `dcdr stale --evaluations`.
+func (sc *StatsClient) WriteEvaluations(path string) error {
+	bts, err := json.MarshalIndent(sc.Evaluations(), "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	return ioutil2.WriteFileAtomic(path, bts, 0644)
+}
+
+func (sc *StatsClient) recordEvaluation(feature string) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if sc.evaluations == nil {
+		sc.evaluations = make(map[string]uint64)
+	}
+
+	sc.evaluations[feature]++
+}
+
+// Incr increments the formatted `statKey` and records an evaluation of `feature`.
 func (sc *StatsClient) Incr(feature string, enabled bool, sampleRate float64) {
+	sc.recordEvaluation(feature)
 	key := sc.statKey(feature, enabled)
 	sc.stats.Incr(key, []string{}, sampleRate)
 }
 
+// IncrReason increments the formatted `reasonStatKey`.
+func (sc *StatsClient) IncrReason(feature string, reason Reason, sampleRate float64) {
+	key := sc.reasonStatKey(feature, reason)
+	sc.stats.Incr(key, []string{}, sampleRate)
+}
+
+// IncrVariant increments the formatted `variantStatKey` and records an
+// evaluation of `feature`.
+func (sc *StatsClient) IncrVariant(feature string, variant string, sampleRate float64) {
+	sc.recordEvaluation(feature)
+	key := sc.variantStatKey(feature, variant)
+	sc.stats.Incr(key, []string{}, sampleRate)
+}
+
 func (sc *StatsClient) statKey(feature string, enabled bool) string {
 	status := "enabled"
 
@@ -100,11 +211,31 @@ func (sc *StatsClient) statKey(feature string, enabled bool) string {
 		status = "disabled"
 	}
 
+	return sc.formatKey(feature, status)
+}
+
+func (sc *StatsClient) reasonStatKey(feature string, reason Reason) string {
+	return sc.formatKey(feature, "reasons", string(reason))
+}
+
+// variantStatKey formats the key for a variant exposure. Ids
+// not allocated to any variant are counted as "none".
+func (sc *StatsClient) variantStatKey(feature string, variant string) string {
+	if variant == "" {
+		variant = "none"
+	}
+
+	return sc.formatKey(feature, "variants", variant)
+}
+
+func (sc *StatsClient) formatKey(feature string, suffix ...string) string {
 	scopes := models.DefaultScope
 
 	if len(sc.Client.Scopes()) > 0 {
 		scopes = strings.Replace(strings.Join(sc.Client.Scopes(), "."), "/", ".", -1)
 	}
 
-	return strings.Join([]string{sc.config.Namespace, scopes, feature, status}, ".")
+	pts := append([]string{sc.config.Namespace, scopes, feature}, suffix...)
+
+	return strings.Join(pts, ".")
 }
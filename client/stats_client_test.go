@@ -1,7 +1,10 @@
 package client
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -119,6 +122,25 @@ func NewMockStatter() (ms *MockStatter) {
 	return
 }
 
+func TestStatsClientWatchesOnce(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte(`{"dcdr": {"info": {"current_sha": "abc"}, "features": {"bool": true}}}`))
+	}))
+	defer ts.Close()
+
+	cfg := config.TestConfig()
+	cfg.Watcher.Type = config.WatcherTypeHTTP
+	cfg.Watcher.URL = ts.URL
+	cfg.Watcher.PollInterval = "1h"
+
+	c, err := NewStatsClient(cfg, NewMockStatter())
+	assert.NoError(t, err)
+	assert.True(t, c.IsAvailable("bool"))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
+
 func TestStatsClientIsAvailable(t *testing.T) {
 	ft := "feature"
 	ms := NewMockStatter()
@@ -141,6 +163,60 @@ func TestStatsClientIsAvailableForID(t *testing.T) {
 	assert.Equal(t, 1, ms.count[key])
 }
 
+func TestStatsClientVariant(t *testing.T) {
+	ft := "feature-3"
+	ms := NewMockStatter()
+	c, err := NewStatsClient(&config.Config{Namespace: "test"}, ms)
+	assert.NoError(t, err)
+
+	variant := c.Variant(ft, 1)
+	assert.Equal(t, "", variant)
+	assert.Equal(t, 1, ms.count["test.default.feature-3.variants.none"])
+
+	c.SetFeatureMap(&models.FeatureMap{
+		Dcdr: models.Root{
+			Info: &models.Info{},
+			FeatureScopes: models.FeatureScopes{
+				models.DefaultScope: map[string]interface{}{
+					ft: models.WeightedVariants{{Name: "a", Weight: 1}},
+				},
+			},
+		},
+	})
+
+	variant = c.Variant(ft, 1)
+	assert.Equal(t, "a", variant)
+	assert.Equal(t, 1, ms.count[c.variantStatKey(ft, "a")])
+}
+
+func TestStatsClientEvaluate(t *testing.T) {
+	ft := "feature-4"
+	ms := NewMockStatter()
+	c, err := NewStatsClient(&config.Config{Namespace: "test"}, ms)
+	assert.NoError(t, err)
+
+	detail := c.Evaluate(ft, 1)
+	assert.Equal(t, NotFound, detail.Reason)
+	assert.Equal(t, 1, ms.count["test.default.feature-4.reasons.not_found"])
+	assert.Equal(t, 1, ms.count[c.statKey(ft, false)])
+}
+
+func TestStatsClientEvaluations(t *testing.T) {
+	ms := NewMockStatter()
+	c, err := NewStatsClient(&config.Config{Namespace: "test"}, ms)
+	assert.NoError(t, err)
+
+	c.IsAvailable("a")
+	c.Evaluate("a", 1)
+	c.Variant("b", 1)
+	c.ScaleValue("c", 0, 10)
+	c.StringValue("d", "")
+	c.IntValue("e", 0)
+	c.JSONValue("f", &struct{}{})
+
+	assert.Equal(t, map[string]uint64{"a": 2, "b": 1, "c": 1, "d": 1, "e": 1, "f": 1}, c.Evaluations())
+}
+
 func TestFormatKey(t *testing.T) {
 	ft := "feature-2"
 	ms := NewMockStatter()
@@ -0,0 +1,145 @@
+package client
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/vsco/dcdr/models"
+)
+
+// changeListener a callback registered with `OnChange` or `Subscribe`
+// along with the scopes of the `Client` it was registered on.
+type changeListener struct {
+	scopes    []string
+	feature   string
+	onChange  func(old, new models.FeatureScopes)
+	onFeature func(old, new interface{})
+	onUpdate  func(fm *models.FeatureMap)
+}
+
+// listeners the set of callbacks shared between a `Client` and the
+// scoped clients created from it with `WithScopes`.
+type listeners struct {
+	sync.RWMutex
+	all []*changeListener
+}
+
+func newListeners() *listeners {
+	return &listeners{}
+}
+
+// add registers `l` returning a func that removes it.
+func (ls *listeners) add(l *changeListener) func() {
+	ls.Lock()
+	defer ls.Unlock()
+
+	ls.all = append(ls.all, l)
+
+	return func() {
+		ls.remove(l)
+	}
+}
+
+func (ls *listeners) remove(l *changeListener) {
+	ls.Lock()
+	defer ls.Unlock()
+
+	for i, registered := range ls.all {
+		if registered == l {
+			ls.all = append(ls.all[:i:i], ls.all[i+1:]...)
+			return
+		}
+	}
+}
+
+// notify diffs `prev` and `next` merged with each listener's scopes
+// and calls the listener when its scope or feature has changed.
+func (ls *listeners) notify(prev *models.FeatureMap, next *models.FeatureMap) {
+	ls.RLock()
+	all := make([]*changeListener, len(ls.all))
+	copy(all, ls.all)
+	ls.RUnlock()
+
+	if len(all) == 0 {
+		return
+	}
+
+	type diff struct {
+		old models.FeatureScopes
+		new models.FeatureScopes
+	}
+
+	diffs := make(map[string]diff)
+
+	for _, l := range all {
+		if l.onUpdate != nil {
+			l.onUpdate(next)
+			continue
+		}
+
+		key := strings.Join(l.scopes, ",")
+		d, ok := diffs[key]
+
+		if !ok {
+			before := make(models.FeatureScopes)
+
+			if prev != nil {
+				before = prev.Dcdr.MergedScopes(l.scopes...)
+			}
+
+			d.old, d.new = before.Diff(next.Dcdr.MergedScopes(l.scopes...))
+			diffs[key] = d
+		}
+
+		if len(d.old) == 0 && len(d.new) == 0 {
+			continue
+		}
+
+		if l.onChange != nil {
+			l.onChange(d.old, d.new)
+		}
+
+		if l.onFeature != nil {
+			_, removed := d.old[l.feature]
+			_, changed := d.new[l.feature]
+
+			if removed || changed {
+				l.onFeature(d.old[l.feature], d.new[l.feature])
+			}
+		}
+	}
+}
+
+// OnChange registers `fn` to be called after `SetFeatureMap` assigns
+// a `FeatureMap` that changes any feature within the client's scopes.
+// `old` contains the previous values of changed and removed features and
+// `new` contains the values of changed and added features. Callbacks are
+// shared with, and fire for, clients created with `WithScopes`. Calling
+// the returned func unregisters `fn`.
+func (c *Client) OnChange(fn func(old, new models.FeatureScopes)) func() {
+	return c.listeners.add(&changeListener{
+		scopes:   c.scopes,
+		onChange: fn,
+	})
+}
+
+// Subscribe registers `fn` to be called after `SetFeatureMap` when the
+// value of `feature` changes within the client's scopes. `old` is nil
+// when the feature is added and `new` is nil when it is removed. Calling
+// the returned func unregisters `fn`.
+func (c *Client) Subscribe(feature string, fn func(old, new interface{})) func() {
+	return c.listeners.add(&changeListener{
+		scopes:    c.scopes,
+		feature:   feature,
+		onFeature: fn,
+	})
+}
+
+// OnUpdate registers `fn` to be called with every `FeatureMap` assigned
+// by `SetFeatureMap`, regardless of scope or whether features changed.
+// Calling the returned func unregisters `fn`.
+func (c *Client) OnUpdate(fn func(fm *models.FeatureMap)) func() {
+	return c.listeners.add(&changeListener{
+		onUpdate: fn,
+	})
+}
@@ -0,0 +1,143 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vsco/dcdr/models"
+)
+
+func TestOnChange(t *testing.T) {
+	c := NewTestClient().SetFeatureMap(MockFeatureMap())
+
+	var old, new models.FeatureScopes
+	calls := 0
+
+	c.OnChange(func(o, n models.FeatureScopes) {
+		old, new = o, n
+		calls++
+	})
+
+	c.SetFeatureMap(MockFeatureMap())
+	assert.Equal(t, 0, calls)
+
+	fm := MockFeatureMap()
+	fm.Dcdr.Defaults()["bool"] = false
+	fm.Dcdr.Defaults()["added"] = true
+	delete(fm.Dcdr.Defaults(), "string")
+	c.SetFeatureMap(fm)
+
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, models.FeatureScopes{"bool": true, "string": "a"}, old)
+	assert.Equal(t, models.FeatureScopes{"bool": false, "added": true}, new)
+}
+
+func TestSubscribe(t *testing.T) {
+	c := NewTestClient().SetFeatureMap(MockFeatureMap())
+
+	var old, new interface{}
+	calls := 0
+
+	c.Subscribe("bool", func(o, n interface{}) {
+		old, new = o, n
+		calls++
+	})
+
+	fm := MockFeatureMap()
+	fm.Dcdr.Defaults()["float"] = 0.5
+	c.SetFeatureMap(fm)
+	assert.Equal(t, 0, calls)
+
+	fm = MockFeatureMap()
+	delete(fm.Dcdr.Defaults(), "bool")
+	c.SetFeatureMap(fm)
+
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, true, old)
+	assert.Nil(t, new)
+}
+
+func TestSubscribeScoped(t *testing.T) {
+	c := NewTestClient().SetFeatureMap(MockFeatureMap())
+	scoped := c.WithScopes("ab")
+
+	var scopedNew interface{}
+	scopedCalls := 0
+
+	scoped.Subscribe("float", func(o, n interface{}) {
+		scopedNew = n
+		scopedCalls++
+	})
+
+	// default/float changes are shadowed by ab/float
+	fm := MockFeatureMap()
+	fm.Dcdr.Defaults()["float"] = 0.9
+	c.SetFeatureMap(fm)
+	assert.Equal(t, 0, scopedCalls)
+
+	fm = MockFeatureMap()
+	fm.Dcdr.InScope("ab")["float"] = 0.7
+	c.SetFeatureMap(fm)
+	assert.Equal(t, 1, scopedCalls)
+	assert.Equal(t, 0.7, scopedNew)
+}
+
+func TestOnUpdate(t *testing.T) {
+	c := NewTestClient()
+	updates := 0
+
+	c.WithScopes("ab").OnUpdate(func(fm *models.FeatureMap) {
+		updates++
+	})
+
+	c.SetFeatureMap(MockFeatureMap())
+	c.SetFeatureMap(MockFeatureMap())
+
+	assert.Equal(t, 2, updates)
+}
+
+func TestUnsubscribe(t *testing.T) {
+	c := NewTestClient().SetFeatureMap(MockFeatureMap())
+	changes, updates := 0, 0
+
+	unsubscribe := c.Subscribe("bool", func(o, n interface{}) {
+		changes++
+	})
+	stop := c.OnUpdate(func(fm *models.FeatureMap) {
+		updates++
+	})
+
+	fm := MockFeatureMap()
+	fm.Dcdr.Defaults()["bool"] = false
+	c.SetFeatureMap(fm)
+	assert.Equal(t, 1, changes)
+	assert.Equal(t, 1, updates)
+
+	unsubscribe()
+	unsubscribe()
+	stop()
+
+	c.SetFeatureMap(MockFeatureMap())
+	assert.Equal(t, 1, changes)
+	assert.Equal(t, 1, updates)
+}
+
+func TestScopedClientUpdates(t *testing.T) {
+	c := NewTestClient().SetFeatureMap(MockFeatureMap())
+	scoped := c.WithScopes("ab")
+
+	var seen interface{}
+
+	scoped.Subscribe("float", func(o, n interface{}) {
+		seen = scoped.Features()["float"]
+	})
+
+	fm := MockFeatureMap()
+	fm.Dcdr.InScope("ab")["float"] = 0.7
+	c.SetFeatureMap(fm)
+
+	// the scoped client reads the map its callback fired for
+	assert.Equal(t, 0.7, seen)
+	assert.Equal(t, 0.7, scoped.Features()["float"])
+	assert.Equal(t, fm, scoped.FeatureMap())
+}
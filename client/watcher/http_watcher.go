@@ -0,0 +1,293 @@
+package watcher
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vsco/dcdr/cli/printer"
+	"github.com/vsco/dcdr/models"
+)
+
+const (
+	// maxBackoffFactor caps the poll interval backoff after errors.
+	maxBackoffFactor = 12
+	// requestTimeout bounds each poll independently of the interval.
+	requestTimeout = 10 * time.Second
+	// ifNoneMatchHeader sends the last seen Etag to the server.
+	ifNoneMatchHeader = "If-None-Match"
+	// etagHeader contains the CurrentSHA of the served `FeatureMap`.
+	etagHeader = "Etag"
+	// scopesHeader comma delimited scopes resolved by the server.
+	scopesHeader = "x-dcdr-scopes"
+	// rawScopesHeader requests the scopes unmerged so that clients can
+	// resolve them. Servers that do not echo it merge the scopes.
+	rawScopesHeader = "x-dcdr-raw-scopes"
+	// authorizationHeader carries the bearer token for authenticated servers.
+	authorizationHeader = "Authorization"
+)
+
+// ErrNoFeatures returned by `ReadFile` before a `FeatureMap` has
+// been successfully fetched.
+var ErrNoFeatures = errors.New("no features have been fetched")
+
+// HTTPWatcher polls a `dcdr server` endpoint for changes. The Etag of
+// the last response is sent as If-None-Match so that unchanged feature
+// sets are answered with a 304. Failed requests back off exponentially
+// and the last successfully fetched features are kept.
+type HTTPWatcher struct {
+	url           string
+	scopes        []string
+	token         string
+	interval      time.Duration
+	client        *http.Client
+	writeCallback func(bts []byte)
+	etag          string
+	bts           []byte
+	done          chan bool
+	closeOnce     sync.Once
+	mu            sync.Mutex
+}
+
+// NewHTTP initializes an `HTTPWatcher` polling `url` every `interval`.
+func NewHTTP(url string, scopes []string, interval time.Duration) (w *HTTPWatcher) {
+	printer.Logf("polling url: %s", url)
+
+	w = &HTTPWatcher{
+		url:      url,
+		scopes:   scopes,
+		interval: interval,
+		client: &http.Client{
+			Timeout: requestTimeout,
+		},
+		done: make(chan bool),
+	}
+
+	return
+}
+
+// WithToken sets the bearer `token` sent to servers requiring authentication.
+func (w *HTTPWatcher) WithToken(token string) *HTTPWatcher {
+	w.token = token
+
+	return w
+}
+
+// setScopes requests `scopes` from the server unmerged.
+func setScopes(req *http.Request, scopes []string) {
+	if len(scopes) > 0 {
+		req.Header.Set(scopesHeader, strings.Join(scopes, ","))
+	}
+
+	req.Header.Set(rawScopesHeader, "true")
+}
+
+// rawScopes checks if the server sent the scopes unmerged.
+func rawScopes(resp *http.Response) bool {
+	return resp.Header.Get(rawScopesHeader) == "true"
+}
+
+// setToken adds `token` as a bearer token when present.
+func setToken(req *http.Request, token string) {
+	if token != "" {
+		req.Header.Set(authorizationHeader, "Bearer "+token)
+	}
+}
+
+// Init verifies that a url has been provided.
+func (w *HTTPWatcher) Init() error {
+	if w.url == "" {
+		return errors.New("watcher url is required")
+	}
+
+	return nil
+}
+
+// Watch polls the url until `Close` is called.
+func (w *HTTPWatcher) Watch() {
+	wait := w.interval
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-timer.C:
+			err := w.UpdateBytes()
+
+			if err != nil {
+				wait = w.backoff(wait)
+				printer.LogErrf("UpdateBytes error: %v retrying in %s", err, wait)
+			} else {
+				wait = w.interval
+			}
+
+			timer.Reset(wait)
+		}
+	}
+}
+
+// Close stops `Watch`. It is safe to call more than once.
+func (w *HTTPWatcher) Close() {
+	w.closeOnce.Do(func() {
+		close(w.done)
+	})
+}
+
+// Register assigns the update callback.
+func (w *HTTPWatcher) Register(cb func(bts []byte)) {
+	w.writeCallback = cb
+}
+
+// UpdateBytes fetches the url and passes changed features to
+// `writeCallback`. Unchanged (304) responses are ignored.
+func (w *HTTPWatcher) UpdateBytes() error {
+	bts, changed, err := w.fetch()
+
+	if err != nil || !changed {
+		return err
+	}
+
+	w.writeCallback(bts)
+
+	return nil
+}
+
+// ReadFile returns the last successfully fetched features.
+func (w *HTTPWatcher) ReadFile() ([]byte, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.bts) == 0 {
+		return nil, ErrNoFeatures
+	}
+
+	return w.bts, nil
+}
+
+func (w *HTTPWatcher) fetch() ([]byte, bool, error) {
+	req, err := http.NewRequest("GET", w.url, nil)
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	w.mu.Lock()
+	etag := w.etag
+	w.mu.Unlock()
+
+	if etag != "" {
+		req.Header.Set(ifNoneMatchHeader, etag)
+	}
+
+	setScopes(req, w.scopes)
+	setToken(req, w.token)
+
+	resp, err := w.client.Do(req)
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, false, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("unexpected status %d from %s", resp.StatusCode, w.url)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	bts, err := scopedFeatures(body, rawScopes(resp))
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	w.mu.Lock()
+	w.etag = resp.Header.Get(etagHeader)
+	w.bts = bts
+	w.mu.Unlock()
+
+	return bts, true, nil
+}
+
+func (w *HTTPWatcher) backoff(wait time.Duration) time.Duration {
+	wait *= 2
+
+	if max := w.interval * maxBackoffFactor; wait > max {
+		return max
+	}
+
+	return wait
+}
+
+// scopedFeatures returns the features served by `dcdr server`. Unless
+// the server sent the scopes unmerged they are nested by `defaultScoped`.
+func scopedFeatures(bts []byte, raw bool) ([]byte, error) {
+	if !raw {
+		return defaultScoped(bts)
+	}
+
+	fm, err := models.NewFeatureMap(bts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if fm == nil {
+		return nil, errors.New("empty feature map")
+	}
+
+	return bts, nil
+}
+
+// defaultScoped nests the features served by `dcdr server`, which have
+// already been merged for the requested scopes, and their types and rules
+// into the 'default' scope.
+func defaultScoped(bts []byte) ([]byte, error) {
+	fm, err := models.NewFeatureMap(bts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if fm == nil {
+		return nil, errors.New("empty feature map")
+	}
+
+	features := fm.Dcdr.FeatureScopes
+
+	if features == nil {
+		features = make(models.FeatureScopes)
+	}
+
+	fm.Dcdr.FeatureScopes = models.FeatureScopes{
+		models.DefaultScope: map[string]interface{}(features),
+	}
+
+	types, rules := fm.Dcdr.Types, fm.Dcdr.Rules
+	fm.Dcdr.Types, fm.Dcdr.Rules = nil, nil
+
+	for k, t := range types {
+		fm.Dcdr.AddType(models.DefaultScope+"/"+k, t)
+	}
+
+	for k, r := range rules {
+		fm.Dcdr.AddRules(models.DefaultScope+"/"+k, r)
+	}
+
+	return fm.ToJSON()
+}
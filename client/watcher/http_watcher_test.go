@@ -0,0 +1,167 @@
+package watcher
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vsco/dcdr/models"
+)
+
+type mockServer struct {
+	sync.Mutex
+	sha      string
+	status   int
+	requests []*http.Request
+}
+
+func (ms *mockServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ms.Lock()
+	defer ms.Unlock()
+
+	ms.requests = append(ms.requests, r)
+
+	if ms.status != http.StatusOK {
+		w.WriteHeader(ms.status)
+		return
+	}
+
+	w.Header().Set(etagHeader, ms.sha)
+
+	if r.Header.Get(ifNoneMatchHeader) == ms.sha {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Write([]byte(`{"dcdr": {"info": {"current_sha": "` + ms.sha + `"}, "features": {"bool": true}}}`))
+}
+
+func TestHTTPWatcherUpdateBytes(t *testing.T) {
+	ms := &mockServer{sha: "a", status: http.StatusOK}
+	ts := httptest.NewServer(ms)
+	defer ts.Close()
+
+	w := NewHTTP(ts.URL, []string{"a", "b"}, time.Second)
+	assert.NoError(t, w.Init())
+
+	calls := 0
+	var fm *models.FeatureMap
+
+	w.Register(func(bts []byte) {
+		calls++
+		fm, _ = models.NewFeatureMap(bts)
+	})
+
+	assert.NoError(t, w.UpdateBytes())
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, "a", fm.Dcdr.CurrentSHA())
+	assert.Equal(t, true, fm.Dcdr.Defaults()["bool"])
+	assert.Equal(t, "a,b", ms.requests[0].Header.Get(scopesHeader))
+	assert.Equal(t, "true", ms.requests[0].Header.Get(rawScopesHeader))
+
+	// unchanged
+	assert.NoError(t, w.UpdateBytes())
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, "a", ms.requests[1].Header.Get(ifNoneMatchHeader))
+
+	// errors keep the last good features
+	ms.status = http.StatusInternalServerError
+	assert.Error(t, w.UpdateBytes())
+	assert.Equal(t, 1, calls)
+
+	bts, err := w.ReadFile()
+	assert.NoError(t, err)
+	assert.Contains(t, string(bts), `"current_sha": "a"`)
+
+	ms.status = http.StatusOK
+	ms.sha = "b"
+	assert.NoError(t, w.UpdateBytes())
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, "b", fm.Dcdr.CurrentSHA())
+}
+
+func TestHTTPWatcherRawScopes(t *testing.T) {
+	raw := `{"dcdr": {"info": {"current_sha": "a"}, "features": {"default": {"bool": false}, "a": {"bool": true}}}}`
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(rawScopesHeader, r.Header.Get(rawScopesHeader))
+		w.Write([]byte(raw))
+	}))
+	defer ts.Close()
+
+	w := NewHTTP(ts.URL, []string{"a"}, time.Second)
+	var fm *models.FeatureMap
+
+	w.Register(func(bts []byte) {
+		fm, _ = models.NewFeatureMap(bts)
+	})
+
+	assert.NoError(t, w.UpdateBytes())
+	assert.Equal(t, false, fm.Dcdr.Defaults()["bool"])
+	assert.Equal(t, true, fm.Dcdr.InScope("a")["bool"])
+}
+
+func TestHTTPWatcherWatch(t *testing.T) {
+	ms := &mockServer{sha: "a", status: http.StatusOK}
+	ts := httptest.NewServer(ms)
+	defer ts.Close()
+
+	w := NewHTTP(ts.URL, nil, 5*time.Millisecond)
+	assert.NoError(t, w.Init())
+
+	done := make(chan bool)
+	var once sync.Once
+
+	w.Register(func(bts []byte) {
+		once.Do(func() { close(done) })
+	})
+
+	go w.Watch()
+	defer w.Close()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for poll")
+	}
+}
+
+func TestHTTPWatcherClose(t *testing.T) {
+	w := NewHTTP("http://localhost", nil, time.Hour)
+	assert.Equal(t, requestTimeout, w.client.Timeout)
+
+	assert.NotPanics(t, func() {
+		w.Close()
+		w.Close()
+	})
+}
+
+func TestHTTPWatcherBackoff(t *testing.T) {
+	w := NewHTTP("", nil, time.Second)
+
+	assert.Error(t, w.Init())
+	assert.Equal(t, 2*time.Second, w.backoff(time.Second))
+	assert.Equal(t, 12*time.Second, w.backoff(10*time.Second))
+
+	_, err := w.ReadFile()
+	assert.Equal(t, ErrNoFeatures, err)
+}
+
+func TestHTTPWatcherToken(t *testing.T) {
+	ms := &mockServer{sha: "a", status: http.StatusOK}
+	ts := httptest.NewServer(ms)
+	defer ts.Close()
+
+	w := NewHTTP(ts.URL, nil, time.Second)
+	w.Register(func(bts []byte) {})
+	assert.NoError(t, w.UpdateBytes())
+	assert.Equal(t, "", ms.requests[0].Header.Get(authorizationHeader))
+
+	w = NewHTTP(ts.URL, nil, time.Second).WithToken("s3cret")
+	w.Register(func(bts []byte) {})
+	assert.NoError(t, w.UpdateBytes())
+	assert.Equal(t, "Bearer s3cret", ms.requests[1].Header.Get(authorizationHeader))
+}
@@ -0,0 +1,400 @@
+package watcher
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vsco/dcdr/cli/printer"
+)
+
+const (
+	// lastEventIDHeader resumes a stream from the last seen CurrentSHA.
+	lastEventIDHeader = "Last-Event-ID"
+	// featuresEvent event name for `FeatureMap` updates.
+	featuresEvent = "features"
+	// heartbeatHeader the heartbeat interval advertised by the server.
+	heartbeatHeader = "x-dcdr-heartbeat"
+	// defaultHeartbeat assumed when the server does not advertise one.
+	defaultHeartbeat = 15 * time.Second
+	// missedHeartbeats the number of heartbeats that can be missed
+	// before the connection is considered stalled and reconnected.
+	missedHeartbeats = 3
+)
+
+// event a single parsed Server-Sent Event.
+type event struct {
+	id   string
+	name string
+	data string
+}
+
+// StreamWatcher consumes the `dcdr server` Server-Sent Events stream.
+// The connection is opened by `Init` and the first features event is
+// read so that `UpdateBytes` can load the initial `FeatureMap`. Dropped
+// connections are retried with backoff using the Last-Event-ID header,
+// and the backoff is only reset once an event is received. Connections
+// that miss several heartbeats are closed and reconnected.
+type StreamWatcher struct {
+	url           string
+	scopes        []string
+	token         string
+	interval      time.Duration
+	heartbeat     time.Duration
+	timeout       time.Duration
+	raw           bool
+	client        *http.Client
+	writeCallback func(bts []byte)
+	lastID        string
+	bts           []byte
+	resp          *http.Response
+	reader        *bufio.Reader
+	watchdog      *time.Timer
+	done          chan bool
+	closeOnce     sync.Once
+	mu            sync.Mutex
+}
+
+// NewStream initializes a `StreamWatcher` for `url`. `interval` is the
+// base reconnect delay.
+func NewStream(url string, scopes []string, interval time.Duration) (w *StreamWatcher) {
+	printer.Logf("streaming url: %s", url)
+
+	w = &StreamWatcher{
+		url:       url,
+		scopes:    scopes,
+		interval:  interval,
+		heartbeat: defaultHeartbeat,
+		client:    &http.Client{},
+		done:      make(chan bool),
+	}
+
+	return
+}
+
+// WithToken sets the bearer `token` sent to servers requiring authentication.
+func (w *StreamWatcher) WithToken(token string) *StreamWatcher {
+	w.token = token
+
+	return w
+}
+
+// WithHeartbeat sets the heartbeat `interval` expected from servers
+// that do not advertise one.
+func (w *StreamWatcher) WithHeartbeat(interval time.Duration) *StreamWatcher {
+	w.heartbeat = interval
+
+	return w
+}
+
+// Init connects to the stream and waits for the first features event.
+// Like `HTTPWatcher`, a server that is unavailable is not an error.
+// `UpdateBytes` has no features to load and `Watch` keeps retrying.
+func (w *StreamWatcher) Init() error {
+	if w.url == "" {
+		return errors.New("watcher url is required")
+	}
+
+	if err := w.first(); err != nil {
+		printer.LogErrf("stream init error: %v", err)
+	}
+
+	return nil
+}
+
+// first connects and reads events until the first features event.
+func (w *StreamWatcher) first() error {
+	err := w.connect()
+
+	if err != nil {
+		return err
+	}
+
+	for {
+		ev, err := w.next()
+
+		if err != nil {
+			w.disconnect()
+			return err
+		}
+
+		if ev.name == featuresEvent {
+			_, err = w.handle(ev)
+
+			return err
+		}
+	}
+}
+
+// Watch reads events until `Close` is called, reconnecting on errors.
+func (w *StreamWatcher) Watch() {
+	wait := w.interval
+
+	for {
+		select {
+		case <-w.done:
+			return
+		default:
+		}
+
+		if !w.connected() {
+			if err := w.connect(); err != nil {
+				printer.LogErrf("stream connect error: %v retrying in %s", err, wait)
+
+				if !w.sleep(wait) {
+					return
+				}
+
+				wait = w.backoff(wait)
+				continue
+			}
+		}
+
+		ev, err := w.next()
+
+		if err != nil {
+			printer.LogErrf("stream read error: %v reconnecting in %s", err, wait)
+			w.disconnect()
+
+			if !w.sleep(wait) {
+				return
+			}
+
+			wait = w.backoff(wait)
+			continue
+		}
+
+		// the connection is only considered healthy once an event arrives
+		wait = w.interval
+
+		if ev.name != featuresEvent {
+			continue
+		}
+
+		bts, err := w.handle(ev)
+
+		if err != nil {
+			printer.LogErrf("stream event error: %v", err)
+			continue
+		}
+
+		w.writeCallback(bts)
+	}
+}
+
+// Close stops `Watch` and closes the connection. It is safe to call
+// more than once.
+func (w *StreamWatcher) Close() {
+	w.closeOnce.Do(func() {
+		close(w.done)
+	})
+	w.disconnect()
+}
+
+// Register assigns the update callback.
+func (w *StreamWatcher) Register(cb func(bts []byte)) {
+	w.writeCallback = cb
+}
+
+// UpdateBytes passes the last received features to `writeCallback`.
+func (w *StreamWatcher) UpdateBytes() error {
+	bts, err := w.ReadFile()
+
+	if err != nil {
+		return err
+	}
+
+	w.writeCallback(bts)
+
+	return nil
+}
+
+// ReadFile returns the last received features.
+func (w *StreamWatcher) ReadFile() ([]byte, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.bts) == 0 {
+		return nil, ErrNoFeatures
+	}
+
+	return w.bts, nil
+}
+
+func (w *StreamWatcher) connect() error {
+	req, err := http.NewRequest("GET", w.url, nil)
+
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "text/event-stream")
+
+	setScopes(req, w.scopes)
+	setToken(req, w.token)
+
+	w.mu.Lock()
+	lastID := w.lastID
+	w.mu.Unlock()
+
+	if lastID != "" {
+		req.Header.Set(lastEventIDHeader, lastID)
+	}
+
+	resp, err := w.client.Do(req)
+
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, w.url)
+	}
+
+	timeout := w.heartbeatTimeout(resp)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.resp = resp
+	w.reader = bufio.NewReader(resp.Body)
+	w.timeout = timeout
+	w.raw = rawScopes(resp)
+	w.watchdog = time.AfterFunc(timeout, func() {
+		resp.Body.Close()
+	})
+
+	return nil
+}
+
+// heartbeatTimeout allows `missedHeartbeats` of the interval advertised
+// in `resp`, falling back to the configured heartbeat.
+func (w *StreamWatcher) heartbeatTimeout(resp *http.Response) time.Duration {
+	heartbeat := w.heartbeat
+
+	if val := resp.Header.Get(heartbeatHeader); val != "" {
+		d, err := time.ParseDuration(val)
+
+		if err == nil && d > 0 {
+			heartbeat = d
+		} else {
+			printer.LogErrf("invalid heartbeat %q from %s", val, w.url)
+		}
+	}
+
+	return heartbeat * missedHeartbeats
+}
+
+func (w *StreamWatcher) connected() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.reader != nil
+}
+
+func (w *StreamWatcher) disconnect() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.watchdog != nil {
+		w.watchdog.Stop()
+	}
+
+	if w.resp != nil {
+		w.resp.Body.Close()
+	}
+
+	w.resp = nil
+	w.reader = nil
+}
+
+// next reads lines until a complete event has been dispatched. The
+// watchdog closes the connection if no lines arrive before it fires.
+func (w *StreamWatcher) next() (*event, error) {
+	w.mu.Lock()
+	reader, watchdog, timeout := w.reader, w.watchdog, w.timeout
+	w.mu.Unlock()
+
+	if reader == nil {
+		return nil, errors.New("stream is not connected")
+	}
+
+	ev := &event{}
+	var data []string
+
+	for {
+		line, err := reader.ReadString('\n')
+
+		if err != nil {
+			return nil, err
+		}
+
+		watchdog.Reset(timeout)
+
+		line = strings.TrimRight(line, "\r\n")
+
+		switch {
+		case line == "":
+			if ev.name == "" && len(data) == 0 {
+				continue
+			}
+
+			ev.data = strings.Join(data, "\n")
+
+			return ev, nil
+		case strings.HasPrefix(line, ":"):
+			continue
+		case strings.HasPrefix(line, "id:"):
+			ev.id = strings.TrimSpace(strings.TrimPrefix(line, "id:"))
+		case strings.HasPrefix(line, "event:"):
+			ev.name = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+}
+
+// handle stores the features and id from a features event.
+func (w *StreamWatcher) handle(ev *event) ([]byte, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	bts, err := scopedFeatures([]byte(ev.data), w.raw)
+
+	if err != nil {
+		return nil, err
+	}
+
+	w.bts = bts
+
+	if ev.id != "" {
+		w.lastID = ev.id
+	}
+
+	return bts, nil
+}
+
+// sleep waits for `wait`, returning false if `Close` is called first.
+func (w *StreamWatcher) sleep(wait time.Duration) bool {
+	select {
+	case <-w.done:
+		return false
+	case <-time.After(wait):
+		return true
+	}
+}
+
+func (w *StreamWatcher) backoff(wait time.Duration) time.Duration {
+	wait *= 2
+
+	if max := w.interval * maxBackoffFactor; wait > max {
+		return max
+	}
+
+	return wait
+}
@@ -0,0 +1,173 @@
+package watcher
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vsco/dcdr/models"
+)
+
+func TestStreamWatcher(t *testing.T) {
+	lastIDs := make(chan string, 2)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastIDs <- r.Header.Get(lastEventIDHeader)
+		flusher := w.(http.Flusher)
+
+		fmt.Fprint(w, ": comment\n\n")
+		fmt.Fprint(w, "event: heartbeat\ndata: 1\n\n")
+		fmt.Fprint(w, "id: a\nevent: features\ndata: {\"dcdr\": {\"info\": {\"current_sha\": \"a\"}, \"features\": {\"bool\": true}}}\n\n")
+		flusher.Flush()
+		fmt.Fprint(w, "id: b\nevent: features\ndata: {\"dcdr\": {\"info\": {\"current_sha\": \"b\"},\n")
+		fmt.Fprint(w, "data: \"features\": {\"bool\": false}}}\n\n")
+		flusher.Flush()
+	}))
+	defer ts.Close()
+
+	w := NewStream(ts.URL, []string{"a"}, 10*time.Millisecond)
+	assert.NoError(t, w.Init())
+	assert.Equal(t, "", <-lastIDs)
+
+	updates := make(chan *models.FeatureMap, 4)
+
+	w.Register(func(bts []byte) {
+		fm, err := models.NewFeatureMap(bts)
+		assert.NoError(t, err)
+		updates <- fm
+	})
+
+	assert.NoError(t, w.UpdateBytes())
+	fm := <-updates
+	assert.Equal(t, "a", fm.Dcdr.CurrentSHA())
+	assert.Equal(t, true, fm.Dcdr.Defaults()["bool"])
+
+	go w.Watch()
+	defer w.Close()
+
+	fm = <-updates
+	assert.Equal(t, "b", fm.Dcdr.CurrentSHA())
+	assert.Equal(t, false, fm.Dcdr.Defaults()["bool"])
+
+	// the server closes the stream and the watcher resumes from "b"
+	select {
+	case id := <-lastIDs:
+		assert.Equal(t, "b", id)
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for reconnect")
+	}
+}
+
+func TestStreamWatcherInitError(t *testing.T) {
+	var available int32
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&available) == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		fmt.Fprint(w, "id: a\nevent: features\ndata: {\"dcdr\": {\"info\": {\"current_sha\": \"a\"}, \"features\": {\"bool\": true}}}\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+
+	assert.Error(t, NewStream("", nil, time.Second).Init())
+
+	// the server is unavailable at startup and the watcher connects once it is
+	w := NewStream(ts.URL, nil, 10*time.Millisecond)
+	assert.NoError(t, w.Init())
+
+	_, err := w.ReadFile()
+	assert.Equal(t, ErrNoFeatures, err)
+
+	updates := make(chan []byte, 1)
+	w.Register(func(bts []byte) {
+		updates <- bts
+	})
+
+	go w.Watch()
+	defer w.Close()
+
+	atomic.StoreInt32(&available, 1)
+
+	select {
+	case <-updates:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for features")
+	}
+}
+
+func TestStreamWatcherReconnectBackoff(t *testing.T) {
+	var connects int32
+
+	// answers and closes the stream without sending any events
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&connects, 1)
+	}))
+	defer ts.Close()
+
+	w := NewStream(ts.URL, nil, 20*time.Millisecond)
+	assert.NoError(t, w.Init())
+	w.Register(func(bts []byte) {})
+
+	go w.Watch()
+	time.Sleep(200 * time.Millisecond)
+	w.Close()
+	assert.NotPanics(t, w.Close)
+
+	// 20ms, 40ms, and 80ms waits fit in 200ms rather than a tight loop
+	assert.True(t, atomic.LoadInt32(&connects) <= 5, "%d connects", connects)
+}
+
+func TestStreamWatcherHeartbeat(t *testing.T) {
+	connects := make(chan bool, 4)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		connects <- true
+		w.Header().Set(heartbeatHeader, "10ms")
+		fmt.Fprint(w, "id: a\nevent: features\ndata: {\"dcdr\": {\"info\": {\"current_sha\": \"a\"}, \"features\": {\"bool\": true}}}\n\n")
+		w.(http.Flusher).Flush()
+
+		// stall without sending heartbeats
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+
+	// the server never closes the stream so only the watchdog reconnects
+	w := NewStream(ts.URL, nil, 10*time.Millisecond)
+	assert.NoError(t, w.Init())
+	<-connects
+
+	w.Register(func(bts []byte) {})
+
+	go w.Watch()
+	defer w.Close()
+
+	select {
+	case <-connects:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for reconnect")
+	}
+}
+
+func TestStreamWatcherHeartbeatTimeout(t *testing.T) {
+	w := NewStream("", nil, time.Second)
+	resp := &http.Response{Header: http.Header{}}
+
+	assert.Equal(t, defaultHeartbeat*missedHeartbeats, w.heartbeatTimeout(resp))
+
+	w.WithHeartbeat(time.Minute)
+	assert.Equal(t, time.Minute*missedHeartbeats, w.heartbeatTimeout(resp))
+
+	resp.Header.Set(heartbeatHeader, "5s")
+	assert.Equal(t, 5*time.Second*missedHeartbeats, w.heartbeatTimeout(resp))
+
+	resp.Header.Set(heartbeatHeader, "soon")
+	assert.Equal(t, time.Minute*missedHeartbeats, w.heartbeatTimeout(resp))
+}
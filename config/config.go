@@ -5,6 +5,7 @@ import (
 	"io/ioutil"
 	"os"
 	"os/user"
+	"time"
 
 	"github.com/hashicorp/hcl"
 	"github.com/vsco/dcdr/cli/printer"
@@ -18,9 +19,28 @@ const (
 	envConfigDirOverride = "DCDR_CONFIG_DIR"
 	defaultHost          = ":8000"
 	defaultEndpoint      = "/dcdr.json"
+	defaultPollInterval  = 5 * time.Second
+	defaultHeartbeat     = 15 * time.Second
+	defaultDialTimeout   = 5 * time.Second
+
+	// WatcherTypeFile watches `OutputPath` written by `dcdr watch`.
+	WatcherTypeFile = "file"
+	// WatcherTypeHTTP polls a `dcdr server` at `URL`.
+	WatcherTypeHTTP = "http"
+	// WatcherTypeStream streams events from a `dcdr server` at `URL`.
+	WatcherTypeStream = "stream"
+
+	// StorageFile stores features as files under `File.Path`.
+	StorageFile = "file"
+	// StorageEtcd stores features in etcd v3 at `Etcd.Endpoints`.
+	StorageEtcd = "etcd"
+	// DefaultEtcdEndpoint used when no `Etcd.Endpoints` are configured.
+	DefaultEtcdEndpoint = "127.0.0.1:2379"
 
 	// OutputFileName name used for output path.
 	OutputFileName = "decider.json"
+	// StoreDirName name of the directory used by the file store.
+	StoreDirName = "store"
 	// DefaultInfoNamespace path for the info key.
 	DefaultInfoNamespace = defaultNamespace + "/" + "info"
 )
@@ -38,6 +58,11 @@ func OutputPath() string {
 	return fmt.Sprintf("%s/%s", ConfigDir, OutputFileName)
 }
 
+// StorePath default directory for the file store.
+func StorePath() string {
+	return fmt.Sprintf("%s/%s", ConfigDir, StoreDirName)
+}
+
 // ExampleConfig an example config written by `dcdr init`
 var ExampleConfig = []byte(`
 // Username = "dcdr admin"
@@ -48,13 +73,50 @@ var ExampleConfig = []byte(`
 //	 Address = "127.0.0.1:8500"
 // }
 
+// File {
+//   Path = "/etc/dcdr/store"
+// }
+
+// Etcd {
+//   Endpoints = ["127.0.0.1:2379"]
+//   DialTimeout = "5s"
+// }
+
 // Watcher {
 //   OutputPath = "/etc/dcdr/decider.json"
+//   Type = "file"
+//   URL = "http://127.0.0.1:8000/dcdr.json"
+//   Scopes = ["country-codes/us"]
+//   PollInterval = "5s"
+//   Heartbeat = "15s"
+//   Token = "bearer-token"
 // }
 
 // Server {
 //   JsonRoot = "dcdr"
 //   Endpoint = "/dcdr.json"
+//   WriteAPI = false
+//   CertFile = "/etc/dcdr/tls/server.crt"
+//   KeyFile = "/etc/dcdr/tls/server.key"
+//   ClientCAFile = "/etc/dcdr/tls/ca.crt"
+// }
+
+// Auth {
+//   ClientCerts = true
+//
+//   Tokens {
+//     deploy-bot = "bearer-token"
+//   }
+//
+//   HMACKeys {
+//     ci = "signing-key"
+//   }
+//
+//   Policy "deploy-bot" {
+//     Read = ["*"]
+//     Write = ["user-groups/*"]
+//     Prefixes = ["checkout-"]
+//   }
 // }
 
 // Git {
@@ -68,11 +130,48 @@ var ExampleConfig = []byte(`
 //   Port = 8126
 // }`)
 
-// Server config struct for `dcdr server`
+// Server config struct for `dcdr server`. `WriteAPI` enables
+// setting and deleting features over HTTP and requires `Auth`. Providing `CertFile` and
+// `KeyFile` serves over TLS, verifying client certificates signed
+// by `ClientCAFile` when present.
 type Server struct {
-	Endpoint string
-	Host     string
-	JSONRoot string
+	Endpoint     string
+	Host         string
+	JSONRoot     string
+	WriteAPI     bool
+	CertFile     string
+	KeyFile      string
+	ClientCAFile string
+}
+
+// TLSEnabled checks if the server should serve over TLS.
+func (s Server) TLSEnabled() bool {
+	return s.CertFile != "" && s.KeyFile != ""
+}
+
+// Auth config struct for `dcdr server` authentication. `Tokens` and
+// `HMACKeys` map principals to their bearer token or signing key.
+// `ClientCerts` trusts the CN of verified TLS client certificates.
+type Auth struct {
+	Tokens      map[string]string
+	HMACKeys    map[string]string
+	ClientCerts bool
+	Policies    []Policy `hcl:"Policy"`
+}
+
+// Enabled checks if any authenticators have been configured.
+func (a Auth) Enabled() bool {
+	return len(a.Tokens) > 0 || len(a.HMACKeys) > 0 || a.ClientCerts
+}
+
+// Policy restricts the scopes a `Principal` may `Read` and `Write`
+// to those matching the listed patterns. A trailing `*` matches any
+// suffix. `Prefixes` restricts the feature keys, allowing all when empty.
+type Policy struct {
+	Principal string `hcl:",key"`
+	Read      []string
+	Write     []string
+	Prefixes  []string
 }
 
 // Consul config struct for the consul store. Most of consul
@@ -86,9 +185,69 @@ type Redis struct {
 	Address string
 }
 
-// Watcher config struct for `dcdr watch`
+// Etcd config struct for the etcd v3 store.
+type Etcd struct {
+	Endpoints   []string
+	DialTimeout string
+}
+
+// DialDuration parses `DialTimeout`. Defaults to 5 seconds.
+func (e Etcd) DialDuration() (time.Duration, error) {
+	if e.DialTimeout == "" {
+		return defaultDialTimeout, nil
+	}
+
+	return time.ParseDuration(e.DialTimeout)
+}
+
+// File config struct for the file store. Each key is written to
+// `Path` as a JSON file.
+type File struct {
+	Path string
+}
+
+// Watcher config struct for `dcdr watch` and the `Client` watcher.
+// `Type` selects between watching `OutputPath` and polling or
+// streaming a `dcdr server` at `URL` for the features found in `Scopes`.
+// `Token` is sent as a bearer token to servers requiring authentication.
+// `Heartbeat` is used by stream watchers when the server does not
+// advertise its heartbeat interval.
 type Watcher struct {
-	OutputPath string
+	OutputPath   string
+	Type         string
+	URL          string
+	Scopes       []string
+	PollInterval string
+	Heartbeat    string
+	Token        string
+}
+
+// HTTPEnabled checks if the `Client` should poll a `dcdr server`.
+func (w Watcher) HTTPEnabled() bool {
+	return w.Type == WatcherTypeHTTP
+}
+
+// StreamEnabled checks if the `Client` should stream from a `dcdr server`.
+func (w Watcher) StreamEnabled() bool {
+	return w.Type == WatcherTypeStream
+}
+
+// PollDuration parses `PollInterval`. Defaults to 5 seconds.
+func (w Watcher) PollDuration() (time.Duration, error) {
+	if w.PollInterval == "" {
+		return defaultPollInterval, nil
+	}
+
+	return time.ParseDuration(w.PollInterval)
+}
+
+// HeartbeatDuration parses `Heartbeat`. Defaults to 15 seconds.
+func (w Watcher) HeartbeatDuration() (time.Duration, error) {
+	if w.Heartbeat == "" {
+		return defaultHeartbeat, nil
+	}
+
+	return time.ParseDuration(w.Heartbeat)
 }
 
 // Stats config struct for statsd
@@ -111,10 +270,13 @@ type Config struct {
 	Storage   string
 	Consul    Consul
 	Redis     Redis
+	File      File
+	Etcd      Etcd
 	Watcher   Watcher
 	Git       Git
 	Stats     Stats
 	Server    Server
+	Auth      Auth
 }
 
 // GitEnabled checks if a git repo has been configured.
@@ -157,6 +319,12 @@ func DefaultConfig() *Config {
 		Username:  uname,
 		Namespace: defaultNamespace,
 		Storage:   defaultStorage,
+		File: File{
+			Path: StorePath(),
+		},
+		Etcd: Etcd{
+			Endpoints: []string{DefaultEtcdEndpoint},
+		},
 		Watcher: Watcher{
 			OutputPath: OutputPath(),
 		},
@@ -211,6 +379,14 @@ func readConfig() *Config {
 		cfg.Watcher.OutputPath = defaults.Watcher.OutputPath
 	}
 
+	if cfg.File.Path == "" {
+		cfg.File.Path = defaults.File.Path
+	}
+
+	if len(cfg.Etcd.Endpoints) == 0 {
+		cfg.Etcd.Endpoints = defaults.Etcd.Endpoints
+	}
+
 	if cfg.Server.Host == "" {
 		cfg.Server.Host = defaults.Server.Host
 	}
@@ -3,6 +3,7 @@ package config
 import (
 	"os/user"
 	"testing"
+	"time"
 
 	"io/ioutil"
 
@@ -10,6 +11,8 @@ import (
 
 	"fmt"
 
+	"regexp"
+
 	"github.com/stretchr/testify/assert"
 )
 
@@ -40,6 +43,8 @@ func TestDefaultConfig(t *testing.T) {
 	assert.Equal(t, cfg.Username, user.Username)
 	assert.Equal(t, cfg.Storage, defaultStorage)
 	assert.Equal(t, cfg.Watcher.OutputPath, OutputPath())
+	assert.Equal(t, cfg.File.Path, StorePath())
+	assert.Equal(t, cfg.Etcd.Endpoints, []string{DefaultEtcdEndpoint})
 	assert.Equal(t, cfg.Server.Endpoint, defaultEndpoint)
 	assert.Equal(t, cfg.Server.Host, defaultHost)
 	assert.Equal(t, cfg.Server.JSONRoot, defaultNamespace)
@@ -61,3 +66,123 @@ func TestEnvOverride(t *testing.T) {
 	assert.Equal(t, OutputPath(), fmt.Sprintf("%s/%s", os.Getenv(envConfigDirOverride), OutputFileName))
 	assert.Equal(t, cfg.Watcher.OutputPath, OutputPath())
 }
+
+func TestPollDuration(t *testing.T) {
+	w := Watcher{}
+
+	d, err := w.PollDuration()
+	assert.NoError(t, err)
+	assert.Equal(t, defaultPollInterval, d)
+	assert.False(t, w.HTTPEnabled())
+
+	w.Type = WatcherTypeHTTP
+	w.PollInterval = "1m"
+
+	d, err = w.PollDuration()
+	assert.NoError(t, err)
+	assert.Equal(t, time.Minute, d)
+	assert.True(t, w.HTTPEnabled())
+
+	w.PollInterval = "soon"
+	_, err = w.PollDuration()
+	assert.Error(t, err)
+}
+
+func TestHeartbeatDuration(t *testing.T) {
+	w := Watcher{}
+
+	d, err := w.HeartbeatDuration()
+	assert.NoError(t, err)
+	assert.Equal(t, defaultHeartbeat, d)
+
+	w.Heartbeat = "30s"
+
+	d, err = w.HeartbeatDuration()
+	assert.NoError(t, err)
+	assert.Equal(t, 30*time.Second, d)
+
+	w.Heartbeat = "soon"
+	_, err = w.HeartbeatDuration()
+	assert.Error(t, err)
+}
+
+func TestDialDuration(t *testing.T) {
+	e := Etcd{}
+
+	d, err := e.DialDuration()
+	assert.NoError(t, err)
+	assert.Equal(t, defaultDialTimeout, d)
+
+	e.DialTimeout = "1s"
+
+	d, err = e.DialDuration()
+	assert.NoError(t, err)
+	assert.Equal(t, time.Second, d)
+}
+
+func TestReadAuthConfig(t *testing.T) {
+	dir := TempDir(t)
+	defer os.RemoveAll(dir)
+	defer os.Unsetenv(envConfigDirOverride)
+
+	os.Setenv(envConfigDirOverride, dir)
+
+	hcl := []byte(`
+Auth {
+  ClientCerts = true
+
+  Tokens {
+    deploy-bot = "bearer-token"
+  }
+
+  Policy "deploy-bot" {
+    Read = ["*"]
+    Write = ["user-groups/*"]
+    Prefixes = ["checkout-"]
+  }
+}`)
+
+	err := ioutil.WriteFile(fmt.Sprintf("%s/%s", dir, configFileName), hcl, 0644)
+	assert.NoError(t, err)
+
+	cfg := LoadConfig()
+
+	assert.True(t, cfg.Auth.Enabled())
+	assert.Equal(t, map[string]string{"deploy-bot": "bearer-token"}, cfg.Auth.Tokens)
+	assert.Equal(t, []Policy{{
+		Principal: "deploy-bot",
+		Read:      []string{"*"},
+		Write:     []string{"user-groups/*"},
+		Prefixes:  []string{"checkout-"},
+	}}, cfg.Auth.Policies)
+	assert.False(t, DefaultConfig().Auth.Enabled())
+}
+
+func TestReadExampleConfig(t *testing.T) {
+	dir := TempDir(t)
+	defer os.RemoveAll(dir)
+	defer os.Unsetenv(envConfigDirOverride)
+
+	os.Setenv(envConfigDirOverride, dir)
+
+	uncommented := regexp.MustCompile(`(?m)^// ?`).ReplaceAll(ExampleConfig, nil)
+	err := ioutil.WriteFile(fmt.Sprintf("%s/%s", dir, configFileName), uncommented, 0644)
+	assert.NoError(t, err)
+
+	cfg := LoadConfig()
+
+	assert.Equal(t, "dcdr admin", cfg.Username)
+	assert.Equal(t, "consul", cfg.Storage)
+	assert.Equal(t, "127.0.0.1:8500", cfg.Consul.Address)
+	assert.Equal(t, "/etc/dcdr/store", cfg.File.Path)
+	assert.Equal(t, []string{"127.0.0.1:2379"}, cfg.Etcd.Endpoints)
+	assert.Equal(t, "5s", cfg.Etcd.DialTimeout)
+	assert.Equal(t, []string{"country-codes/us"}, cfg.Watcher.Scopes)
+	assert.Equal(t, "15s", cfg.Watcher.Heartbeat)
+	assert.Equal(t, "bearer-token", cfg.Watcher.Token)
+	assert.Equal(t, "/etc/dcdr/tls/ca.crt", cfg.Server.ClientCAFile)
+	assert.Equal(t, map[string]string{"ci": "signing-key"}, cfg.Auth.HMACKeys)
+	assert.Len(t, cfg.Auth.Policies, 1)
+	assert.Equal(t, "git@github.com:vsco/decider-test-config.git", cfg.Git.RepoURL)
+	assert.Equal(t, 8126, cfg.Stats.Port)
+}
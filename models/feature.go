@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strconv"
+	"strings"
 )
 
 // Features a Feature result set
@@ -21,13 +22,33 @@ const (
 	Percentile FeatureType = "percentile"
 	// Boolean boolean `FeatureType`
 	Boolean FeatureType = "boolean"
+	// String string `FeatureType`
+	String FeatureType = "string"
+	// Integer integer `FeatureType`
+	Integer FeatureType = "integer"
+	// JSON arbitrary JSON object or array `FeatureType`
+	JSON FeatureType = "json"
+	// Variant weighted variant allocation `FeatureType`
+	Variant FeatureType = "variant"
 	// Invalid invalid `FeatureType`
 	Invalid FeatureType = "invalid"
 	// FeatureScope scoping for feature keys
 	FeatureScope = "features"
 )
 
-// ParseValueAndFeatureType string to type helper
+// ParseFeatureType validates a user provided type name. Returns
+// `Invalid` for unknown types.
+func ParseFeatureType(t string) FeatureType {
+	switch ft := FeatureType(strings.ToLower(t)); ft {
+	case Percentile, Boolean, String, Integer, JSON, Variant:
+		return ft
+	default:
+		return Invalid
+	}
+}
+
+// ParseValueAndFeatureType string to type helper. `String` and `Integer`
+// values cannot be inferred and must be parsed with `ParseValueForFeatureType`.
 func ParseValueAndFeatureType(v string) (interface{}, FeatureType) {
 	b, err := strconv.ParseBool(v)
 
@@ -47,9 +68,101 @@ func ParseValueAndFeatureType(v string) (interface{}, FeatureType) {
 		return i, Percentile
 	}
 
+	if j, ok := parseJSON(v); ok {
+		return j, JSON
+	}
+
+	return nil, Invalid
+}
+
+// ParseValueForFeatureType parses `v` as the explicitly provided `ft`.
+// Returns `Invalid` if `v` cannot be represented as `ft`.
+func ParseValueForFeatureType(v string, ft FeatureType) (interface{}, FeatureType) {
+	switch ft {
+	case Percentile:
+		f, err := strconv.ParseFloat(v, 64)
+
+		if err == nil {
+			return f, Percentile
+		}
+	case Boolean:
+		b, err := strconv.ParseBool(v)
+
+		if err == nil {
+			return b, Boolean
+		}
+	case String:
+		return v, String
+	case Integer:
+		i, err := strconv.ParseInt(v, 10, 64)
+
+		if err == nil {
+			return i, Integer
+		}
+	case JSON:
+		if j, ok := parseJSON(v); ok {
+			return j, JSON
+		}
+	case Variant:
+		if wv, ok := ParseWeightedVariants(v); ok {
+			return wv, Variant
+		}
+	}
+
 	return nil, Invalid
 }
 
+// parseJSON only accepts JSON objects and arrays so that scalar
+// values continue to be parsed as percentiles and booleans.
+func parseJSON(v string) (interface{}, bool) {
+	trimmed := strings.TrimSpace(v)
+
+	if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") {
+		return nil, false
+	}
+
+	var j interface{}
+	err := json.Unmarshal([]byte(trimmed), &j)
+
+	if err != nil {
+		return nil, false
+	}
+
+	return j, true
+}
+
+// TypeOfValue infers the `FeatureType` of a decoded `FeatureScopes` value.
+// Whole numbers, including 0 and 1, are considered `Integer`. Prefer the
+// type recorded in the `FeatureMap` with `Root.TypeOf`.
+func TypeOfValue(v interface{}) FeatureType {
+	if tv, ok := TargetedValueFromValue(v); ok {
+		return TypeOfValue(tv.Value)
+	}
+
+	if _, ok := WeightedVariantsFromValue(v); ok {
+		return Variant
+	}
+
+	switch val := v.(type) {
+	case bool:
+		return Boolean
+	case string:
+		return String
+	case int, int64:
+		return Integer
+	case float64:
+		if val == float64(int64(val)) {
+			return Integer
+		}
+
+		return Percentile
+	case map[string]interface{}, []interface{}:
+		return JSON
+	default:
+		return Invalid
+	}
+}
+
 // Feature KV model for feature flags
 type Feature struct {
 	FeatureType FeatureType `json:"feature_type"`
@@ -59,6 +172,8 @@ type Feature struct {
 	Value       interface{} `json:"value"`
 	Comment     string      `json:"comment"`
 	UpdatedBy   string      `json:"updated_by"`
+	Rules       Rules       `json:"rules,omitempty"`
+	Metadata
 }
 
 // GetScope scope accessor
@@ -89,6 +204,14 @@ func NewFeature(name string, value interface{}, comment string, user string, sco
 		ft = Percentile
 	case bool:
 		ft = Boolean
+	case string:
+		ft = String
+	case int, int64:
+		ft = Integer
+	case map[string]interface{}, []interface{}:
+		ft = JSON
+	case WeightedVariants:
+		ft = Variant
 	}
 
 	f = &Feature{
@@ -114,6 +237,43 @@ func (f *Feature) BoolValue() bool {
 	return f.Value.(bool)
 }
 
+// StringValue cast Value to string
+func (f *Feature) StringValue() string {
+	return f.Value.(string)
+}
+
+// IntValue cast Value to int64. Integers decoded from JSON
+// are represented as float64 and are converted.
+func (f *Feature) IntValue() int64 {
+	switch v := f.Value.(type) {
+	case float64:
+		return int64(v)
+	case int:
+		return int64(v)
+	default:
+		return f.Value.(int64)
+	}
+}
+
+// Targeted checks if the feature has targeting `Rules`.
+func (f *Feature) Targeted() bool {
+	return len(f.Rules) > 0
+}
+
+// MapValue the value of the feature as compared between sources.
+// Targeted features are wrapped in a `TargetedValue`. The `FeatureMap`
+// holds the plain `Value` and records rules separately.
+func (f *Feature) MapValue() interface{} {
+	if f.Targeted() {
+		return TargetedValue{
+			Rules: f.Rules,
+			Value: f.Value,
+		}
+	}
+
+	return f.Value
+}
+
 // ToJSON marshal feature to json
 func (f *Feature) ToJSON() ([]byte, error) {
 	return json.Marshal(f)
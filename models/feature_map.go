@@ -2,6 +2,7 @@ package models
 
 import (
 	"encoding/json"
+	"reflect"
 	"strings"
 	"sync"
 )
@@ -21,14 +22,157 @@ type Info struct {
 	LastModifiedDate int64  `json:"last_modfied_date,omitempty"`
 }
 
-// FeatureScopes the map of percentile and boolean K/Vs.
+// FeatureScopes the map of feature K/Vs nested by scope.
 type FeatureScopes map[string]interface{}
 
-// Root wrapper struct for `Info` and `Features`.
+// Diff compares `fs` to `next` returning the previous values of changed
+// or removed keys and the next values of changed or added keys.
+func (fs FeatureScopes) Diff(next FeatureScopes) (FeatureScopes, FeatureScopes) {
+	prev := make(FeatureScopes)
+	changed := make(FeatureScopes)
+
+	for k, v := range fs {
+		if nv, ok := next[k]; !ok || !reflect.DeepEqual(v, nv) {
+			prev[k] = v
+		}
+	}
+
+	for k, v := range next {
+		if pv, ok := fs[k]; !ok || !reflect.DeepEqual(pv, v) {
+			changed[k] = v
+		}
+	}
+
+	return prev, changed
+}
+
+// Root wrapper struct for `Info` and `Features`. `Types`, `Rules` and
+// `Metadata` hold the type, targeting rules and metadata of each feature
+// keyed by "<scope>/<name>". Features always hold their plain value so
+// readers unaware of rules still find it.
 type Root struct {
 	sync.RWMutex
-	Info          *Info         `json:"info"`
-	FeatureScopes FeatureScopes `json:"features"`
+	Info          *Info                  `json:"info"`
+	FeatureScopes FeatureScopes          `json:"features"`
+	Types         map[string]FeatureType `json:"types,omitempty"`
+	Rules         map[string]Rules       `json:"rules,omitempty"`
+	Metadata      map[string]Metadata    `json:"metadata,omitempty"`
+}
+
+// AddType records the `FeatureType` of the feature at `path`.
+func (d *Root) AddType(path string, t FeatureType) {
+	if t == "" {
+		return
+	}
+
+	if d.Types == nil {
+		d.Types = make(map[string]FeatureType)
+	}
+
+	d.Types[path] = t
+}
+
+// AddRules records the targeting `rules` of the feature at `path`
+// unless there are none.
+func (d *Root) AddRules(path string, rules Rules) {
+	if len(rules) == 0 {
+		return
+	}
+
+	if d.Rules == nil {
+		d.Rules = make(map[string]Rules)
+	}
+
+	d.Rules[path] = rules
+}
+
+// TargetedValue wraps `v` in a `TargetedValue` when the feature at
+// `path` has rules so that values and rules can be compared together.
+func (d *Root) TargetedValue(path string, v interface{}) interface{} {
+	if rules, ok := d.Rules[path]; ok {
+		return TargetedValue{Rules: rules, Value: v}
+	}
+
+	return v
+}
+
+// TypeOf returns the recorded `FeatureType` of the feature at `path`
+// falling back to `TypeOfValue`. Maps written before types were recorded
+// treat 0 and 1 as percentiles as earlier versions did.
+func (d *Root) TypeOf(path string, v interface{}) FeatureType {
+	if t, ok := d.Types[path]; ok {
+		return t
+	}
+
+	t := TypeOfValue(v)
+
+	if d.Types == nil && t == Integer && (v == 0.0 || v == 1.0) {
+		return Percentile
+	}
+
+	return t
+}
+
+// MergedTypes returns the `FeatureType` of each feature merged by
+// `MergedScopes` keyed by the feature name.
+func (d *Root) MergedTypes(scopes ...string) map[string]FeatureType {
+	if d.Types == nil {
+		return nil
+	}
+
+	types := make(map[string]FeatureType)
+
+	for k, path := range d.mergedPaths(scopes...) {
+		if t, ok := d.Types[path]; ok {
+			types[k] = t
+		}
+	}
+
+	return types
+}
+
+// MergedRules returns the `Rules` of each feature merged by
+// `MergedScopes` keyed by the feature name.
+func (d *Root) MergedRules(scopes ...string) map[string]Rules {
+	if d.Rules == nil {
+		return nil
+	}
+
+	rules := make(map[string]Rules)
+
+	for k, path := range d.mergedPaths(scopes...) {
+		if r, ok := d.Rules[path]; ok {
+			rules[k] = r
+		}
+	}
+
+	return rules
+}
+
+// mergedPaths maps each feature merged by `MergedScopes` to the
+// "<scope>/<name>" path it is resolved from.
+func (d *Root) mergedPaths(scopes ...string) map[string]string {
+	paths := make(map[string]string)
+
+	for k := range d.MergedScopes(scopes...) {
+		scope, _ := d.ResolveScope(k, scopes...)
+		paths[k] = scope + "/" + k
+	}
+
+	return paths
+}
+
+// AddMetadata records `md` for the feature at `path` unless it is empty.
+func (d *Root) AddMetadata(path string, md Metadata) {
+	if md.Empty() {
+		return
+	}
+
+	if d.Metadata == nil {
+		d.Metadata = make(map[string]Metadata)
+	}
+
+	d.Metadata[path] = md
 }
 
 // EmptyFeatureMap helper method for constructing an empty `FeatureMap`.
@@ -79,11 +223,13 @@ func (d *Root) InScope(scope string) FeatureScopes {
 
 	top := d.FeatureScopes
 	for _, s := range scopes {
-		if m, ok := top[s]; ok {
-			top = m.(map[string]interface{})
-		} else {
+		m, ok := top[s].(map[string]interface{})
+
+		if !ok {
 			return make(map[string]interface{})
 		}
+
+		top = m
 	}
 
 	return top
@@ -114,6 +260,73 @@ func (d *Root) MergedScopes(scopes ...string) FeatureScopes {
 	return mrg
 }
 
+// ResolveScope given a slice of scopes in priority order returns the
+// scope that `feature` is resolved from by `MergedScopes`.
+func (d *Root) ResolveScope(feature string, scopes ...string) (string, bool) {
+	scopes = append(scopes, DefaultScope)
+
+	for _, scope := range scopes {
+		if scope == "" {
+			continue
+		}
+
+		if _, ok := d.InScope(scope)[feature]; ok {
+			return scope, true
+		}
+	}
+
+	return "", false
+}
+
+// SelectScopes returns a `FeatureMap` holding the features in `scopes`
+// and the 'default' scope without merging them, along with their types
+// and rules. Nested scopes are only included when requested.
+func (d *Root) SelectScopes(scopes ...string) *FeatureMap {
+	fm := EmptyFeatureMap()
+	fm.Dcdr.Info = d.Info
+
+	for _, scope := range append(scopes, DefaultScope) {
+		if scope == "" {
+			continue
+		}
+
+		selected := fm.Dcdr.FeatureScopes.scope(scope)
+
+		for k, v := range d.InScope(scope) {
+			path := scope + "/" + k
+			_, typed := d.Types[path]
+
+			if _, nested := v.(map[string]interface{}); nested && !typed {
+				continue
+			}
+
+			selected[k] = v
+			fm.Dcdr.AddType(path, d.Types[path])
+			fm.Dcdr.AddRules(path, d.Rules[path])
+		}
+	}
+
+	return fm
+}
+
+// scope returns the map for `scope` creating any missing parents.
+func (fs FeatureScopes) scope(scope string) map[string]interface{} {
+	top := map[string]interface{}(fs)
+
+	for _, s := range strings.Split(scope, "/") {
+		m, ok := top[s].(map[string]interface{})
+
+		if !ok {
+			m = make(map[string]interface{})
+			top[s] = m
+		}
+
+		top = m
+	}
+
+	return top
+}
+
 func rev(a []string) {
 	for i := len(a)/2 - 1; i >= 0; i-- {
 		opp := len(a) - 1 - i
@@ -96,3 +96,94 @@ func TestToJson(t *testing.T) {
 
 	assert.Equal(t, FixtureBytes(), bts)
 }
+
+func TestResolveScope(t *testing.T) {
+	fm := FixtureMap()
+
+	scope, ok := fm.Dcdr.ResolveScope("bool", "cc/cn", "ab")
+	assert.True(t, ok)
+	assert.Equal(t, "cc/cn", scope)
+
+	scope, ok = fm.Dcdr.ResolveScope("default_float", "cc/cn", "ab")
+	assert.True(t, ok)
+	assert.Equal(t, DefaultScope, scope)
+
+	_, ok = fm.Dcdr.ResolveScope("nope", "cc/cn")
+	assert.False(t, ok)
+}
+
+func TestDiff(t *testing.T) {
+	prev := FeatureScopes{"a": true, "b": 0.5, "c": []interface{}{"x"}, "d": "same"}
+	next := FeatureScopes{"b": 0.6, "c": []interface{}{"x"}, "d": "same", "e": false}
+
+	old, new := prev.Diff(next)
+
+	assert.Equal(t, FeatureScopes{"a": true, "b": 0.5}, old)
+	assert.Equal(t, FeatureScopes{"b": 0.6, "e": false}, new)
+}
+
+func TestTypeOf(t *testing.T) {
+	var legacy Root
+	assert.Equal(t, Percentile, legacy.TypeOf("default/a", 1.0))
+	assert.Equal(t, Percentile, legacy.TypeOf("default/a", 0.0))
+	assert.Equal(t, Integer, legacy.TypeOf("default/a", 25.0))
+
+	var d Root
+	d.AddType("default/count", Integer)
+	d.AddType("default/rollout", Percentile)
+	assert.Equal(t, Integer, d.TypeOf("default/count", 1.0))
+	assert.Equal(t, Percentile, d.TypeOf("default/rollout", 1.0))
+	assert.Equal(t, Integer, d.TypeOf("default/other", 0.0))
+}
+
+func TestMergedTypes(t *testing.T) {
+	fm := EmptyFeatureMap()
+	fm.Dcdr.FeatureScopes = FeatureScopes{
+		DefaultScope: map[string]interface{}{"a": 1.0, "b": true},
+		"beta":       map[string]interface{}{"a": 0.5},
+	}
+	fm.Dcdr.AddType("default/a", Integer)
+	fm.Dcdr.AddType("default/b", Boolean)
+	fm.Dcdr.AddType("beta/a", Percentile)
+
+	assert.Equal(t, map[string]FeatureType{"a": Percentile, "b": Boolean}, fm.Dcdr.MergedTypes("beta"))
+	assert.Equal(t, map[string]FeatureType{"a": Integer, "b": Boolean}, fm.Dcdr.MergedTypes())
+}
+
+func TestSelectScopes(t *testing.T) {
+	fm := EmptyFeatureMap()
+	fm.Dcdr.Info.CurrentSHA = "abcde"
+	fm.Dcdr.FeatureScopes = FeatureScopes{
+		DefaultScope: map[string]interface{}{"a": 1.0, "cfg": map[string]interface{}{"b": true}},
+		"cc": map[string]interface{}{
+			"a":  0.5,
+			"cn": map[string]interface{}{"a": 0.25},
+			"us": map[string]interface{}{"a": 0.75},
+		},
+	}
+	fm.Dcdr.AddType("default/a", Integer)
+	fm.Dcdr.AddType("default/cfg", JSON)
+	fm.Dcdr.AddType("cc/a", Percentile)
+	fm.Dcdr.AddType("cc/cn/a", Percentile)
+	fm.Dcdr.AddType("cc/us/a", Percentile)
+	fm.Dcdr.AddRules("cc/cn/a", Rules{{Attribute: "city", Operator: In, Values: []string{"sh"}}})
+
+	selected := fm.Dcdr.SelectScopes("cc/cn", "cc")
+
+	assert.Equal(t, "abcde", selected.Dcdr.CurrentSHA())
+	assert.Equal(t, FeatureScopes{
+		DefaultScope: map[string]interface{}{"a": 1.0, "cfg": map[string]interface{}{"b": true}},
+		"cc": map[string]interface{}{
+			"a":  0.5,
+			"cn": map[string]interface{}{"a": 0.25},
+		},
+	}, selected.Dcdr.FeatureScopes)
+	assert.Equal(t, map[string]FeatureType{
+		"default/a":   Integer,
+		"default/cfg": JSON,
+		"cc/a":        Percentile,
+		"cc/cn/a":     Percentile,
+	}, selected.Dcdr.Types)
+	assert.Equal(t, fm.Dcdr.Rules, selected.Dcdr.Rules)
+	assert.Equal(t, fm.Dcdr.MergedScopes("cc/cn"), selected.Dcdr.MergedScopes("cc/cn"))
+}
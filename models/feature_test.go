@@ -16,6 +16,51 @@ func TestGetFeatureTypeFromValue(t *testing.T) {
 	}
 }
 
+func TestParseValueAndFeatureTypeJSON(t *testing.T) {
+	v, ft := ParseValueAndFeatureType(`{"a": [1, 2]}`)
+	assert.Equal(t, JSON, ft)
+	assert.Equal(t, map[string]interface{}{"a": []interface{}{float64(1), float64(2)}}, v)
+
+	_, ft = ParseValueAndFeatureType("variant-a")
+	assert.Equal(t, Invalid, ft)
+}
+
+func TestParseValueForFeatureType(t *testing.T) {
+	v, ft := ParseValueForFeatureType("variant-a", String)
+	assert.Equal(t, String, ft)
+	assert.Equal(t, "variant-a", v)
+
+	v, ft = ParseValueForFeatureType("25", Integer)
+	assert.Equal(t, Integer, ft)
+	assert.Equal(t, int64(25), v)
+
+	_, ft = ParseValueForFeatureType("2.5", Integer)
+	assert.Equal(t, Invalid, ft)
+
+	v, ft = ParseValueForFeatureType("[1]", JSON)
+	assert.Equal(t, JSON, ft)
+	assert.Equal(t, []interface{}{float64(1)}, v)
+
+	_, ft = ParseValueForFeatureType("{", JSON)
+	assert.Equal(t, Invalid, ft)
+
+	assert.Equal(t, Integer, ParseFeatureType("Integer"))
+	assert.Equal(t, Invalid, ParseFeatureType("float"))
+}
+
+func TestTypeOfValue(t *testing.T) {
+	assert.Equal(t, Boolean, TypeOfValue(true))
+	assert.Equal(t, Percentile, TypeOfValue(0.5))
+	assert.Equal(t, Integer, TypeOfValue(float64(1)))
+	assert.Equal(t, Integer, TypeOfValue(float64(0)))
+	assert.Equal(t, Integer, TypeOfValue(float64(25)))
+	assert.Equal(t, String, TypeOfValue("a"))
+	assert.Equal(t, JSON, TypeOfValue(map[string]interface{}{"a": 1.0}))
+	assert.Equal(t, Variant, TypeOfValue(WeightedVariants{{"a", 1}}))
+	assert.Equal(t, Boolean, TypeOfValue(TargetedValue{Value: true}))
+	assert.Equal(t, Invalid, TypeOfValue(nil))
+}
+
 func TestMarshaling(t *testing.T) {
 	f := &Feature{
 		Key:         "test",
@@ -40,4 +85,15 @@ func TestTypes(t *testing.T) {
 	pf = NewFeature("key", true, "comment", "user", "scope", "n")
 	assert.Equal(t, Boolean, pf.FeatureType)
 	assert.Equal(t, true, pf.BoolValue())
+
+	pf = NewFeature("key", "a", "comment", "user", "scope", "n")
+	assert.Equal(t, String, pf.FeatureType)
+	assert.Equal(t, "a", pf.StringValue())
+
+	pf = NewFeature("key", int64(3), "comment", "user", "scope", "n")
+	assert.Equal(t, Integer, pf.FeatureType)
+	assert.Equal(t, int64(3), pf.IntValue())
+
+	pf = NewFeature("key", map[string]interface{}{}, "comment", "user", "scope", "n")
+	assert.Equal(t, JSON, pf.FeatureType)
 }
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ExpiryDateFormat the date format accepted by `ParseExpiry`.
+const ExpiryDateFormat = "2006-01-02"
+
+// ErrInvalidExpiry returned for expiry dates that cannot be parsed.
+var ErrInvalidExpiry = errors.New("invalid expiry. use YYYY-MM-DD or RFC3339")
+
+// Metadata ownership and lifecycle details of a `Feature`.
+// `CreatedAt` and `UpdatedAt` are maintained by `api.Client.Set`,
+// which keeps an empty `Owner` or `ExpiresAt` from the existing
+// feature unless `ClearOwner` or `ClearExpiry` is set.
+type Metadata struct {
+	Owner       string     `json:"owner,omitempty"`
+	Tags        []string   `json:"tags,omitempty"`
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	ClearOwner  bool       `json:"-"`
+	ClearExpiry bool       `json:"-"`
+}
+
+// Empty checks if no metadata has been set.
+func (m Metadata) Empty() bool {
+	return m.Owner == "" && len(m.Tags) == 0 &&
+		m.CreatedAt == nil && m.UpdatedAt == nil && m.ExpiresAt == nil
+}
+
+// HasTag checks if `tag` is one of `Tags`.
+func (m Metadata) HasTag(tag string) bool {
+	for _, t := range m.Tags {
+		if t == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Expired checks if `ExpiresAt` is set and before `now`.
+func (m Metadata) Expired(now time.Time) bool {
+	return m.ExpiresAt != nil && m.ExpiresAt.Before(now)
+}
+
+// ParseTags splits comma separated tags, dropping blanks and duplicates.
+func ParseTags(s string) []string {
+	tags := []string{}
+	seen := make(map[string]bool)
+
+	for _, t := range strings.Split(s, ",") {
+		t = strings.TrimSpace(t)
+
+		if t == "" || seen[t] {
+			continue
+		}
+
+		seen[t] = true
+		tags = append(tags, t)
+	}
+
+	sort.Strings(tags)
+
+	return tags
+}
+
+// ParseExpiry parses a date formatted as YYYY-MM-DD, which expires at
+// the start of that day in UTC, or an RFC3339 timestamp.
+func ParseExpiry(s string) (time.Time, error) {
+	if t, err := time.Parse(ExpiryDateFormat, s); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+
+	if err != nil {
+		return time.Time{}, ErrInvalidExpiry
+	}
+
+	return t.UTC(), nil
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTags(t *testing.T) {
+	assert.Equal(t, []string{"checkout", "experiment"}, ParseTags(" experiment, checkout,,experiment "))
+	assert.Equal(t, []string{}, ParseTags(""))
+}
+
+func TestParseExpiry(t *testing.T) {
+	exp, err := ParseExpiry("2026-03-01")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), exp)
+
+	exp, err = ParseExpiry("2026-03-01T12:00:00-08:00")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 3, 1, 20, 0, 0, 0, time.UTC), exp)
+
+	_, err = ParseExpiry("next week")
+	assert.Equal(t, ErrInvalidExpiry, err)
+}
+
+func TestMetadata(t *testing.T) {
+	exp := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	md := Metadata{Owner: "growth", Tags: []string{"checkout"}, ExpiresAt: &exp}
+
+	assert.False(t, md.Empty())
+	assert.True(t, Metadata{}.Empty())
+	assert.True(t, md.HasTag("checkout"))
+	assert.False(t, md.HasTag("search"))
+	assert.True(t, md.Expired(exp.Add(time.Hour)))
+	assert.False(t, md.Expired(exp.Add(-time.Hour)))
+	assert.False(t, Metadata{}.Expired(exp))
+}
+
+func TestFeatureMetadataJSON(t *testing.T) {
+	exp := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	f := NewFeature("key", true, "", "", "", "n")
+	f.Owner = "growth"
+	f.ExpiresAt = &exp
+
+	bts, err := f.ToJSON()
+	assert.NoError(t, err)
+
+	var m map[string]interface{}
+	json.Unmarshal(bts, &m)
+
+	assert.Equal(t, "growth", m["owner"])
+	assert.Equal(t, "2026-03-01T00:00:00Z", m["expires_at"])
+	assert.NotContains(t, m, "tags")
+	assert.NotContains(t, m, "created_at")
+
+	var decoded Feature
+	err = json.Unmarshal(bts, &decoded)
+	assert.NoError(t, err)
+	assert.Equal(t, f.Metadata, decoded.Metadata)
+}
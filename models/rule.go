@@ -0,0 +1,181 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Operator comparison used by a targeting `Rule`.
+type Operator string
+
+const (
+	// In attribute matches any of the rule values
+	In Operator = "in"
+	// NotIn attribute matches none of the rule values
+	NotIn Operator = "not_in"
+	// Eq attribute equals the rule value
+	Eq Operator = "=="
+	// Neq attribute does not equal the rule value
+	Neq Operator = "!="
+	// Gt attribute is greater than the rule value
+	Gt Operator = ">"
+	// Gte attribute is greater than or equal to the rule value
+	Gte Operator = ">="
+	// Lt attribute is less than the rule value
+	Lt Operator = "<"
+	// Lte attribute is less than or equal to the rule value
+	Lte Operator = "<="
+	// InSegment attribute is found within the JSON array stored
+	// in any of the features named by the rule values
+	InSegment Operator = "segment"
+)
+
+var operators = []Operator{In, NotIn, Eq, Neq, Gt, Gte, Lt, Lte, InSegment}
+
+// ErrInvalidRule returned when a rule cannot be parsed.
+var ErrInvalidRule = errors.New("invalid rule. use '<attribute> <in|not_in|==|!=|>|>=|<|<=|segment> <value>[,<value>]'")
+
+// Rule a single targeting condition evaluated against an attribute
+// from the client's evaluation context.
+//
+// country in us,ca => Rule{"country", In, []string{"us", "ca"}}
+type Rule struct {
+	Attribute string   `json:"attribute"`
+	Operator  Operator `json:"operator"`
+	Values    []string `json:"values"`
+}
+
+// Rules a set of `Rule` that must all match.
+type Rules []Rule
+
+// TargetedValue a value along with the `Rules` that must all match for
+// clients to consider it.
+type TargetedValue struct {
+	Rules Rules       `json:"rules"`
+	Value interface{} `json:"value"`
+}
+
+// ParseRules parses a semicolon delimited list of rules.
+//
+// "country in us,ca; app_version >= 5.2" => Rules{...}
+func ParseRules(v string) (Rules, error) {
+	rules := make(Rules, 0)
+
+	for _, pt := range strings.Split(v, ";") {
+		if strings.TrimSpace(pt) == "" {
+			continue
+		}
+
+		r, err := ParseRule(pt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		rules = append(rules, *r)
+	}
+
+	return rules, nil
+}
+
+// ParseRule parses a single rule formatted as "<attribute> <operator> <values>".
+func ParseRule(v string) (*Rule, error) {
+	fields := strings.Fields(v)
+
+	if len(fields) < 3 {
+		return nil, ErrInvalidRule
+	}
+
+	op := Operator(fields[1])
+
+	if !op.Valid() {
+		return nil, ErrInvalidRule
+	}
+
+	values := make([]string, 0)
+
+	for _, val := range strings.Split(strings.Join(fields[2:], " "), ",") {
+		if val = strings.TrimSpace(val); val != "" {
+			values = append(values, val)
+		}
+	}
+
+	if len(values) == 0 {
+		return nil, ErrInvalidRule
+	}
+
+	return &Rule{
+		Attribute: fields[0],
+		Operator:  op,
+		Values:    values,
+	}, nil
+}
+
+// Valid checks `o` against the supported operators.
+func (o Operator) Valid() bool {
+	for _, op := range operators {
+		if o == op {
+			return true
+		}
+	}
+
+	return false
+}
+
+// String formats a rule as it would be provided to `ParseRule`.
+func (r Rule) String() string {
+	return fmt.Sprintf("%s %s %s", r.Attribute, r.Operator, strings.Join(r.Values, ","))
+}
+
+// String formats rules as they would be provided to `ParseRules`.
+func (rs Rules) String() string {
+	strs := make([]string, len(rs))
+
+	for i, r := range rs {
+		strs[i] = r.String()
+	}
+
+	return strings.Join(strs, "; ")
+}
+
+// TargetedValueFromValue converts a decoded `FeatureScopes` value
+// into a `TargetedValue`. Returns false for untargeted values.
+func TargetedValueFromValue(v interface{}) (*TargetedValue, bool) {
+	switch val := v.(type) {
+	case TargetedValue:
+		return &val, true
+	case *TargetedValue:
+		return val, val != nil
+	case map[string]interface{}:
+		if len(val) != 2 {
+			return nil, false
+		}
+
+		if _, ok := val["rules"].([]interface{}); !ok {
+			return nil, false
+		}
+
+		if _, ok := val["value"]; !ok {
+			return nil, false
+		}
+
+		bts, err := json.Marshal(val)
+
+		if err != nil {
+			return nil, false
+		}
+
+		var tv TargetedValue
+		err = json.Unmarshal(bts, &tv)
+
+		if err != nil {
+			return nil, false
+		}
+
+		return &tv, true
+	default:
+		return nil, false
+	}
+}
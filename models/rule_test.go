@@ -0,0 +1,49 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules("country in us, ca; app_version >= 5.2;")
+
+	assert.NoError(t, err)
+	assert.Equal(t, Rules{
+		{Attribute: "country", Operator: In, Values: []string{"us", "ca"}},
+		{Attribute: "app_version", Operator: Gte, Values: []string{"5.2"}},
+	}, rules)
+	assert.Equal(t, "country in us,ca; app_version >= 5.2", rules.String())
+
+	invalid := []string{"country", "country in", "country like us", "country in ,"}
+
+	for _, v := range invalid {
+		_, err := ParseRules(v)
+		assert.Equal(t, ErrInvalidRule, err, v)
+	}
+}
+
+func TestTargetedValueFromValue(t *testing.T) {
+	f := NewFeature("key", true, "", "", "", "n")
+	f.Rules = Rules{{Attribute: "country", Operator: In, Values: []string{"us"}}}
+
+	bts, err := json.Marshal(map[string]interface{}{"key": f.MapValue()})
+	assert.NoError(t, err)
+
+	var m map[string]interface{}
+	err = json.Unmarshal(bts, &m)
+	assert.NoError(t, err)
+
+	tv, ok := TargetedValueFromValue(m["key"])
+	assert.True(t, ok)
+	assert.Equal(t, f.Rules, tv.Rules)
+	assert.Equal(t, true, tv.Value)
+
+	_, ok = TargetedValueFromValue(true)
+	assert.False(t, ok)
+
+	_, ok = TargetedValueFromValue(map[string]interface{}{"value": 1.0, "other": 1.0})
+	assert.False(t, ok)
+}
@@ -0,0 +1,110 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// ScheduleScope scoping for schedule keys
+const ScheduleScope = "schedules"
+
+// ErrInvalidRamp returned for ramps without a positive duration and interval.
+var ErrInvalidRamp = errors.New("ramps require a positive duration and interval")
+
+// ScheduleStep a value to set a feature to once `At` has passed.
+type ScheduleStep struct {
+	At    time.Time   `json:"at"`
+	Value interface{} `json:"value"`
+}
+
+// Schedule planned changes to the value of a feature. Schedules are
+// stored next to the feature they change and applied by `dcdr scheduler`.
+type Schedule struct {
+	Key       string         `json:"key"`
+	Scope     string         `json:"scope"`
+	Namespace string         `json:"namespace"`
+	Steps     []ScheduleStep `json:"steps"`
+	CreatedBy string         `json:"created_by"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// NewSchedule creates a `Schedule` with `steps` sorted by time.
+func NewSchedule(name string, scope string, ns string, user string, steps []ScheduleStep) *Schedule {
+	s := &Schedule{
+		Key:       name,
+		Scope:     scope,
+		Namespace: ns,
+		Steps:     steps,
+		CreatedBy: user,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	sort.SliceStable(s.Steps, func(i, j int) bool {
+		return s.Steps[i].At.Before(s.Steps[j].At)
+	})
+
+	return s
+}
+
+// GetScope helper for accessing the schedule's scope.
+func (s *Schedule) GetScope() string {
+	if s.Scope == "" {
+		s.Scope = DefaultScope
+	}
+
+	return s.Scope
+}
+
+// ScopedKey expanded key with namespace and scope
+func (s *Schedule) ScopedKey() string {
+	return fmt.Sprintf("%s/%s/%s/%s", s.Namespace, ScheduleScope, s.GetScope(), s.Key)
+}
+
+// FeatureKey the scoped key of the feature changed by the schedule.
+func (s *Schedule) FeatureKey() string {
+	return fmt.Sprintf("%s/%s/%s/%s", s.Namespace, FeatureScope, s.GetScope(), s.Key)
+}
+
+// Due returns the latest step at or before `now` and the steps after
+// it. Earlier due steps are skipped since only the latest value matters.
+func (s *Schedule) Due(now time.Time) (*ScheduleStep, []ScheduleStep) {
+	i := sort.Search(len(s.Steps), func(i int) bool {
+		return s.Steps[i].At.After(now)
+	})
+
+	if i == 0 {
+		return nil, s.Steps
+	}
+
+	return &s.Steps[i-1], s.Steps[i:]
+}
+
+// Next returns the first pending step or nil when the schedule is done.
+func (s *Schedule) Next() *ScheduleStep {
+	if len(s.Steps) == 0 {
+		return nil
+	}
+
+	return &s.Steps[0]
+}
+
+// Ramp returns steps moving a percentile linearly from `from` to `to`
+// starting at `start`, once every `every` for `over`. The last step
+// sets `to` at `start` plus `over`.
+func Ramp(from float64, to float64, start time.Time, over time.Duration, every time.Duration) ([]ScheduleStep, error) {
+	if over <= 0 || every <= 0 {
+		return nil, ErrInvalidRamp
+	}
+
+	var steps []ScheduleStep
+
+	for d := time.Duration(0); d < over; d += every {
+		v := from + (to-from)*float64(d)/float64(over)
+		steps = append(steps, ScheduleStep{At: start.Add(d), Value: math.Round(v*10000) / 10000})
+	}
+
+	return append(steps, ScheduleStep{At: start.Add(over), Value: to}), nil
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduleKeys(t *testing.T) {
+	s := NewSchedule("rollout", "", "dcdr", "alice", nil)
+
+	assert.Equal(t, "dcdr/schedules/default/rollout", s.ScopedKey())
+	assert.Equal(t, "dcdr/features/default/rollout", s.FeatureKey())
+}
+
+func TestScheduleDue(t *testing.T) {
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	s := NewSchedule("rollout", "", "dcdr", "alice", []ScheduleStep{
+		{At: start.Add(2 * time.Hour), Value: 0.5},
+		{At: start, Value: 0.1},
+		{At: start.Add(time.Hour), Value: 0.2},
+	})
+
+	assert.Equal(t, 0.1, s.Next().Value)
+
+	step, rest := s.Due(start.Add(-time.Minute))
+	assert.Nil(t, step)
+	assert.Len(t, rest, 3)
+
+	step, rest = s.Due(start.Add(90 * time.Minute))
+	assert.Equal(t, 0.2, step.Value)
+	assert.Equal(t, []ScheduleStep{{At: start.Add(2 * time.Hour), Value: 0.5}}, rest)
+
+	step, rest = s.Due(start.Add(2 * time.Hour))
+	assert.Equal(t, 0.5, step.Value)
+	assert.Empty(t, rest)
+}
+
+func TestRamp(t *testing.T) {
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	steps, err := Ramp(0, 1, start, 7*24*time.Hour, 48*time.Hour)
+
+	assert.NoError(t, err)
+	assert.Len(t, steps, 5)
+	assert.Equal(t, ScheduleStep{At: start, Value: 0.0}, steps[0])
+	assert.Equal(t, ScheduleStep{At: start.Add(48 * time.Hour), Value: 0.2857}, steps[1])
+	assert.Equal(t, ScheduleStep{At: start.Add(7 * 24 * time.Hour), Value: 1.0}, steps[4])
+
+	_, err = Ramp(0, 1, start, 0, time.Hour)
+	assert.Equal(t, ErrInvalidRamp, err)
+
+	_, err = Ramp(0, 1, start, time.Hour, 0)
+	assert.Equal(t, ErrInvalidRamp, err)
+}
@@ -0,0 +1,154 @@
+package models
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// WeightedVariant a named variant and its relative weight.
+type WeightedVariant struct {
+	Name   string  `json:"name"`
+	Weight float64 `json:"weight"`
+}
+
+// WeightedVariants the value of a `Variant` feature. Weights are relative
+// to the total weight of all variants.
+type WeightedVariants []WeightedVariant
+
+// ParseWeightedVariants parses a variant spec formatted as
+// "name:weight,name:weight" or a JSON array of `WeightedVariant`.
+//
+// "control:50,treatment:50" => [{control 50} {treatment 50}]
+func ParseWeightedVariants(v string) (WeightedVariants, bool) {
+	var wv WeightedVariants
+	trimmed := strings.TrimSpace(v)
+
+	if strings.HasPrefix(trimmed, "[") {
+		err := json.Unmarshal([]byte(trimmed), &wv)
+
+		if err != nil {
+			return nil, false
+		}
+
+		return wv, wv.Valid()
+	}
+
+	for _, pt := range strings.Split(trimmed, ",") {
+		kv := strings.SplitN(strings.TrimSpace(pt), ":", 2)
+
+		if len(kv) != 2 {
+			return nil, false
+		}
+
+		w, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64)
+
+		if err != nil {
+			return nil, false
+		}
+
+		wv = append(wv, WeightedVariant{
+			Name:   strings.TrimSpace(kv[0]),
+			Weight: w,
+		})
+	}
+
+	return wv, wv.Valid()
+}
+
+// WeightedVariantsFromValue converts a decoded `FeatureScopes` value
+// into `WeightedVariants`.
+func WeightedVariantsFromValue(v interface{}) (WeightedVariants, bool) {
+	switch val := v.(type) {
+	case WeightedVariants:
+		return val, val.Valid()
+	case []interface{}:
+		wv := make(WeightedVariants, 0, len(val))
+
+		for _, i := range val {
+			m, ok := i.(map[string]interface{})
+
+			if !ok {
+				return nil, false
+			}
+
+			name, _ := m["name"].(string)
+			weight, _ := m["weight"].(float64)
+
+			wv = append(wv, WeightedVariant{
+				Name:   name,
+				Weight: weight,
+			})
+		}
+
+		return wv, wv.Valid()
+	default:
+		return nil, false
+	}
+}
+
+// Valid ensures variants are named, unique, non-negative,
+// and have a positive total weight.
+func (wv WeightedVariants) Valid() bool {
+	if len(wv) == 0 {
+		return false
+	}
+
+	seen := make(map[string]bool, len(wv))
+
+	for _, v := range wv {
+		if v.Name == "" || v.Weight < 0 || seen[v.Name] {
+			return false
+		}
+
+		seen[v.Name] = true
+	}
+
+	return wv.TotalWeight() > 0
+}
+
+// TotalWeight sum of all variant weights.
+func (wv WeightedVariants) TotalWeight() float64 {
+	var total float64
+
+	for _, v := range wv {
+		total += v.Weight
+	}
+
+	return total
+}
+
+// Names the variant names in order.
+func (wv WeightedVariants) Names() []string {
+	names := make([]string, len(wv))
+
+	for i, v := range wv {
+		names[i] = v.Name
+	}
+
+	return names
+}
+
+// Bucket returns the variant that `bucket` (0-99) falls within. Each
+// variant is allocated a contiguous range sized by its share of the
+// total weight, so a two variant split of 10/90 buckets identically
+// to a percentile of 0.1.
+func (wv WeightedVariants) Bucket(bucket uint32) string {
+	total := wv.TotalWeight()
+
+	if len(wv) == 0 || total <= 0 {
+		return ""
+	}
+
+	var cumulative float64
+
+	for _, v := range wv {
+		cumulative += v.Weight
+
+		if bucket < uint32(cumulative/total*100) {
+			return v.Name
+		}
+	}
+
+	return wv[len(wv)-1].Name
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseWeightedVariants(t *testing.T) {
+	wv, ok := ParseWeightedVariants("control:50, treatment:50")
+	assert.True(t, ok)
+	assert.Equal(t, WeightedVariants{{"control", 50}, {"treatment", 50}}, wv)
+
+	wv, ok = ParseWeightedVariants(`[{"name": "a", "weight": 1}, {"name": "b", "weight": 3}]`)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"a", "b"}, wv.Names())
+	assert.Equal(t, float64(4), wv.TotalWeight())
+
+	invalid := []string{"", "a", "a:b", "a:1,a:1", "a:-1,b:2", "a:0,b:0", ":1"}
+
+	for _, v := range invalid {
+		_, ok := ParseWeightedVariants(v)
+		assert.False(t, ok, v)
+	}
+}
+
+func TestWeightedVariantsFromValue(t *testing.T) {
+	var v interface{}
+	err := json.Unmarshal([]byte(`[{"name": "a", "weight": 1}, {"name": "b", "weight": 1}]`), &v)
+	assert.NoError(t, err)
+
+	wv, ok := WeightedVariantsFromValue(v)
+	assert.True(t, ok)
+	assert.Equal(t, WeightedVariants{{"a", 1}, {"b", 1}}, wv)
+
+	_, ok = WeightedVariantsFromValue(0.5)
+	assert.False(t, ok)
+
+	_, ok = WeightedVariantsFromValue([]interface{}{"a"})
+	assert.False(t, ok)
+}
+
+func TestBucket(t *testing.T) {
+	wv := WeightedVariants{{"a", 1}, {"b", 9}}
+
+	assert.Equal(t, "a", wv.Bucket(0))
+	assert.Equal(t, "a", wv.Bucket(9))
+	assert.Equal(t, "b", wv.Bucket(10))
+	assert.Equal(t, "b", wv.Bucket(99))
+
+	wv = WeightedVariants{{"a", 1}, {"b", 1}, {"c", 1}}
+
+	assert.Equal(t, "a", wv.Bucket(32))
+	assert.Equal(t, "b", wv.Bucket(33))
+	assert.Equal(t, "c", wv.Bucket(99))
+}
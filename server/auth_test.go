@@ -0,0 +1,120 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vsco/dcdr/config"
+	"github.com/vsco/dcdr/server/handlers"
+	"github.com/vsco/dcdr/server/middleware"
+	http_assert "github.com/vsco/http-test/assert"
+	"github.com/vsco/http-test/builder"
+)
+
+func authServer() (*Server, *committingClient) {
+	srv, kv := apiServer(nil)
+
+	auth := config.Auth{
+		Tokens: map[string]string{
+			"admin":  "admin-token",
+			"beta":   "beta-token",
+			"reader": "reader-token",
+			"ops":    "ops-token",
+		},
+		Policies: []config.Policy{
+			{Principal: "admin", Read: []string{"*"}, Write: []string{"*"}},
+			{Principal: "beta", Read: []string{"default", "user-groups/*"}, Write: []string{"user-groups/*"}, Prefixes: []string{"beta-"}},
+			{Principal: "ops", Read: []string{"user-groups/*"}},
+			{Principal: middleware.AnyPrincipal, Read: []string{"default"}},
+		},
+	}
+
+	srv.UseAuth(middleware.Authorize(auth.Policies), authenticators(auth)...)
+
+	return srv, kv
+}
+
+func TestAuthentication(t *testing.T) {
+	srv, _ := authServer()
+
+	resp := builder.WithMux(srv).Get(srv.config.Server.Endpoint).Do()
+	http_assert.Response(t, resp.Response).HasStatusCode(http.StatusUnauthorized).IsJSON()
+
+	resp = builder.WithMux(srv).Get(srv.config.Server.Endpoint).
+		Header(middleware.AuthorizationHeader, "Bearer nope").Do()
+	http_assert.Response(t, resp.Response).HasStatusCode(http.StatusUnauthorized)
+
+	resp = builder.WithMux(srv).Get(srv.config.Server.Endpoint).
+		Header(middleware.AuthorizationHeader, "Bearer reader-token").Do()
+	http_assert.Response(t, resp.Response).IsOK()
+}
+
+func TestAuthorization(t *testing.T) {
+	cases := []struct {
+		Token  string
+		Method string
+		Path   string
+		Scopes string
+		Status int
+	}{
+		{"admin-token", "GET", FeaturesPath, "", http.StatusOK},
+		{"beta-token", "GET", FeaturesPath, "", http.StatusForbidden},
+		{"beta-token", "GET", FeaturesPath + "?scope=user-groups/beta&prefix=beta-", "", http.StatusOK},
+		{"beta-token", "GET", FeaturesPath + "?scope=user-groups/beta&prefix=b", "", http.StatusForbidden},
+		{"beta-token", "GET", "/dcdr.json", "", http.StatusForbidden},
+		{"beta-token", "GET", "/dcdr.json?prefix=beta-", "", http.StatusForbidden},
+		{"beta-token", "GET", EvaluatePath + "/beta-flag?id=1&country=us", "user-groups/beta", http.StatusOK},
+		{"beta-token", "GET", EvaluatePath + "/beta-flag", "country-codes/us", http.StatusForbidden},
+		{"ops-token", "GET", EvaluatePath + "/beta-flag", "user-groups/beta", http.StatusForbidden},
+		{"ops-token", "GET", "/dcdr.json", "user-groups/beta", http.StatusForbidden},
+		{"beta-token", "PUT", FeaturesPath + "/user-groups/beta/beta-flag", "", http.StatusOK},
+		{"beta-token", "PUT", FeaturesPath + "/user-groups/beta/other-flag", "", http.StatusForbidden},
+		{"beta-token", "PUT", FeaturesPath + "/default/beta-flag", "", http.StatusForbidden},
+		{"reader-token", "GET", "/dcdr.json", "", http.StatusOK},
+		{"reader-token", "GET", "/dcdr.json", "user-groups/beta", http.StatusForbidden},
+		{"reader-token", "DELETE", FeaturesPath + "/default/flag", "", http.StatusForbidden},
+		{"reader-token", "POST", EvaluatePath, "", http.StatusBadRequest},
+		{"beta-token", "POST", EvaluatePath, "", http.StatusForbidden},
+	}
+
+	for _, tc := range cases {
+		srv, _ := authServer()
+
+		resp := builder.WithMux(srv).
+			Method(tc.Method).
+			Path(tc.Path).
+			Header(middleware.AuthorizationHeader, "Bearer "+tc.Token).
+			Header(handlers.DcdrScopesHeader, tc.Scopes).
+			JSON(map[string]interface{}{"value": true}).Do()
+
+		assert.Equal(t, tc.Status, resp.Response.Code, "%s %s %s", tc.Token, tc.Method, tc.Path)
+	}
+}
+
+func TestPrincipalUpdatedBy(t *testing.T) {
+	srv, kv := authServer()
+
+	resp := builder.WithMux(srv).
+		Put(FeaturesPath+"/user-groups/beta/beta-flag").
+		Header(middleware.AuthorizationHeader, "Bearer beta-token").
+		JSON(map[string]interface{}{"value": true}).Do()
+
+	http_assert.Response(t, resp.Response).IsOK()
+	assert.Equal(t, "beta", kv.committed.UpdatedBy)
+}
+
+func TestAuthFromConfig(t *testing.T) {
+	cfg := config.TestConfig()
+	cfg.Auth.Tokens = map[string]string{"admin": "admin-token"}
+
+	srv := New(cfg, cl)
+
+	resp := builder.WithMux(srv).Get(srv.config.Server.Endpoint).Do()
+	http_assert.Response(t, resp.Response).HasStatusCode(http.StatusUnauthorized)
+
+	// principals without a policy are forbidden
+	resp = builder.WithMux(srv).Get(srv.config.Server.Endpoint).
+		Header(middleware.AuthorizationHeader, "Bearer admin-token").Do()
+	http_assert.Response(t, resp.Response).HasStatusCode(http.StatusForbidden)
+}
@@ -0,0 +1,261 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vsco/dcdr/cli/api"
+	"github.com/vsco/dcdr/cli/api/stores"
+	"github.com/vsco/dcdr/config"
+	"github.com/vsco/dcdr/models"
+	"github.com/vsco/dcdr/server/handlers"
+	http_assert "github.com/vsco/http-test/assert"
+	"github.com/vsco/http-test/builder"
+)
+
+type committingClient struct {
+	*api.Client
+	committed *models.Feature
+	deleted   bool
+	pushed    bool
+}
+
+func (c *committingClient) Commit(ft *models.Feature, deleted bool) error {
+	c.committed = ft
+	c.deleted = deleted
+
+	return nil
+}
+
+func (c *committingClient) UpdateCurrentSHA() (string, error) {
+	return "abc", nil
+}
+
+func (c *committingClient) Push() error {
+	c.pushed = true
+
+	return nil
+}
+
+func apiServer(existing *models.Feature) (*Server, *committingClient) {
+	cfg := config.TestConfig()
+	cfg.Git.RepoPath = "/tmp/dcdr-audit"
+
+	kv := &committingClient{
+		Client: api.New(stores.NewMockStore(existing, nil), &stores.MockRepo{}, cfg, nil),
+	}
+
+	srv := New(cfg, cl)
+	srv.API = kv
+
+	return srv, kv
+}
+
+func TestFeaturesAPIDisabled(t *testing.T) {
+	srv := mockServer()
+	resp := builder.WithMux(srv).Get(FeaturesPath).Do()
+
+	http_assert.Response(t, resp.Response).HasStatusCode(http.StatusNotFound)
+}
+
+func TestFeaturesAPIRequiresAuth(t *testing.T) {
+	srv, _ := apiServer(nil)
+
+	assert.Equal(t, ErrUnauthenticatedAPI, srv.Serve())
+}
+
+func TestListFeaturesAPI(t *testing.T) {
+	ft := models.NewFeature("test", 0.5, "c", "u", "default", "dcdr")
+	srv, _ := apiServer(ft)
+
+	resp := builder.WithMux(srv).Get(FeaturesPath).Param(handlers.PrefixParam, "te").Do()
+	http_assert.Response(t, resp.Response).IsOK().IsJSON()
+
+	var fts models.Features
+	err := resp.Response.UnmarshalBody(&fts)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.Features{*ft}, fts)
+}
+
+func TestSetFeatureAPI(t *testing.T) {
+	srv, kv := apiServer(nil)
+
+	resp := builder.WithMux(srv).
+		Put(FeaturesPath + "/user-groups/beta/test").
+		JSON(map[string]interface{}{"value": 0.5, "comment": "c"}).Do()
+
+	http_assert.Response(t, resp.Response).IsOK().IsJSON()
+
+	var fr handlers.FeatureResponse
+	err := resp.Response.UnmarshalBody(&fr)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", fr.CurrentSHA)
+	assert.Equal(t, "test", fr.Feature.Key)
+	assert.Equal(t, "user-groups/beta", fr.Feature.Scope)
+	assert.Equal(t, models.Percentile, fr.Feature.FeatureType)
+	assert.Equal(t, 0.5, fr.Feature.Value)
+
+	assert.Equal(t, "dcdr/features/user-groups/beta/test", kv.committed.ScopedKey())
+	assert.False(t, kv.deleted)
+	assert.False(t, kv.pushed)
+}
+
+func TestSetTypedFeatureAPI(t *testing.T) {
+	srv, kv := apiServer(nil)
+
+	resp := builder.WithMux(srv).
+		Put(FeaturesPath + "/default/test").
+		JSON(map[string]interface{}{
+			"value": "a:50,b:50",
+			"type":  "variant",
+		}).Do()
+
+	http_assert.Response(t, resp.Response).IsOK()
+	assert.Equal(t, models.Variant, kv.committed.FeatureType)
+	assert.Equal(t, models.WeightedVariants{{Name: "a", Weight: 50}, {Name: "b", Weight: 50}}, kv.committed.Value)
+}
+
+func TestSetFeatureAPIMetadata(t *testing.T) {
+	srv, kv := apiServer(nil)
+
+	resp := builder.WithMux(srv).
+		Put(FeaturesPath + "/default/test").
+		JSON(map[string]interface{}{
+			"value":      true,
+			"owner":      "growth",
+			"tags":       []string{" checkout", "growth", "checkout ", ""},
+			"expires_at": "2026-03-01T00:00:00Z",
+		}).Do()
+
+	http_assert.Response(t, resp.Response).IsOK()
+	assert.Equal(t, "growth", kv.committed.Owner)
+	assert.Equal(t, []string{"checkout", "growth"}, kv.committed.Tags)
+	assert.Equal(t, "2026-03-01", kv.committed.ExpiresAt.Format(models.ExpiryDateFormat))
+	assert.NotNil(t, kv.committed.CreatedAt)
+	assert.NotNil(t, kv.committed.UpdatedAt)
+}
+
+func TestSetFeatureAPIClearMetadata(t *testing.T) {
+	exp := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	existing := models.NewFeature("test", true, "c", "u", "default", "dcdr")
+	existing.Rules = models.Rules{{Attribute: "country", Operator: models.In, Values: []string{"us"}}}
+	existing.Owner = "growth"
+	existing.Tags = []string{"checkout"}
+	existing.ExpiresAt = &exp
+
+	srv, kv := apiServer(existing)
+
+	resp := builder.WithMux(srv).Put(FeaturesPath + "/default/test").JSON(map[string]interface{}{"value": false}).Do()
+	http_assert.Response(t, resp.Response).IsOK()
+	assert.Equal(t, existing.Rules, kv.committed.Rules)
+	assert.Equal(t, "growth", kv.committed.Owner)
+	assert.Equal(t, []string{"checkout"}, kv.committed.Tags)
+	assert.True(t, exp.Equal(*kv.committed.ExpiresAt))
+
+	for _, body := range []map[string]interface{}{
+		{"rules": nil, "owner": nil, "tags": nil, "expires_at": nil},
+		{"rules": []string{}, "owner": "", "tags": []string{}, "expires_at": nil},
+	} {
+		srv, kv = apiServer(existing)
+
+		resp = builder.WithMux(srv).Put(FeaturesPath + "/default/test").JSON(body).Do()
+		http_assert.Response(t, resp.Response).IsOK()
+		assert.Empty(t, kv.committed.Rules, body)
+		assert.Empty(t, kv.committed.Owner, body)
+		assert.Empty(t, kv.committed.Tags, body)
+		assert.Nil(t, kv.committed.ExpiresAt, body)
+	}
+}
+
+func TestSetFeatureAPIExistingType(t *testing.T) {
+	existing := models.NewFeature("count", int64(10), "c", "u", "default", "dcdr")
+	existing.FeatureType = models.Integer
+
+	for _, v := range []int64{5, 1} {
+		srv, kv := apiServer(existing)
+
+		resp := builder.WithMux(srv).
+			Put(FeaturesPath + "/default/count").
+			JSON(map[string]interface{}{"value": v}).Do()
+
+		http_assert.Response(t, resp.Response).IsOK()
+		assert.Equal(t, models.Integer, kv.committed.FeatureType)
+		assert.Equal(t, v, kv.committed.Value)
+	}
+}
+
+func TestSetFeatureAPIErrors(t *testing.T) {
+	existing := models.NewFeature("test", 0.5, "c", "u", "default", "dcdr")
+
+	cases := []struct {
+		Body   interface{}
+		Status int
+		Error  string
+	}{
+		{map[string]interface{}{"value": true, "type": "boolean"}, http.StatusConflict, api.ErrTypeChange.Error()},
+		{map[string]interface{}{"value": true}, http.StatusBadRequest, handlers.ErrInvalidValue.Error()},
+		{map[string]interface{}{"value": 2}, http.StatusBadRequest, handlers.ErrInvalidRange.Error()},
+		{map[string]interface{}{"value": "x", "type": "integer"}, http.StatusBadRequest, handlers.ErrInvalidValue.Error()},
+		{map[string]interface{}{"value": 1, "type": "nope"}, http.StatusBadRequest, handlers.ErrInvalidType.Error()},
+		{"not an object", http.StatusBadRequest, handlers.ErrInvalidBody.Error()},
+	}
+
+	for _, tc := range cases {
+		srv, kv := apiServer(existing)
+
+		resp := builder.WithMux(srv).Put(FeaturesPath + "/default/test").JSON(tc.Body).Do()
+		http_assert.Response(t, resp.Response).HasStatusCode(tc.Status).IsJSON()
+
+		var er handlers.ErrorResponse
+		err := resp.Response.UnmarshalBody(&er)
+
+		assert.NoError(t, err)
+		assert.Equal(t, tc.Error, er.Error)
+		assert.Nil(t, kv.committed)
+	}
+}
+
+type conflictStore struct {
+	*stores.MockStore
+}
+
+func (cs *conflictStore) SetCAS(key string, bts []byte, version uint64) error {
+	return stores.ErrConflict
+}
+
+func TestSetFeatureAPIConflict(t *testing.T) {
+	existing := models.NewFeature("test", 0.5, "c", "u", "default", "dcdr")
+	srv, kv := apiServer(existing)
+	kv.Client.Store = &conflictStore{stores.NewMockStore(existing, nil)}
+
+	resp := builder.WithMux(srv).Put(FeaturesPath + "/default/test").JSON(map[string]interface{}{"value": 0.7}).Do()
+	http_assert.Response(t, resp.Response).HasStatusCode(http.StatusConflict).IsJSON()
+
+	var er handlers.ErrorResponse
+	err := resp.Response.UnmarshalBody(&er)
+
+	assert.NoError(t, err)
+	assert.Equal(t, api.ErrConflict.Error(), er.Error)
+	assert.Nil(t, kv.committed)
+}
+
+func TestDeleteFeatureAPI(t *testing.T) {
+	existing := models.NewFeature("test", 0.5, "c", "u", "default", "dcdr")
+	srv, kv := apiServer(existing)
+
+	resp := builder.WithMux(srv).Delete(FeaturesPath + "/default/test").Do()
+	http_assert.Response(t, resp.Response).IsOK().IsJSON()
+
+	assert.True(t, kv.deleted)
+	assert.Equal(t, "dcdr/features/default/test", kv.committed.ScopedKey())
+
+	srv, kv = apiServer(nil)
+
+	resp = builder.WithMux(srv).Delete(FeaturesPath + "/default/missing").Do()
+	http_assert.Response(t, resp.Response).HasStatusCode(http.StatusNotFound).IsJSON()
+	assert.Nil(t, kv.committed)
+}
@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/vsco/dcdr/client"
+	"github.com/vsco/dcdr/models"
+)
+
+const (
+	// IDParam query param containing the id to evaluate features for
+	IDParam = "id"
+	// FeatureVar route variable containing the feature name
+	FeatureVar = "feature"
+	// BatchEvaluateRoute name of the route bound to `BatchEvaluateHandler`
+	BatchEvaluateRoute = "batch-evaluate"
+	// MaxBatchIDs maximum number of ids accepted by `BatchEvaluateHandler`
+	MaxBatchIDs = 1000
+)
+
+var (
+	// ErrNoIDs returned when a batch request contains no ids.
+	ErrNoIDs = errors.New("ids are required")
+	// ErrTooManyIDs returned when a batch request exceeds `MaxBatchIDs`.
+	ErrTooManyIDs = fmt.Errorf("no more than %d ids may be evaluated at once", MaxBatchIDs)
+	// ErrIDRequired returned when a request is missing `IDParam`.
+	ErrIDRequired = fmt.Errorf("%s is required", IDParam)
+	// ErrInvalidID returned when `IDParam` is not an unsigned integer.
+	ErrInvalidID = fmt.Errorf("%s must be an unsigned integer", IDParam)
+)
+
+// ID a uint64 id that may be provided as a JSON number or string.
+// Ids are always written as strings so that they are not truncated
+// by JavaScript consumers.
+type ID uint64
+
+// UnmarshalJSON accepts both `123` and `"123"`.
+func (id *ID) UnmarshalJSON(bts []byte) error {
+	v, err := strconv.ParseUint(strings.Trim(string(bts), `"`), 10, 64)
+
+	if err != nil {
+		return err
+	}
+
+	*id = ID(v)
+
+	return nil
+}
+
+// MarshalJSON writes the id as a string.
+func (id ID) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(strconv.FormatUint(uint64(id), 10))), nil
+}
+
+// FeatureEvaluations the resolved boolean of each feature for `ID`.
+type FeatureEvaluations struct {
+	ID       ID              `json:"id"`
+	Features map[string]bool `json:"features"`
+}
+
+// EvaluateResponse JSON body written by `EvaluateHandler`.
+type EvaluateResponse struct {
+	CurrentSHA string `json:"current_sha"`
+	FeatureEvaluations
+}
+
+// BatchEvaluateRequest JSON body accepted by `BatchEvaluateHandler`.
+// `Features` optionally limits the features that are evaluated and
+// `Attributes` are matched against targeting rules for every id.
+type BatchEvaluateRequest struct {
+	IDs        []ID              `json:"ids"`
+	Features   []string          `json:"features,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// BatchEvaluateResponse JSON body written by `BatchEvaluateHandler`.
+type BatchEvaluateResponse struct {
+	CurrentSHA string               `json:"current_sha"`
+	Results    []FeatureEvaluations `json:"results"`
+}
+
+// ParseID parses the required `IDParam` from the query string.
+func ParseID(r *http.Request) (uint64, error) {
+	v := r.URL.Query().Get(IDParam)
+
+	if v == "" {
+		return 0, ErrIDRequired
+	}
+
+	id, err := strconv.ParseUint(v, 10, 64)
+
+	if err != nil {
+		return 0, ErrInvalidID
+	}
+
+	return id, nil
+}
+
+// ParseContext parses the `EvalContext` for a request. `IDParam` is
+// required and every other query param is an attribute matched against
+// targeting rules.
+//
+// ?id=123&country=us => EvalContext{ID: 123, Attributes: {"country": "us"}}
+func ParseContext(r *http.Request) (client.EvalContext, error) {
+	id, err := ParseID(r)
+
+	if err != nil {
+		return client.EvalContext{}, err
+	}
+
+	ctx := client.EvalContext{ID: id}
+
+	for k, v := range r.URL.Query() {
+		if k == IDParam || len(v) == 0 {
+			continue
+		}
+
+		if ctx.Attributes == nil {
+			ctx.Attributes = make(map[string]string)
+		}
+
+		ctx.Attributes[k] = v[0]
+	}
+
+	return ctx, nil
+}
+
+// EvaluateFeatureHandler serves the `EvaluationDetail` for the feature
+// found in the `FeatureVar` route variable scoped to the values
+// found in DcdrScopesHeader.
+//
+// GET /evaluate/new-signup-flow?id=123&country=us
+func EvaluateFeatureHandler(c client.IFace) func(
+	w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, err := ParseContext(r)
+
+		if err != nil {
+			WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		feature := mux.Vars(r)[FeatureVar]
+		detail := c.WithScopes(GetScopes(r)...).EvaluateFor(feature, ctx)
+
+		WriteJSON(w, r, http.StatusOK, detail)
+	}
+}
+
+// EvaluateHandler serves the resolved boolean of every boolean and
+// percentile feature for the `ParseContext` of the request scoped to the
+// values found in DcdrScopesHeader. Results match `Client.IsAvailableFor`
+// so that all platforms bucket identically.
+//
+// GET /evaluate?id=123&country=us
+func EvaluateHandler(c client.IFace) func(
+	w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, err := ParseContext(r)
+
+		if err != nil {
+			WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		sc := c.WithScopes(GetScopes(r)...)
+
+		WriteJSON(w, r, http.StatusOK, &EvaluateResponse{
+			CurrentSHA:         sc.Info().CurrentSHA,
+			FeatureEvaluations: EvaluateFeatures(sc, ctx, nil),
+		})
+	}
+}
+
+// BatchEvaluateHandler serves `FeatureEvaluations` for each id in a
+// `BatchEvaluateRequest` scoped to the values found in DcdrScopesHeader.
+//
+// POST /evaluate {"ids": ["123", "456"], "attributes": {"country": "us"}}
+func BatchEvaluateHandler(c client.IFace) func(
+	w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req BatchEvaluateRequest
+
+		err := json.NewDecoder(r.Body).Decode(&req)
+
+		if err != nil {
+			WriteError(w, http.StatusBadRequest, ErrInvalidBody)
+			return
+		}
+
+		switch {
+		case len(req.IDs) == 0:
+			WriteError(w, http.StatusBadRequest, ErrNoIDs)
+			return
+		case len(req.IDs) > MaxBatchIDs:
+			WriteError(w, http.StatusBadRequest, ErrTooManyIDs)
+			return
+		}
+
+		sc := c.WithScopes(GetScopes(r)...)
+		resp := &BatchEvaluateResponse{
+			CurrentSHA: sc.Info().CurrentSHA,
+			Results:    make([]FeatureEvaluations, len(req.IDs)),
+		}
+
+		for i, id := range req.IDs {
+			ctx := client.EvalContext{ID: uint64(id), Attributes: req.Attributes}
+			resp.Results[i] = EvaluateFeatures(sc, ctx, req.Features)
+		}
+
+		WriteJSON(w, r, http.StatusOK, resp)
+	}
+}
+
+// EvaluateFeatures resolves `features`, or every boolean and percentile
+// feature when empty, for `ctx`. Unknown features resolve to false.
+func EvaluateFeatures(c client.IFace, ctx client.EvalContext, features []string) FeatureEvaluations {
+	if len(features) == 0 {
+		for k := range c.Features() {
+			switch c.FeatureType(k) {
+			case models.Boolean, models.Percentile:
+				features = append(features, k)
+			}
+		}
+	}
+
+	fe := FeatureEvaluations{
+		ID:       ID(ctx.ID),
+		Features: make(map[string]bool, len(features)),
+	}
+
+	for _, f := range features {
+		fe.Features[f] = c.EvaluateFor(f, ctx).Enabled
+	}
+
+	return fe
+}
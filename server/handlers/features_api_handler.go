@@ -0,0 +1,352 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/vsco/dcdr/cli/api"
+	"github.com/vsco/dcdr/config"
+	"github.com/vsco/dcdr/models"
+)
+
+const (
+	// ScopeVar route variable containing the feature scope
+	ScopeVar = "scope"
+	// KeyVar route variable containing the feature key
+	KeyVar = "key"
+	// PrefixParam query param used to filter listed features by key prefix
+	PrefixParam = "prefix"
+	// ScopeParam query param used to filter listed features by scope
+	ScopeParam = "scope"
+	// ListFeaturesRoute name of the route bound to `FeaturesAPIHandler.List`
+	ListFeaturesRoute = "list-features"
+)
+
+var (
+	// ErrInvalidBody returned when a request body is not valid JSON.
+	ErrInvalidBody = errors.New("invalid request body")
+	// ErrInvalidType returned for unknown feature types.
+	ErrInvalidType = errors.New("invalid type. use percentile, boolean, string, integer, json, or variant")
+	// ErrInvalidValue returned when a value cannot be parsed.
+	ErrInvalidValue = errors.New("invalid value for feature type")
+	// ErrInvalidRange returned for percentiles outside of 0.0-1.0.
+	ErrInvalidRange = errors.New("invalid value for percentile. use 0.0-1.0")
+)
+
+// ErrorResponse JSON body written for failed requests.
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// SetFeatureRequest JSON body accepted by `FeaturesAPIHandler.Set`.
+// `Value` is parsed the same way as `dcdr set --value` and `Type`
+// the same as `--type`. `Tags` are trimmed, deduped, and sorted like
+// `--tags`. Omitted `Rules`, `Owner`, `Tags`, and
+// `ExpiresAt` keep the existing values while `null`, an empty
+// owner, or an empty list clears them.
+type SetFeatureRequest struct {
+	Value     json.RawMessage `json:"value"`
+	Type      string          `json:"type"`
+	Comment   string          `json:"comment"`
+	Rules     *models.Rules   `json:"rules"`
+	Owner     *string         `json:"owner"`
+	Tags      []string        `json:"tags"`
+	ExpiresAt *time.Time      `json:"expires_at"`
+
+	// nulls fields sent as `null`, which decode the same as omitted ones
+	nulls map[string]bool
+}
+
+// UnmarshalJSON decodes the request and records which fields were
+// sent as `null` so they can be cleared.
+func (req *SetFeatureRequest) UnmarshalJSON(bts []byte) error {
+	type request SetFeatureRequest
+	var fields map[string]json.RawMessage
+
+	err := json.Unmarshal(bts, (*request)(req))
+
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(bts, &fields)
+
+	if err != nil {
+		return err
+	}
+
+	req.nulls = make(map[string]bool)
+
+	for k, v := range fields {
+		if strings.TrimSpace(string(v)) == "null" {
+			req.nulls[k] = true
+		}
+	}
+
+	return nil
+}
+
+// FeatureResponse JSON body written for successful writes.
+type FeatureResponse struct {
+	Feature    *models.Feature `json:"feature"`
+	CurrentSHA string          `json:"current_sha,omitempty"`
+}
+
+// FeaturesAPIHandler lists, sets, and deletes features in the
+// store using an `api.ClientIFace`. Writes are serialized so that
+// each change is committed to the audit repo in order.
+type FeaturesAPIHandler struct {
+	client api.ClientIFace
+	config *config.Config
+	mu     sync.Mutex
+}
+
+// NewFeaturesAPIHandler creates a `FeaturesAPIHandler`.
+func NewFeaturesAPIHandler(kv api.ClientIFace, cfg *config.Config) (h *FeaturesAPIHandler) {
+	h = &FeaturesAPIHandler{
+		client: kv,
+		config: cfg,
+	}
+
+	return
+}
+
+// List serves the features found in the store filtered by the optional
+// `PrefixParam` and `ScopeParam`.
+//
+// GET /features?scope=user-groups/beta&prefix=new-
+func (h *FeaturesAPIHandler) List(w http.ResponseWriter, r *http.Request) {
+	prefix := r.URL.Query().Get(PrefixParam)
+	scope := r.URL.Query().Get(ScopeParam)
+
+	if prefix != "" && scope == "" {
+		scope = models.DefaultScope
+	}
+
+	fts, err := h.client.List(prefix, scope)
+
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	WriteJSON(w, r, http.StatusOK, fts)
+}
+
+// Set creates or updates the feature found in the `ScopeVar` and `KeyVar`
+// route variables from a `SetFeatureRequest` and commits the change.
+//
+// PUT /features/user-groups/beta/new-signup-flow {"value": 0.5}
+func (h *FeaturesAPIHandler) Set(w http.ResponseWriter, r *http.Request) {
+	var req SetFeatureRequest
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, ErrInvalidBody)
+		return
+	}
+
+	vars := mux.Vars(r)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	existing, err := api.ExistingFeatureType(h.client, vars[KeyVar], vars[ScopeVar])
+
+	if err != nil {
+		WriteError(w, statusForError(err), err)
+		return
+	}
+
+	ft, err := req.Feature(vars[KeyVar], vars[ScopeVar], existing, h.config)
+
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ft.UpdatedBy = h.updatedBy(r)
+
+	err = h.client.Set(ft)
+
+	if err != nil {
+		WriteError(w, statusForError(err), err)
+		return
+	}
+
+	h.commit(w, r, ft, false)
+}
+
+// Delete removes the feature found in the `ScopeVar` and `KeyVar`
+// route variables and commits the change.
+//
+// DELETE /features/user-groups/beta/new-signup-flow
+func (h *FeaturesAPIHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	ft := &models.Feature{
+		Key:       vars[KeyVar],
+		Scope:     vars[ScopeVar],
+		Namespace: h.config.Namespace,
+		UpdatedBy: h.updatedBy(r),
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	err := h.client.Delete(ft.Key, ft.GetScope())
+
+	if err != nil {
+		WriteError(w, statusForError(err), err)
+		return
+	}
+
+	h.commit(w, r, ft, true)
+}
+
+// updatedBy returns the authenticated principal falling back
+// to the configured `Username`.
+func (h *FeaturesAPIHandler) updatedBy(r *http.Request) string {
+	if p := Principal(r); p != "" {
+		return p
+	}
+
+	return h.config.Username
+}
+
+func (h *FeaturesAPIHandler) commit(w http.ResponseWriter, r *http.Request, ft *models.Feature, deleted bool) {
+	sha, err := api.CommitFeatures(h.client, h.config, ft, deleted)
+
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	WriteJSON(w, r, http.StatusOK, &FeatureResponse{
+		Feature:    ft,
+		CurrentSHA: sha,
+	})
+}
+
+// Feature builds a `models.Feature` from the request. Values are
+// validated like `dcdr set`, leaving a nil `Value` to be filled in
+// from the existing feature. Without a `Type` values are parsed as the
+// `existing` type of the stored feature when it is set.
+func (req *SetFeatureRequest) Feature(key string, scope string, existing models.FeatureType, cfg *config.Config) (*models.Feature, error) {
+	var v interface{}
+	var ft models.FeatureType
+
+	if req.Type != "" {
+		ft = models.ParseFeatureType(req.Type)
+
+		if ft == models.Invalid {
+			return nil, ErrInvalidType
+		}
+	} else {
+		ft = existing
+	}
+
+	if val := req.rawValue(); val != "" {
+		if ft != "" {
+			v, ft = models.ParseValueForFeatureType(val, ft)
+		} else {
+			v, ft = models.ParseValueAndFeatureType(val)
+		}
+
+		if ft == models.Invalid {
+			return nil, ErrInvalidValue
+		}
+
+		if ft == models.Percentile {
+			if v.(float64) > 1.0 || v.(float64) < 0 {
+				return nil, ErrInvalidRange
+			}
+		}
+	}
+
+	f := models.NewFeature(key, v, req.Comment, cfg.Username, scope, cfg.Namespace)
+	f.FeatureType = ft
+
+	if req.Rules != nil {
+		f.Rules = *req.Rules
+	} else if req.nulls["rules"] {
+		f.Rules = models.Rules{}
+	}
+
+	if req.Owner != nil {
+		f.Owner = *req.Owner
+	}
+
+	f.ClearOwner = f.Owner == "" && (req.Owner != nil || req.nulls["owner"])
+
+	if req.Tags != nil {
+		f.Tags = models.ParseTags(strings.Join(req.Tags, ","))
+	} else if req.nulls["tags"] {
+		f.Tags = []string{}
+	}
+
+	f.ExpiresAt = req.ExpiresAt
+	f.ClearExpiry = req.nulls["expires_at"]
+
+	return f, nil
+}
+
+// rawValue returns JSON strings unquoted and all other values as
+// their JSON text so both `"0.5"` and `0.5` are accepted.
+func (req *SetFeatureRequest) rawValue() string {
+	raw := strings.TrimSpace(string(req.Value))
+
+	if raw == "" || raw == "null" {
+		return ""
+	}
+
+	var s string
+
+	if json.Unmarshal(req.Value, &s) == nil {
+		return s
+	}
+
+	return raw
+}
+
+// statusForError maps `api.Client` errors to response codes.
+func statusForError(err error) int {
+	switch {
+	case errors.Is(err, api.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, api.ErrTypeChange), errors.Is(err, api.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, api.ErrNilValue), errors.Is(err, api.ErrRulesType):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// WriteJSON writes `v` as JSON with `status` and the common response headers.
+func WriteJSON(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	bts, err := json.Marshal(v)
+
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	SetResponseHeaders(w, r)
+	w.WriteHeader(status)
+	w.Write(bts)
+}
+
+// WriteError writes `err` as an `ErrorResponse` with `status`.
+func WriteError(w http.ResponseWriter, status int, err error) {
+	bts, _ := json.Marshal(&ErrorResponse{Error: err.Error()})
+
+	w.Header().Set(ContentTypeHeader, ContentType)
+	w.WriteHeader(status)
+	w.Write(bts)
+}
@@ -12,6 +12,8 @@ import (
 const (
 	// DcdrScopesHeader comma delimited scopes to pass to the client
 	DcdrScopesHeader = "x-dcdr-scopes"
+	// DcdrRawScopesHeader requests the scopes unmerged when "true"
+	DcdrRawScopesHeader = "x-dcdr-raw-scopes"
 	// ContentTypeHeader header for content type
 	ContentTypeHeader = "Content-Type"
 	// ContentType set JSON content type for responses
@@ -24,6 +26,15 @@ const (
 func SetResponseHeaders(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set(ContentTypeHeader, ContentType)
 	w.Header().Set(DcdrScopesHeader, r.Header.Get(DcdrScopesHeader))
+
+	if RawScopes(r) {
+		w.Header().Set(DcdrRawScopesHeader, "true")
+	}
+}
+
+// RawScopes checks if DcdrRawScopesHeader requests unmerged scopes.
+func RawScopes(r *http.Request) bool {
+	return r.Header.Get(DcdrRawScopesHeader) == "true"
 }
 
 func contains(s []string, str string) bool {
@@ -77,9 +88,16 @@ func SetScopes(r *http.Request, scopes []string) {
 }
 
 // ScopeMapFromRequest helper method for returning a FeatureMap scoped to
-// the values found in DcdrScopesHeader.
+// the values found in DcdrScopesHeader. The scopes are merged into a
+// single set of features unless `RawScopes` is requested.
 func ScopeMapFromRequest(c client.IFace, r *http.Request) *models.FeatureMap {
-	return c.WithScopes(GetScopes(r)...).ScopedMap()
+	scoped := c.WithScopes(GetScopes(r)...)
+
+	if RawScopes(r) {
+		return scoped.UnmergedMap()
+	}
+
+	return scoped.ScopedMap()
 }
 
 // FeaturesHandler default handler for serving a FeatureMap via HTTP
@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gorilla/context"
+)
+
+type principalKey struct{}
+
+// SetPrincipal records the authenticated `principal` for `r`. Like
+// route variables the value is cleared once the router has served `r`.
+func SetPrincipal(r *http.Request, principal string) {
+	context.Set(r, principalKey{}, principal)
+}
+
+// Principal returns the authenticated principal for `r` or an
+// empty string if the request has not been authenticated.
+func Principal(r *http.Request) string {
+	p, _ := context.Get(r, principalKey{}).(string)
+
+	return p
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/vsco/dcdr/client"
+	"github.com/vsco/dcdr/models"
+)
+
+const (
+	// LastEventIDHeader the CurrentSHA of the last event seen by the client
+	LastEventIDHeader = "Last-Event-ID"
+	// EventStreamContentType content type for Server-Sent Events
+	EventStreamContentType = "text/event-stream"
+	// FeaturesEvent event name for scoped `FeatureMap` updates
+	FeaturesEvent = "features"
+	// HeartbeatEvent event name for keep alive messages
+	HeartbeatEvent = "heartbeat"
+	// HeartbeatHeader advertises the interval between heartbeat events
+	HeartbeatHeader = "x-dcdr-heartbeat"
+	// DefaultHeartbeatInterval time between heartbeat events
+	DefaultHeartbeatInterval = 15 * time.Second
+)
+
+// StreamHandler serves Server-Sent Events containing the `FeatureMap`
+// returned by `ScopeMapFromRequest`. An event is pushed on connect and
+// whenever the client's scoped features change. Event ids are the
+// `CurrentSHA` so that clients reconnecting with a matching
+// Last-Event-ID are not sent the features they already have. The
+// `Heartbeat` interval is advertised in HeartbeatHeader so clients can
+// detect stalled connections.
+type StreamHandler struct {
+	Heartbeat time.Duration
+	client    client.IFace
+	conns     map[chan bool]bool
+	mu        sync.Mutex
+}
+
+// NewStreamHandler creates a `StreamHandler` observing updates to `c`.
+func NewStreamHandler(c client.IFace) (sh *StreamHandler) {
+	sh = &StreamHandler{
+		Heartbeat: DefaultHeartbeatInterval,
+		client:    c,
+		conns:     make(map[chan bool]bool),
+	}
+
+	c.OnUpdate(sh.broadcast)
+
+	return
+}
+
+// ServeHTTP streams events until the request is closed.
+func (sh *StreamHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	updates := sh.subscribe()
+	defer sh.unsubscribe(updates)
+
+	heartbeat := time.NewTicker(sh.Heartbeat)
+	defer heartbeat.Stop()
+
+	SetResponseHeaders(w, r)
+	w.Header().Set(ContentTypeHeader, EventStreamContentType)
+	w.Header().Set(HeartbeatHeader, sh.Heartbeat.String())
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	var last models.FeatureScopes
+	lastID := r.Header.Get(LastEventIDHeader)
+
+	if lastID == "" || lastID != sh.client.Info().CurrentSHA {
+		fm := ScopeMapFromRequest(sh.client, r)
+
+		if err := WriteFeaturesEvent(w, fm); err != nil {
+			return
+		}
+
+		last = fm.Dcdr.FeatureScopes
+		flusher.Flush()
+	} else {
+		last = ScopeMapFromRequest(sh.client, r).Dcdr.FeatureScopes
+	}
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			err := WriteEvent(w, "", HeartbeatEvent, []byte(strconv.FormatInt(time.Now().Unix(), 10)))
+
+			if err != nil {
+				return
+			}
+
+			flusher.Flush()
+		case <-updates:
+			fm := ScopeMapFromRequest(sh.client, r)
+
+			if reflect.DeepEqual(last, fm.Dcdr.FeatureScopes) {
+				continue
+			}
+
+			if err := WriteFeaturesEvent(w, fm); err != nil {
+				return
+			}
+
+			last = fm.Dcdr.FeatureScopes
+			flusher.Flush()
+		}
+	}
+}
+
+// WriteFeaturesEvent writes `fm` as a `FeaturesEvent` with its `CurrentSHA` as the id.
+func WriteFeaturesEvent(w io.Writer, fm *models.FeatureMap) error {
+	bts, err := json.Marshal(fm)
+
+	if err != nil {
+		return err
+	}
+
+	return WriteEvent(w, fm.Dcdr.CurrentSHA(), FeaturesEvent, bts)
+}
+
+// WriteEvent writes a single Server-Sent Event. `data` must not contain newlines.
+func WriteEvent(w io.Writer, id string, event string, data []byte) error {
+	if id != "" {
+		if _, err := fmt.Fprintf(w, "id: %s\n", id); err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+
+	return err
+}
+
+func (sh *StreamHandler) subscribe() chan bool {
+	ch := make(chan bool, 1)
+
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	sh.conns[ch] = true
+
+	return ch
+}
+
+func (sh *StreamHandler) unsubscribe(ch chan bool) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	delete(sh.conns, ch)
+}
+
+// broadcast signals every connection without blocking. Connections
+// that have not yet handled a previous signal coalesce updates.
+func (sh *StreamHandler) broadcast(fm *models.FeatureMap) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	for ch := range sh.conns {
+		select {
+		case ch <- true:
+		default:
+		}
+	}
+}
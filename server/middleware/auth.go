@@ -0,0 +1,216 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vsco/dcdr/client"
+	"github.com/vsco/dcdr/server/handlers"
+)
+
+const (
+	// AuthorizationHeader header containing bearer tokens and HMAC signatures
+	AuthorizationHeader = "Authorization"
+	// BearerScheme authorization scheme for static tokens
+	BearerScheme = "Bearer"
+	// HMACScheme authorization scheme for signed requests. The
+	// credentials are formatted as "<principal>:<hex signature>".
+	HMACScheme = "DCDR-HMAC-SHA256"
+	// TimestampHeader unix time the request was signed at
+	TimestampHeader = "x-dcdr-timestamp"
+	// MaxClockSkew maximum age of a signed request
+	MaxClockSkew = 5 * time.Minute
+)
+
+var (
+	// ErrUnauthorized returned when credentials are missing or invalid.
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrExpiredSignature returned for signed requests outside of `MaxClockSkew`.
+	ErrExpiredSignature = errors.New("signature timestamp is invalid or expired")
+)
+
+// Authenticator resolves the principal making `r`. Authenticators
+// return an empty principal and no error when `r` does not carry their
+// kind of credentials, and `ErrUnauthorized` when the credentials are invalid.
+type Authenticator func(r *http.Request) (string, error)
+
+// Authenticate middleware that sets the principal found by `a` on the
+// request. Invalid credentials are rejected with a 401 and requests
+// already authenticated by another `Authenticator` are passed through.
+func Authenticate(a Authenticator) func(client.IFace) func(http.Handler) http.Handler {
+	return func(dcdr client.IFace) func(http.Handler) http.Handler {
+		return func(h http.Handler) http.Handler {
+			fn := func(w http.ResponseWriter, r *http.Request) {
+				if handlers.Principal(r) == "" {
+					p, err := a(r)
+
+					if err != nil {
+						handlers.WriteError(w, http.StatusUnauthorized, err)
+						return
+					}
+
+					if p != "" {
+						handlers.SetPrincipal(r, p)
+					}
+				}
+
+				h.ServeHTTP(w, r)
+			}
+
+			return http.HandlerFunc(fn)
+		}
+	}
+}
+
+// BearerTokens authenticates "Authorization: Bearer <token>" requests
+// using `tokens`, a map of principals to tokens.
+func BearerTokens(tokens map[string]string) Authenticator {
+	return func(r *http.Request) (string, error) {
+		token, ok := credentials(r, BearerScheme)
+
+		if !ok {
+			return "", nil
+		}
+
+		for principal, t := range tokens {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(t)) == 1 {
+				return principal, nil
+			}
+		}
+
+		return "", ErrUnauthorized
+	}
+}
+
+// HMACSigned authenticates requests signed with `SignRequest` using
+// `keys`, a map of principals to signing keys.
+func HMACSigned(keys map[string]string) Authenticator {
+	return func(r *http.Request) (string, error) {
+		creds, ok := credentials(r, HMACScheme)
+
+		if !ok {
+			return "", nil
+		}
+
+		parts := strings.SplitN(creds, ":", 2)
+
+		if len(parts) != 2 {
+			return "", ErrUnauthorized
+		}
+
+		principal, sig := parts[0], parts[1]
+		key, ok := keys[principal]
+
+		if !ok {
+			return "", ErrUnauthorized
+		}
+
+		ts, err := strconv.ParseInt(r.Header.Get(TimestampHeader), 10, 64)
+
+		if err != nil {
+			return "", ErrExpiredSignature
+		}
+
+		skew := time.Since(time.Unix(ts, 0))
+
+		if skew > MaxClockSkew || skew < -MaxClockSkew {
+			return "", ErrExpiredSignature
+		}
+
+		expected, err := Signature(r, key)
+
+		if err != nil {
+			return "", err
+		}
+
+		if !hmac.Equal([]byte(sig), []byte(expected)) {
+			return "", ErrUnauthorized
+		}
+
+		return principal, nil
+	}
+}
+
+// ClientCertCN authenticates requests using the common name of a
+// verified TLS client certificate.
+func ClientCertCN() Authenticator {
+	return func(r *http.Request) (string, error) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			return "", nil
+		}
+
+		cn := r.TLS.VerifiedChains[0][0].Subject.CommonName
+
+		if cn == "" {
+			return "", ErrUnauthorized
+		}
+
+		return cn, nil
+	}
+}
+
+// SignRequest signs `r` with `key` on behalf of `principal`, setting
+// the `TimestampHeader` and `AuthorizationHeader`.
+func SignRequest(r *http.Request, principal string, key string) error {
+	r.Header.Set(TimestampHeader, strconv.FormatInt(time.Now().Unix(), 10))
+
+	sig, err := Signature(r, key)
+
+	if err != nil {
+		return err
+	}
+
+	r.Header.Set(AuthorizationHeader, fmt.Sprintf("%s %s:%s", HMACScheme, principal, sig))
+
+	return nil
+}
+
+// Signature computes the hex encoded HMAC-SHA256 of the request
+// method, URI, `TimestampHeader`, and SHA256 of the body. The body
+// is restored so it can be read again by the handler.
+func Signature(r *http.Request, key string) (string, error) {
+	var body []byte
+
+	if r.Body != nil {
+		bts, err := ioutil.ReadAll(r.Body)
+
+		if err != nil {
+			return "", err
+		}
+
+		r.Body.Close()
+		r.Body = ioutil.NopCloser(bytes.NewReader(bts))
+		body = bts
+	}
+
+	digest := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, []byte(key))
+
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%s", r.Method, r.URL.RequestURI(),
+		r.Header.Get(TimestampHeader), hex.EncodeToString(digest[:]))
+
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// credentials returns the credentials in the `AuthorizationHeader`
+// if they use `scheme`.
+func credentials(r *http.Request, scheme string) (string, bool) {
+	hdr := r.Header.Get(AuthorizationHeader)
+	prefix := scheme + " "
+
+	if len(hdr) <= len(prefix) || !strings.EqualFold(hdr[:len(prefix)], prefix) {
+		return "", false
+	}
+
+	return strings.TrimSpace(hdr[len(prefix):]), true
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBearerTokens(t *testing.T) {
+	auth := BearerTokens(map[string]string{"deploy-bot": "s3cret"})
+
+	r, _ := http.NewRequest("GET", "/dcdr.json", nil)
+	p, err := auth(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "", p)
+
+	r.Header.Set(AuthorizationHeader, "Bearer s3cret")
+	p, err = auth(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "deploy-bot", p)
+
+	r.Header.Set(AuthorizationHeader, "Bearer nope")
+	_, err = auth(r)
+	assert.Equal(t, ErrUnauthorized, err)
+}
+
+func TestHMACSigned(t *testing.T) {
+	auth := HMACSigned(map[string]string{"ci": "key"})
+	body := []byte(`{"value": true}`)
+
+	r, _ := http.NewRequest("PUT", "/features/default/a?x=1", bytes.NewReader(body))
+	assert.NoError(t, SignRequest(r, "ci", "key"))
+
+	p, err := auth(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "ci", p)
+
+	// the body is still readable by the handler
+	bts, _ := ioutil.ReadAll(r.Body)
+	assert.Equal(t, body, bts)
+
+	r, _ = http.NewRequest("PUT", "/features/default/a", bytes.NewReader(body))
+	assert.NoError(t, SignRequest(r, "ci", "wrong"))
+	_, err = auth(r)
+	assert.Equal(t, ErrUnauthorized, err)
+
+	r, _ = http.NewRequest("PUT", "/features/default/a", bytes.NewReader(body))
+	assert.NoError(t, SignRequest(r, "unknown", "key"))
+	_, err = auth(r)
+	assert.Equal(t, ErrUnauthorized, err)
+
+	r, _ = http.NewRequest("PUT", "/features/default/a", bytes.NewReader(body))
+	assert.NoError(t, SignRequest(r, "ci", "key"))
+	r.Header.Set(TimestampHeader, strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10))
+	_, err = auth(r)
+	assert.Equal(t, ErrExpiredSignature, err)
+}
+
+func TestClientCertCN(t *testing.T) {
+	auth := ClientCertCN()
+
+	r, _ := http.NewRequest("GET", "/dcdr.json", nil)
+	p, err := auth(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "", p)
+
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "billing-service"}}
+	r.TLS = &tls.ConnectionState{
+		VerifiedChains: [][]*x509.Certificate{{cert}},
+	}
+
+	p, err = auth(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "billing-service", p)
+}
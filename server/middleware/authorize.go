@@ -0,0 +1,192 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/vsco/dcdr/client"
+	"github.com/vsco/dcdr/config"
+	"github.com/vsco/dcdr/models"
+	"github.com/vsco/dcdr/server/handlers"
+)
+
+const (
+	// AnyPrincipal policy principal applied to principals without their own policy
+	AnyPrincipal = "*"
+	// allScopes scope checked for requests that are not limited to a scope
+	allScopes = "*"
+)
+
+// ErrForbidden returned when a principal's policy does not allow the request.
+var ErrForbidden = errors.New("forbidden")
+
+// Authorize middleware that checks the authenticated principal against
+// `policies`. Requests without a principal are rejected with a 401 and
+// requests outside of the principal's policy with a 403. GET and HEAD
+// requests and batch evaluations are checked against `Read` and all
+// others against `Write`.
+func Authorize(policies []config.Policy) func(client.IFace) func(http.Handler) http.Handler {
+	return func(dcdr client.IFace) func(http.Handler) http.Handler {
+		return func(h http.Handler) http.Handler {
+			fn := func(w http.ResponseWriter, r *http.Request) {
+				principal := handlers.Principal(r)
+
+				if principal == "" {
+					handlers.WriteError(w, http.StatusUnauthorized, ErrUnauthorized)
+					return
+				}
+
+				p := findPolicy(policies, principal)
+
+				if p == nil || !Allowed(p, r) {
+					handlers.WriteError(w, http.StatusForbidden, ErrForbidden)
+					return
+				}
+
+				h.ServeHTTP(w, r)
+			}
+
+			return http.HandlerFunc(fn)
+		}
+	}
+}
+
+// Allowed checks that every scope and the feature key or prefix
+// requested by `r` are permitted by `p`. Requests that are not limited
+// to a key, like the full feature map, require unrestricted `Prefixes`.
+func Allowed(p *config.Policy, r *http.Request) bool {
+	patterns := p.Write
+
+	if reading(r) {
+		patterns = p.Read
+	}
+
+	for _, scope := range requestScopes(r) {
+		if !matchesScope(patterns, scope) {
+			return false
+		}
+	}
+
+	return matchesPrefix(p.Prefixes, requestKey(r))
+}
+
+func findPolicy(policies []config.Policy, principal string) *config.Policy {
+	var fallback *config.Policy
+
+	for i := range policies {
+		switch policies[i].Principal {
+		case principal:
+			return &policies[i]
+		case AnyPrincipal:
+			fallback = &policies[i]
+		}
+	}
+
+	return fallback
+}
+
+// requestScopes returns the scope route variable, the scope query
+// param when listing features, or the scopes header. Listing without
+// a scope or prefix reads all scopes. Scopes from the header are merged
+// with the default scope, so it is checked along with them, and other
+// requests without scopes read from the default scope.
+func requestScopes(r *http.Request) []string {
+	if scope := mux.Vars(r)[handlers.ScopeVar]; scope != "" {
+		return []string{scope}
+	}
+
+	if listing(r) {
+		q := r.URL.Query()
+
+		switch {
+		case q.Get(handlers.ScopeParam) != "":
+			return []string{q.Get(handlers.ScopeParam)}
+		case q.Get(handlers.PrefixParam) != "":
+			return []string{models.DefaultScope}
+		default:
+			return []string{allScopes}
+		}
+	}
+
+	if scopes := handlers.GetScopes(r); len(scopes) > 0 {
+		return append(scopes, models.DefaultScope)
+	}
+
+	return []string{models.DefaultScope}
+}
+
+// requestKey returns the feature key or listed prefix being accessed.
+func requestKey(r *http.Request) string {
+	vars := mux.Vars(r)
+
+	if key := vars[handlers.KeyVar]; key != "" {
+		return key
+	}
+
+	if feature := vars[handlers.FeatureVar]; feature != "" {
+		return feature
+	}
+
+	if listing(r) {
+		return r.URL.Query().Get(handlers.PrefixParam)
+	}
+
+	return ""
+}
+
+func listing(r *http.Request) bool {
+	return routeName(r) == handlers.ListFeaturesRoute
+}
+
+func reading(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		return true
+	default:
+		return routeName(r) == handlers.BatchEvaluateRoute
+	}
+}
+
+func routeName(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		return route.GetName()
+	}
+
+	return ""
+}
+
+// matchesScope checks `scope` against `patterns`. A trailing `*`
+// matches any suffix.
+func matchesScope(patterns []string, scope string) bool {
+	for _, p := range patterns {
+		if p == scope {
+			return true
+		}
+
+		if strings.HasSuffix(p, "*") && strings.HasPrefix(scope, strings.TrimSuffix(p, "*")) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchesPrefix(prefixes []string, key string) bool {
+	if len(prefixes) == 0 {
+		return true
+	}
+
+	if key == "" {
+		return false
+	}
+
+	for _, p := range prefixes {
+		if strings.HasPrefix(key, p) {
+			return true
+		}
+	}
+
+	return false
+}
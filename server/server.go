@@ -1,27 +1,55 @@
 package server
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
 	"net/http"
+	"sync"
 
 	"os"
 
 	gh "github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
+	"github.com/vsco/dcdr/cli/api"
 	"github.com/vsco/dcdr/client"
 	"github.com/vsco/dcdr/config"
 	"github.com/vsco/dcdr/server/handlers"
 	"github.com/vsco/dcdr/server/middleware"
 )
 
+const (
+	// EvaluatePath path prefix for feature evaluation routes.
+	EvaluatePath = "/evaluate"
+	// StreamPath path for the Server-Sent Events feature stream.
+	StreamPath = "/dcdr/stream"
+	// FeaturesPath path prefix for the features write API.
+	FeaturesPath = "/features"
+)
+
+var (
+	// ErrInvalidClientCA returned when `ClientCAFile` contains no certificates.
+	ErrInvalidClientCA = errors.New("no certificates found in ClientCAFile")
+	// ErrUnauthenticatedAPI returned when serving the write API without `Auth`.
+	ErrUnauthenticatedAPI = errors.New("WriteAPI requires an Auth block in config.hcl")
+)
+
 // Middleware helper type for handlers that receive a `Client`.
 type Middleware func(client.IFace) func(http.Handler) http.Handler
 
-// Server HTTP API for accessing `Features`
+// Server HTTP API for accessing `Features`. Setting `API` enables
+// the `FeaturesPath` routes for listing and modifying features.
 type Server struct {
 	Client     client.IFace
+	API        api.ClientIFace
 	Router     *mux.Router
+	Stream     *handlers.StreamHandler
 	middleware []Middleware
+	authorize  Middleware
+	authn      []Middleware
 	config     *config.Config
+	routes     sync.Once
 }
 
 // NewDefault creates a new `Server` using `config.hcl`.
@@ -43,21 +71,89 @@ func New(cfg *config.Config, dcdr client.IFace) (srv *Server) {
 	srv = &Server{
 		Client: dcdr,
 		Router: mux.NewRouter(),
+		Stream: handlers.NewStreamHandler(dcdr),
 		config: cfg,
 	}
 
+	if cfg.Auth.Enabled() {
+		srv.UseAuth(middleware.Authorize(cfg.Auth.Policies), authenticators(cfg.Auth)...)
+	}
+
 	return
 }
 
-// RegisterRoutes binds `Endpoint` to the `FeaturesHandler`.
+// authenticators creates the authentication `Middleware` for the
+// methods configured in `Auth`.
+func authenticators(a config.Auth) (mws []Middleware) {
+	if a.ClientCerts {
+		mws = append(mws, middleware.Authenticate(middleware.ClientCertCN()))
+	}
+
+	if len(a.Tokens) > 0 {
+		mws = append(mws, middleware.Authenticate(middleware.BearerTokens(a.Tokens)))
+	}
+
+	if len(a.HMACKeys) > 0 {
+		mws = append(mws, middleware.Authenticate(middleware.HMACSigned(a.HMACKeys)))
+	}
+
+	return
+}
+
+// RegisterRoutes binds `Endpoint` to the `FeaturesHandler`,
+// `EvaluatePath` to the evaluation handlers, `StreamPath`
+// to the `StreamHandler`, and `FeaturesPath` to the
+// `FeaturesAPIHandler` when `API` is set.
 func (srv *Server) RegisterRoutes() {
 	srv.Router.Handle(srv.config.Server.Endpoint, srv.FeaturesHandler()).Methods("GET")
+	srv.Router.Handle(EvaluatePath, srv.EvaluateHandler()).Methods("GET")
+	srv.Router.Handle(EvaluatePath, srv.BatchEvaluateHandler()).Methods("POST").
+		Name(handlers.BatchEvaluateRoute)
+	srv.Router.Handle(EvaluatePath+"/{"+handlers.FeatureVar+"}", srv.EvaluateFeatureHandler()).Methods("GET")
+	srv.Router.Handle(StreamPath, srv.WithMiddleware(srv.Stream)).Methods("GET")
+
+	if srv.API != nil {
+		h := handlers.NewFeaturesAPIHandler(srv.API, srv.config)
+		// scopes may be nested, keys are the last path segment
+		feature := FeaturesPath + "/{" + handlers.ScopeVar + ":.+}/{" + handlers.KeyVar + ":[^/]+}"
+
+		srv.Router.Handle(FeaturesPath, srv.WithMiddleware(http.HandlerFunc(h.List))).Methods("GET").
+			Name(handlers.ListFeaturesRoute)
+		srv.Router.Handle(feature, srv.WithMiddleware(http.HandlerFunc(h.Set))).Methods("PUT")
+		srv.Router.Handle(feature, srv.WithMiddleware(http.HandlerFunc(h.Delete))).Methods("DELETE")
+	}
 }
 
 // FeaturesHandler delegates to `handlers.FeaturesHandler` and adds the
-// middleware chain.
+// middleware chain followed by the `HTTPCachingHandler`. Caching is only
+// applied here as the other routes do not serve the `FeatureMap` as is.
 func (srv *Server) FeaturesHandler() http.Handler {
 	fn := handlers.FeaturesHandler(srv.Client)
+	h := middleware.HTTPCachingHandler(srv.Client)(http.HandlerFunc(fn))
+
+	return srv.WithMiddleware(h)
+}
+
+// EvaluateHandler delegates to `handlers.EvaluateHandler` and adds
+// the middleware chain.
+func (srv *Server) EvaluateHandler() http.Handler {
+	fn := handlers.EvaluateHandler(srv.Client)
+
+	return srv.WithMiddleware(http.HandlerFunc(fn))
+}
+
+// BatchEvaluateHandler delegates to `handlers.BatchEvaluateHandler`
+// and adds the middleware chain.
+func (srv *Server) BatchEvaluateHandler() http.Handler {
+	fn := handlers.BatchEvaluateHandler(srv.Client)
+
+	return srv.WithMiddleware(http.HandlerFunc(fn))
+}
+
+// EvaluateFeatureHandler delegates to `handlers.EvaluateFeatureHandler`
+// and adds the middleware chain.
+func (srv *Server) EvaluateFeatureHandler() http.Handler {
+	fn := handlers.EvaluateFeatureHandler(srv.Client)
 
 	return srv.WithMiddleware(http.HandlerFunc(fn))
 }
@@ -67,26 +163,77 @@ func (srv *Server) Use(h ...Middleware) {
 	srv.middleware = append(srv.middleware, h...)
 }
 
-// ServeHTTP registers the `HTTPCachingHandler` and sets up the route
-// handlers and logging.
+// UseAuth sets the `authenticators` and `authorize` middleware. These
+// run before all other middleware, with `authenticators` called in order
+// followed by `authorize`.
+func (srv *Server) UseAuth(authorize Middleware, authenticators ...Middleware) {
+	srv.authorize = authorize
+	srv.authn = authenticators
+}
+
+// ServeHTTP sets up the route handlers and logging. Routes are
+// registered once on the first request.
 func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	srv.Use(middleware.HTTPCachingHandler)
-	srv.RegisterRoutes()
+	srv.routes.Do(srv.RegisterRoutes)
 
 	logger := gh.CombinedLoggingHandler(os.Stdout, srv.Router)
 	logger.ServeHTTP(w, r)
 }
 
-// Serve starts the server on the configured `Host`.
+// Serve starts the server on the configured `Host`. When TLS is
+// configured client certificates are verified against `ClientCAFile`.
+// The write API is only served with authentication configured.
 func (srv *Server) Serve() error {
-	return http.ListenAndServe(srv.config.Server.Host, srv)
+	cfg := srv.config.Server
+
+	if srv.API != nil && srv.authorize == nil {
+		return ErrUnauthenticatedAPI
+	}
+
+	if !cfg.TLSEnabled() {
+		return http.ListenAndServe(cfg.Host, srv)
+	}
+
+	s := &http.Server{
+		Addr:      cfg.Host,
+		Handler:   srv,
+		TLSConfig: &tls.Config{},
+	}
+
+	if cfg.ClientCAFile != "" {
+		pem, err := ioutil.ReadFile(cfg.ClientCAFile)
+
+		if err != nil {
+			return err
+		}
+
+		pool := x509.NewCertPool()
+
+		if !pool.AppendCertsFromPEM(pem) {
+			return ErrInvalidClientCA
+		}
+
+		s.TLSConfig.ClientCAs = pool
+		s.TLSConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	return s.ListenAndServeTLS(cfg.CertFile, cfg.KeyFile)
 }
 
 // WithMiddleware adds the middleware chain to `h` passing each the `Client`.
+// Auth middleware is added last so that it runs first.
 func (srv *Server) WithMiddleware(h http.Handler) http.Handler {
 	for _, mw := range srv.middleware {
 		h = mw(srv.Client)(h)
 	}
 
+	if srv.authorize != nil {
+		h = srv.authorize(srv.Client)(h)
+	}
+
+	for i := len(srv.authn) - 1; i >= 0; i-- {
+		h = srv.authn[i](srv.Client)(h)
+	}
+
 	return h
 }
@@ -1,7 +1,9 @@
 package server
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
 	"testing"
 
 	"bytes"
@@ -59,6 +61,26 @@ func TestScopeHeader(t *testing.T) {
 	assert.Equal(t, cl.WithScopes("scope").ScopedMap(), &m)
 }
 
+func TestRawScopesHeader(t *testing.T) {
+	srv := mockServer()
+	resp := builder.WithMux(srv).
+		Get(srv.config.Server.Endpoint).
+		Header(handlers.DcdrScopesHeader, "scope").
+		Header(handlers.DcdrRawScopesHeader, "true").Do()
+
+	http_assert.Response(t, resp.Response).
+		IsOK().
+		IsJSON().
+		ContainsHeaderValue(handlers.DcdrRawScopesHeader, "true")
+
+	var m models.FeatureMap
+	err := resp.Response.UnmarshalBody(&m)
+
+	assert.NoError(t, err)
+	assert.Equal(t, cl.WithScopes("scope").UnmergedMap(), &m)
+	assert.Equal(t, cl.WithScopes("scope").Features(), m.Dcdr.MergedScopes("scope"))
+}
+
 func TestGetScopes(t *testing.T) {
 	rd := bytes.NewReader([]byte{})
 	r, err := http.NewRequest("GET", "/", rd)
@@ -113,4 +135,192 @@ func TestHTTPCaching(t *testing.T) {
 		ContainsHeaderValue(middleware.CacheControlHeader, middleware.CacheControl).
 		ContainsHeaderValue(middleware.PragmaHeader, middleware.Pragma).
 		ContainsHeaderValue(middleware.ExpiresHeader, middleware.Expires)
+
+	// evaluations depend on the request and are never cached
+	resp = builder.WithMux(srv).
+		Get(EvaluatePath+"?id=1&country=us").
+		Header(middleware.IfNoneMatchHeader, fm.Dcdr.Info.CurrentSHA).Do()
+
+	http_assert.Response(t, resp.Response).HasStatusCode(http.StatusOK)
+	assert.Equal(t, "", resp.Response.Headers.Get(middleware.EtagHeader))
+}
+
+func TestEvaluateFeature(t *testing.T) {
+	srv := mockServer()
+	fm := models.EmptyFeatureMap()
+	fm.Dcdr.FeatureScopes["default"] = map[string]interface{}{"bool": true}
+	fm.Dcdr.FeatureScopes["scope"] = map[string]interface{}{"bool": false}
+	srv.Client.SetFeatureMap(fm)
+
+	resp := builder.WithMux(srv).
+		Get(fmt.Sprintf("%s/bool", EvaluatePath)).
+		Param(handlers.IDParam, "1").
+		Header(handlers.DcdrScopesHeader, "scope").Do()
+
+	http_assert.Response(t, resp.Response).
+		IsOK().
+		IsJSON()
+
+	var d client.EvaluationDetail
+	err := resp.Response.UnmarshalBody(&d)
+
+	assert.NoError(t, err)
+	assert.Equal(t, client.Default, d.Reason)
+	assert.Equal(t, "scope", d.Scope)
+	assert.False(t, d.Enabled)
+
+	resp = builder.WithMux(srv).
+		Get(fmt.Sprintf("%s/bool", EvaluatePath)).
+		Param(handlers.IDParam, "abc").Do()
+
+	http_assert.Response(t, resp.Response).
+		HasStatusCode(http.StatusBadRequest).
+		IsJSON()
+
+	var er handlers.ErrorResponse
+	assert.NoError(t, resp.Response.UnmarshalBody(&er))
+	assert.Equal(t, handlers.ErrInvalidID.Error(), er.Error)
+
+	resp = builder.WithMux(srv).
+		Get(fmt.Sprintf("%s/bool", EvaluatePath)).Do()
+
+	http_assert.Response(t, resp.Response).
+		HasStatusCode(http.StatusBadRequest).
+		IsJSON()
+
+	assert.NoError(t, resp.Response.UnmarshalBody(&er))
+	assert.Equal(t, handlers.ErrIDRequired.Error(), er.Error)
+}
+
+func TestEvaluateTargeted(t *testing.T) {
+	srv := mockServer()
+	fm := models.EmptyFeatureMap()
+	fm.Dcdr.FeatureScopes["default"] = map[string]interface{}{"targeted": true}
+	fm.Dcdr.AddRules("default/targeted", models.Rules{{Attribute: "country", Operator: models.In, Values: []string{"us"}}})
+	srv.Client.SetFeatureMap(fm)
+
+	for country, enabled := range map[string]bool{"us": true, "ca": false} {
+		resp := builder.WithMux(srv).
+			Get(fmt.Sprintf("%s/targeted", EvaluatePath)).
+			Param(handlers.IDParam, "1").
+			Param("country", country).Do()
+
+		var d client.EvaluationDetail
+		assert.NoError(t, resp.Response.UnmarshalBody(&d))
+		assert.Equal(t, enabled, d.Enabled, country)
+
+		resp = builder.WithMux(srv).
+			Get(EvaluatePath).
+			Param(handlers.IDParam, "1").
+			Param("country", country).Do()
+
+		var er handlers.EvaluateResponse
+		assert.NoError(t, resp.Response.UnmarshalBody(&er))
+		assert.Equal(t, enabled, er.Features["targeted"], country)
+
+		resp = builder.WithMux(srv).
+			Post(EvaluatePath).
+			JSON(map[string]interface{}{
+				"ids":        []interface{}{1},
+				"attributes": map[string]string{"country": country},
+			}).Do()
+
+		var br handlers.BatchEvaluateResponse
+		assert.NoError(t, resp.Response.UnmarshalBody(&br))
+		assert.Equal(t, enabled, br.Results[0].Features["targeted"], country)
+	}
+
+	resp := builder.WithMux(srv).Get(EvaluatePath).Do()
+
+	http_assert.Response(t, resp.Response).
+		HasStatusCode(http.StatusBadRequest)
+}
+
+func evaluateFeatureMap() *models.FeatureMap {
+	fm := models.EmptyFeatureMap()
+	fm.Dcdr.Info.CurrentSHA = "abc"
+	fm.Dcdr.FeatureScopes["default"] = map[string]interface{}{
+		"bool":    true,
+		"percent": 0.5,
+		"string":  "a",
+		"count":   1.0,
+	}
+	fm.Dcdr.FeatureScopes["scope"] = map[string]interface{}{"bool": false}
+	fm.Dcdr.AddType("default/count", models.Integer)
+
+	return fm
+}
+
+func TestEvaluateAll(t *testing.T) {
+	srv := mockServer()
+	srv.Client.SetFeatureMap(evaluateFeatureMap())
+
+	for _, id := range []uint64{1, 2, 3, 18446744073709551615} {
+		resp := builder.WithMux(srv).
+			Get(EvaluatePath).
+			Param(handlers.IDParam, strconv.FormatUint(id, 10)).
+			Header(handlers.DcdrScopesHeader, "scope").Do()
+
+		http_assert.Response(t, resp.Response).
+			IsOK().
+			IsJSON()
+
+		var er handlers.EvaluateResponse
+		err := resp.Response.UnmarshalBody(&er)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "abc", er.CurrentSHA)
+		assert.Equal(t, handlers.ID(id), er.ID)
+		assert.Equal(t, map[string]bool{
+			"bool":    false,
+			"percent": srv.Client.IsAvailableForID("percent", id),
+		}, er.Features)
+		assert.Contains(t, resp.Response.BodyString, `"id":"`+strconv.FormatUint(id, 10)+`"`)
+	}
+}
+
+func TestBatchEvaluate(t *testing.T) {
+	srv := mockServer()
+	srv.Client.SetFeatureMap(evaluateFeatureMap())
+
+	resp := builder.WithMux(srv).
+		Post(EvaluatePath).
+		JSON(map[string]interface{}{
+			"ids":      []interface{}{"1", 2},
+			"features": []string{"bool", "percent", "missing"},
+		}).Do()
+
+	http_assert.Response(t, resp.Response).
+		IsOK().
+		IsJSON()
+
+	var br handlers.BatchEvaluateResponse
+	err := resp.Response.UnmarshalBody(&br)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", br.CurrentSHA)
+	assert.Len(t, br.Results, 2)
+
+	for i, id := range []uint64{1, 2} {
+		assert.Equal(t, handlers.ID(id), br.Results[i].ID)
+		assert.Equal(t, map[string]bool{
+			"bool":    true,
+			"percent": srv.Client.IsAvailableForID("percent", id),
+			"missing": false,
+		}, br.Results[i].Features)
+	}
+
+	resp = builder.WithMux(srv).
+		Post(EvaluatePath).
+		JSON(map[string]interface{}{"ids": []string{}}).Do()
+
+	http_assert.Response(t, resp.Response).
+		HasStatusCode(http.StatusBadRequest)
+
+	resp = builder.WithMux(srv).
+		Post(EvaluatePath).
+		JSON(map[string]interface{}{"ids": []string{"abc"}}).Do()
+
+	http_assert.Response(t, resp.Response).
+		HasStatusCode(http.StatusBadRequest)
 }
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vsco/dcdr/client"
+	"github.com/vsco/dcdr/config"
+	"github.com/vsco/dcdr/models"
+	"github.com/vsco/dcdr/server/handlers"
+	"github.com/vsco/dcdr/server/middleware"
+)
+
+func streamFeatureMap(sha string, value bool) *models.FeatureMap {
+	fm := models.EmptyFeatureMap()
+	fm.Dcdr.Info.CurrentSHA = sha
+	fm.Dcdr.FeatureScopes["default"] = map[string]interface{}{"bool": value}
+	fm.Dcdr.FeatureScopes["scope"] = map[string]interface{}{"bool": !value}
+
+	return fm
+}
+
+func readLine(t *testing.T, r *bufio.Reader) string {
+	line, err := r.ReadString('\n')
+	assert.NoError(t, err)
+
+	return strings.TrimSpace(line)
+}
+
+func TestStream(t *testing.T) {
+	c, _ := client.New(config.TestConfig())
+	c.SetFeatureMap(streamFeatureMap("a", true))
+
+	srv := New(config.TestConfig(), c)
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	req, _ := http.NewRequest("GET", ts.URL+StreamPath, nil)
+	req.Header.Set(handlers.DcdrScopesHeader, "scope")
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, handlers.EventStreamContentType, resp.Header.Get(handlers.ContentTypeHeader))
+	assert.Equal(t, handlers.DefaultHeartbeatInterval.String(), resp.Header.Get(handlers.HeartbeatHeader))
+	assert.Equal(t, "", resp.Header.Get(middleware.EtagHeader))
+
+	r := bufio.NewReader(resp.Body)
+
+	assert.Equal(t, "id: a", readLine(t, r))
+	assert.Equal(t, "event: features", readLine(t, r))
+	assert.Contains(t, readLine(t, r), `"bool":false`)
+	assert.Equal(t, "", readLine(t, r))
+
+	c.SetFeatureMap(streamFeatureMap("b", false))
+
+	assert.Equal(t, "id: b", readLine(t, r))
+	assert.Equal(t, "event: features", readLine(t, r))
+	assert.Contains(t, readLine(t, r), `"bool":true`)
+}
+
+func TestStreamResume(t *testing.T) {
+	c, _ := client.New(config.TestConfig())
+	c.SetFeatureMap(streamFeatureMap("a", true))
+
+	srv := New(config.TestConfig(), c)
+	srv.Stream.Heartbeat = 10 * time.Millisecond
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	req, _ := http.NewRequest("GET", ts.URL+StreamPath, nil)
+	req.Header.Set(handlers.LastEventIDHeader, "a")
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	r := bufio.NewReader(resp.Body)
+
+	// up to date clients only receive heartbeats
+	assert.Equal(t, "event: heartbeat", readLine(t, r))
+}
+
+func TestStreamingClient(t *testing.T) {
+	c, _ := client.New(config.TestConfig())
+	c.SetFeatureMap(streamFeatureMap("a", true))
+
+	srv := New(config.TestConfig(), c)
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	defer ts.CloseClientConnections()
+
+	cfg := config.TestConfig()
+	cfg.Watcher.Type = config.WatcherTypeStream
+	cfg.Watcher.URL = ts.URL + StreamPath
+	cfg.Watcher.Scopes = []string{"scope"}
+
+	sc, err := client.New(cfg)
+	assert.NoError(t, err)
+	assert.False(t, sc.IsAvailable("bool"))
+
+	changed := make(chan interface{}, 1)
+	sc.Subscribe("bool", func(old, new interface{}) {
+		changed <- new
+	})
+
+	c.SetFeatureMap(streamFeatureMap("b", false))
+
+	select {
+	case v := <-changed:
+		assert.Equal(t, true, v)
+		assert.Equal(t, "b", sc.Info().CurrentSHA)
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for streamed update")
+	}
+}